@@ -3,12 +3,16 @@ package main
 import (
 	"log"
 	"os"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/joho/godotenv"
+	"github.com/kshzz24/gosocial/internal/analytics"
 	"github.com/kshzz24/gosocial/internal/database"
 	"github.com/kshzz24/gosocial/internal/handlers"
+	"github.com/kshzz24/gosocial/internal/jobs"
 	"github.com/kshzz24/gosocial/internal/middleware"
+	"github.com/kshzz24/gosocial/internal/realtime"
 )
 
 func main() {
@@ -28,6 +32,17 @@ func main() {
 	}
 
 	defer database.Close()
+
+	if connStr, err := database.ConnectionString(); err != nil {
+		log.Printf("Real-time fan-out disabled: %v", err)
+	} else if err := realtime.StartPostgresBridge(connStr); err != nil {
+		log.Printf("Real-time fan-out disabled: %v", err)
+	}
+	go realtime.DefaultPresence.Run(30*time.Second, nil)
+	go jobs.RunScheduledPosts(time.Minute, nil)
+	go jobs.RunArchiver(time.Hour, nil)
+	go analytics.Default.Run(time.Minute, nil)
+
 	router := gin.New()
 	router.Use(gin.Logger())
 
@@ -39,19 +54,144 @@ func main() {
 		auth.POST("/reset-password", handlers.ResetPassword)
 	}
 	subredditRoutes := router.Group("/api/subreddits")
+	subredditRoutes.Use(middleware.OptionalAuth())
 	{
 		subredditRoutes.GET("/:name", handlers.GetSubreddit)
 		subredditRoutes.GET("/", handlers.ListSubreddits)
+		subredditRoutes.POST("/:name/presence", handlers.SubredditHeartbeat)
+		subredditRoutes.GET("/:name/content-warnings", handlers.ListContentWarnings)
+		subredditRoutes.GET("/:name/awards", handlers.ListAwardTypes)
+		subredditRoutes.GET("/:name/similar", handlers.ListSimilarSubreddits)
+		subredditRoutes.GET("/:name/wiki", handlers.ListWikiPages)
+		subredditRoutes.GET("/:name/wiki/pages/*path", handlers.GetWikiPage)
+	}
+	postRoutes := router.Group("/api/posts")
+	postRoutes.Use(middleware.OptionalAuth())
+	{
+		postRoutes.GET("/:id", handlers.GetPost)
+		postRoutes.GET("/:id/duplicates", handlers.ListOtherDiscussions)
+		postRoutes.GET("/:id/revisions", handlers.ListPostRevisions)
+		postRoutes.GET("/:id/video/thumbnail", handlers.GetVideoThumbnail)
+		postRoutes.GET("/", handlers.ListPosts)
+	}
+	discoverRoutes := router.Group("/api/discover")
+	discoverRoutes.Use(middleware.OptionalAuth())
+	{
+		discoverRoutes.GET("/categories", handlers.ListCategories)
+		discoverRoutes.GET("/trending", handlers.ListTrendingSubreddits)
+	}
+	wikiRoutes := router.Group("/api/wiki")
+	wikiRoutes.Use(middleware.OptionalAuth())
+	{
+		wikiRoutes.GET("/:id/revisions", handlers.ListWikiRevisions)
+	}
+	userRoutes := router.Group("/api/users")
+	userRoutes.Use(middleware.OptionalAuth())
+	{
+		userRoutes.GET("/:username", handlers.GetUserProfile)
+		userRoutes.GET("/:username/posts", handlers.ListUserPosts)
+		userRoutes.GET("/:username/overview", handlers.ListUserOverview)
+		userRoutes.GET("/:username/karma", handlers.GetUserKarma)
+		userRoutes.GET("/:username/followers", handlers.ListFollowers)
+		userRoutes.GET("/:username/following", handlers.ListFollowing)
+	}
+	searchRoutes := router.Group("/api/search")
+	searchRoutes.Use(middleware.OptionalAuth())
+	{
+		searchRoutes.GET("", handlers.Search)
+		searchRoutes.GET("/subreddits", handlers.AutocompleteSubreddits)
 	}
 	api := router.Group("/api")
 	api.Use(middleware.RequireAuth())
 	{
 		api.GET("/me", handlers.GetMe)
+		api.PUT("/me/profile", handlers.UpdateProfile)
+		api.GET("/me/preferences", handlers.GetPreferences)
+		api.PUT("/me/preferences", handlers.UpdatePreferences)
+		api.GET("/me/karma/ledger", handlers.ListKarmaLedger)
+		api.GET("/me/awards/balance", handlers.GetAwardBalance)
+		api.POST("/me/karma/recompute", handlers.RecomputeKarma)
 		api.POST("/logout", handlers.Logout)
 		api.POST("/update-password", handlers.ChangePassword)
 		api.POST("/subreddits", handlers.CreateSubreddit)
-		api.PUT("/subreddits/:id", handlers.UpdateSubreddit)
-		api.DELETE("/subreddits/:id", handlers.DeleteSubreddit)
+		// The segment holds the subreddit ID here; gin needs one wildcard
+		// name per position, and the other /subreddits routes use :name.
+		api.PUT("/subreddits/:name", handlers.UpdateSubreddit)
+		api.DELETE("/subreddits/:name", handlers.DeleteSubreddit)
+		api.POST("/posts", handlers.CreatePost)
+		api.PUT("/posts/:id", handlers.UpdatePost)
+		api.DELETE("/posts/:id", handlers.DeletePost)
+		api.POST("/posts/:id/lock", handlers.LockPost)
+		api.DELETE("/posts/:id/lock", handlers.UnlockPost)
+		api.POST("/posts/:id/sticky", handlers.StickyPost)
+		api.DELETE("/posts/:id/sticky", handlers.UnstickyPost)
+		api.PUT("/posts/:id/distinguish", handlers.DistinguishPost)
+		api.DELETE("/posts/:id/distinguish", handlers.UndistinguishPost)
+		api.POST("/posts/:id/contest-mode", handlers.EnableContestMode)
+		api.DELETE("/posts/:id/contest-mode", handlers.DisableContestMode)
+		api.PUT("/posts/:id/suggested-sort", handlers.SetSuggestedSort)
+		api.PUT("/posts/:id/content-labels", handlers.SetPostContentLabels)
+		api.DELETE("/posts/:id/suggested-sort", handlers.ClearSuggestedSort)
+		api.POST("/posts/:id/crosspost", handlers.CreateCrosspost)
+		api.POST("/posts/:id/vote", handlers.VotePost)
+		api.POST("/posts/:id/poll/vote", handlers.VotePoll)
+		api.POST("/posts/:id/awards", handlers.GiveAward)
+		api.POST("/posts/:id/save", handlers.SavePost)
+		api.DELETE("/posts/:id/save", handlers.UnsavePost)
+		api.POST("/posts/:id/hide", handlers.HidePost)
+		api.DELETE("/posts/:id/hide", handlers.UnhidePost)
+		api.GET("/me/saved", handlers.ListSavedPosts)
+		api.GET("/me/saved/categories", handlers.ListSavedCategories)
+		api.GET("/me/hidden", handlers.ListHiddenPosts)
+		api.GET("/notifications", handlers.ListNotifications)
+		api.GET("/notifications/unread-count", handlers.GetUnreadNotificationCount)
+		api.POST("/notifications/:id/read", handlers.MarkNotificationRead)
+		api.POST("/notifications/read-all", handlers.MarkAllNotificationsRead)
+		api.GET("/notifications/preferences", handlers.GetNotificationPreferences)
+		api.PUT("/notifications/preferences", handlers.UpdateNotificationPreferences)
+		api.GET("/stream", handlers.Stream)
+		api.POST("/users/:username/block", handlers.BlockUser)
+		api.DELETE("/users/:username/block", handlers.UnblockUser)
+		api.POST("/users/:username/follow", handlers.FollowUser)
+		api.DELETE("/users/:username/follow", handlers.UnfollowUser)
+		api.GET("/me/feed", handlers.GetFollowingFeed)
+		api.GET("/me/mentions", handlers.ListMentions)
+		api.GET("/me/drafts", handlers.ListDrafts)
+		api.POST("/me/drafts", handlers.CreateDraft)
+		api.GET("/me/drafts/:id", handlers.GetDraft)
+		api.PUT("/me/drafts/:id", handlers.UpdateDraft)
+		api.DELETE("/me/drafts/:id", handlers.DeleteDraft)
+		api.POST("/me/drafts/:id/cancel", handlers.CancelDraft)
+		api.POST("/me/drafts/:id/publish", handlers.PublishDraft)
+		api.GET("/me/blocks", handlers.ListBlockedUsers)
+		api.GET("/me/muted-subreddits", handlers.ListMutedSubreddits)
+		api.PUT("/me/muted-subreddits/:name", handlers.MuteSubreddit)
+		api.DELETE("/me/muted-subreddits/:name", handlers.UnmuteSubreddit)
+		api.GET("/conversations", handlers.ListConversations)
+		api.POST("/conversations", handlers.StartConversation)
+		api.GET("/conversations/:id", handlers.GetConversation)
+		api.POST("/conversations/:id/messages", handlers.ReplyToConversation)
+		api.POST("/conversations/:id/read", handlers.MarkConversationRead)
+		api.DELETE("/messages/:id", handlers.DeleteMessage)
+		api.POST("/subreddits/:name/modmail", handlers.CreateModmail)
+		api.POST("/subreddits/:name/content-warnings", handlers.CreateContentWarning)
+		api.PUT("/content-warnings/:id", handlers.UpdateContentWarning)
+		api.DELETE("/content-warnings/:id", handlers.DeleteContentWarning)
+		api.POST("/subreddits/:name/awards", handlers.CreateAwardType)
+		api.DELETE("/awards/:id", handlers.RetireAwardType)
+		api.POST("/subreddits/:name/wiki/pages/*path", handlers.EditWikiPage)
+		api.GET("/subreddits/:name/wiki/editors", handlers.ListWikiEditors)
+		api.PUT("/subreddits/:name/wiki/editors/:username", handlers.AddWikiEditor)
+		api.DELETE("/subreddits/:name/wiki/editors/:username", handlers.RemoveWikiEditor)
+		api.GET("/subreddits/:name/traffic", handlers.GetSubredditTraffic)
+		api.POST("/wiki/:id/revert", handlers.RevertWikiPage)
+		api.PUT("/wiki/:id/settings", handlers.UpdateWikiPageSettings)
+		api.DELETE("/wiki/:id", handlers.DeleteWikiPage)
+		api.GET("/modmail", handlers.ListModmail)
+		api.GET("/modmail/:id", handlers.GetModmail)
+		api.POST("/modmail/:id/messages", handlers.ReplyToModmail)
+		api.POST("/modmail/:id/archive", handlers.ArchiveModmail)
+		api.DELETE("/modmail/:id/archive", handlers.UnarchiveModmail)
 
 
 	}
@@ -0,0 +1,230 @@
+// Package analytics records subreddit traffic for moderators.
+package analytics
+
+import (
+	"cmp"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+// VisitorRetention is how long hourly visitor sets are kept. Unique
+// visitors can't be counted for older periods.
+const VisitorRetention = 400 * 24 * time.Hour
+
+// Metric is a traffic counter other than page views.
+type Metric int
+
+const (
+	Subscriptions Metric = iota
+	Unsubscriptions
+	Posts
+	Comments
+)
+
+type bucketKey struct {
+	subredditID int
+	hour        time.Time
+}
+
+type bucket struct {
+	pageViews int
+	metrics   [4]int
+	visitors  map[[16]byte]struct{}
+}
+
+// Recorder counts traffic in memory and adds it to the hourly rollups on
+// Flush, so page views don't cost a database write each.
+type Recorder struct {
+	mu      sync.Mutex
+	buckets map[bucketKey]*bucket
+}
+
+var Default = NewRecorder()
+
+func NewRecorder() *Recorder {
+	return &Recorder{buckets: make(map[bucketKey]*bucket)}
+}
+
+func (r *Recorder) bucket(subredditID int) *bucket {
+	key := bucketKey{subredditID, time.Now().UTC().Truncate(time.Hour)}
+	b := r.buckets[key]
+	if b == nil {
+		b = &bucket{visitors: make(map[[16]byte]struct{})}
+		r.buckets[key] = b
+	}
+	return b
+}
+
+var (
+	secretOnce sync.Once
+	secret     []byte
+)
+
+// visitorSecret returns TRAFFIC_SECRET, or a random secret for this process
+// when it is unset. Instances that don't share the secret count the same
+// visitor separately.
+func visitorSecret() []byte {
+	secretOnce.Do(func() {
+		if s := os.Getenv("TRAFFIC_SECRET"); s != "" {
+			secret = []byte(s)
+			return
+		}
+		log.Println("analytics: TRAFFIC_SECRET is not set, using a random secret for this process")
+		secret = make([]byte, 32)
+		rand.Read(secret)
+	})
+	return secret
+}
+
+// hashVisitor returns a keyed hash of visitor. The key is derived from the
+// secret and the UTC month, so it rotates monthly while visitors stay
+// countable across the hours, days and months of a report.
+func hashVisitor(visitor string, t time.Time) [16]byte {
+	month := hmac.New(sha256.New, visitorSecret())
+	month.Write([]byte(t.UTC().Format("2006-01")))
+
+	mac := hmac.New(sha256.New, month.Sum(nil))
+	mac.Write([]byte(visitor))
+	var hash [16]byte
+	copy(hash[:], mac.Sum(nil))
+	return hash
+}
+
+// PageView records a view of a subreddit page by visitor (a user or
+// anonymous client key). Only a keyed hash of the key is stored, see
+// hashVisitor.
+func (r *Recorder) PageView(subredditID int, visitor string) {
+	hash := hashVisitor(visitor, time.Now())
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	b := r.bucket(subredditID)
+	b.pageViews++
+	b.visitors[hash] = struct{}{}
+}
+
+// Count adds one to a subreddit's metric for the current hour.
+func (r *Recorder) Count(subredditID int, m Metric) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.bucket(subredditID).metrics[m]++
+}
+
+// Flush adds the counts recorded since the last flush to the rollups. On
+// failure the counts are dropped; traffic is best effort.
+func (r *Recorder) Flush() error {
+	r.mu.Lock()
+	buckets := r.buckets
+	r.buckets = make(map[bucketKey]*bucket)
+	r.mu.Unlock()
+
+	if len(buckets) == 0 {
+		return nil
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Rows are written in key order so instances flushing the same hours
+	// lock them in the same order and can't deadlock.
+	keys := make([]bucketKey, 0, len(buckets))
+	for key := range buckets {
+		keys = append(keys, key)
+	}
+	slices.SortFunc(keys, func(a, b bucketKey) int {
+		return cmp.Or(cmp.Compare(a.subredditID, b.subredditID), a.hour.Compare(b.hour))
+	})
+
+	for _, key := range keys {
+		b := buckets[key]
+		visitors := make([][]byte, 0, len(b.visitors))
+		for v := range b.visitors {
+			visitors = append(visitors, v[:])
+		}
+
+		_, err := tx.Exec(`
+			INSERT INTO subreddit_traffic_visitors (subreddit_id, hour, visitor)
+			SELECT $1, $2, v FROM unnest($3::bytea[]) AS v
+			WHERE EXISTS (SELECT 1 FROM subreddits WHERE id = $1)
+			ON CONFLICT DO NOTHING
+		`, key.subredditID, key.hour, pq.ByteaArray(visitors))
+		if err != nil {
+			return fmt.Errorf("failed to record visitors: %w", err)
+		}
+
+		_, err = tx.Exec(`
+			INSERT INTO subreddit_traffic_hourly
+				(subreddit_id, hour, page_views, unique_visitors, subscriptions, unsubscriptions, posts, comments)
+			SELECT $1, $2, $3,
+			       (SELECT COUNT(*) FROM subreddit_traffic_visitors WHERE subreddit_id = $1 AND hour = $2),
+			       $4, $5, $6, $7
+			WHERE EXISTS (SELECT 1 FROM subreddits WHERE id = $1)
+			ON CONFLICT (subreddit_id, hour) DO UPDATE SET
+				page_views = subreddit_traffic_hourly.page_views + EXCLUDED.page_views,
+				unique_visitors = EXCLUDED.unique_visitors,
+				subscriptions = subreddit_traffic_hourly.subscriptions + EXCLUDED.subscriptions,
+				unsubscriptions = subreddit_traffic_hourly.unsubscriptions + EXCLUDED.unsubscriptions,
+				posts = subreddit_traffic_hourly.posts + EXCLUDED.posts,
+				comments = subreddit_traffic_hourly.comments + EXCLUDED.comments
+		`, key.subredditID, key.hour, b.pageViews,
+			b.metrics[Subscriptions], b.metrics[Unsubscriptions], b.metrics[Posts], b.metrics[Comments])
+		if err != nil {
+			return fmt.Errorf("failed to record traffic: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit traffic: %w", err)
+	}
+	return nil
+}
+
+// PruneVisitors deletes visitor sets older than VisitorRetention. The
+// hourly counters are kept.
+func PruneVisitors() error {
+	_, err := database.DB.Exec(`DELETE FROM subreddit_traffic_visitors WHERE hour < $1`,
+		time.Now().UTC().Add(-VisitorRetention))
+	if err != nil {
+		return fmt.Errorf("failed to prune traffic visitors: %w", err)
+	}
+	return nil
+}
+
+// Run flushes every interval and prunes old visitor sets daily until stop
+// is closed. Errors are logged and retried on the next tick.
+func (r *Recorder) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	prune := time.NewTicker(24 * time.Hour)
+	defer prune.Stop()
+	for {
+		select {
+		case <-stop:
+			if err := r.Flush(); err != nil {
+				log.Printf("analytics: flushing traffic failed: %v", err)
+			}
+			return
+		case <-ticker.C:
+			if err := r.Flush(); err != nil {
+				log.Printf("analytics: flushing traffic failed: %v", err)
+			}
+		case <-prune.C:
+			if err := PruneVisitors(); err != nil {
+				log.Printf("analytics: pruning traffic failed: %v", err)
+			}
+		}
+	}
+}
@@ -12,7 +12,9 @@ import (
 
 var DB *sql.DB
 
-func Connect() error {
+// ConnectionString builds the lib/pq connection string from the DB_*
+// environment variables.
+func ConnectionString() (string, error) {
 	// Load .env file (ignore error if not found - allows for system env vars)
 	_ = godotenv.Load()
 
@@ -26,16 +28,22 @@ func Connect() error {
 
 	// Validate required variables
 	if DB_HOST == "" || DB_PORT == "" || DB_USER == "" || DB_PASSWORD == "" || DB_NAME == "" {
-		return fmt.Errorf("missing required database environment variables - check .env file")
+		return "", fmt.Errorf("missing required database environment variables - check .env file")
 	}
-	fmt.Println(DB_NAME, DB_HOST, DB_USER)
 
 	// Build connection string
-	DB_URL := fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
-		DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE)
+	return fmt.Sprintf("host=%s port=%s user=%s password=%s dbname=%s sslmode=%s",
+		DB_HOST, DB_PORT, DB_USER, DB_PASSWORD, DB_NAME, DB_SSLMODE), nil
+}
+
+func Connect() error {
+	DB_URL, err := ConnectionString()
+	if err != nil {
+		return err
+	}
+	fmt.Println(os.Getenv("DB_NAME"), os.Getenv("DB_HOST"), os.Getenv("DB_USER"))
 
 	// Open database connection
-	var err error
 	DB, err = sql.Open("postgres", DB_URL)
 	if err != nil {
 		return fmt.Errorf("error opening database: %w", err)
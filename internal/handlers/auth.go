@@ -30,8 +30,8 @@ type ForgotPasswordInput struct {
 }
 
 type ResetPasswordInput struct {
-	Token    string `json:token`
-	Password string `json:password`
+	Token    string `json:"token"`
+	Password string `json:"password"`
 }
 
 func Register(c *gin.Context) {
@@ -176,6 +176,8 @@ func GetMe(c *gin.Context) {
 			"id":         user.ID,
 			"username":   user.Username,
 			"email":      user.Email,
+			"avatar_url": user.AvatarURL,
+			"bio":        user.Bio,
 			"created_at": user.CreatedAt,
 		},
 	})
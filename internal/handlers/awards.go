@@ -0,0 +1,244 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type AwardTypePayload struct {
+	Slug    string  `json:"slug"`
+	Name    string  `json:"name" binding:"required,max=64"`
+	IconURL *string `json:"icon_url"`
+	Cost    int     `json:"cost" binding:"min=0"` // 0 = free reaction
+}
+
+type GiveAwardPayload struct {
+	AwardID   int  `json:"award_id" binding:"required"`
+	Anonymous bool `json:"anonymous"`
+}
+
+// ListAwardTypes handles GET /api/subreddits/:name/awards
+//
+// Returns the site-wide awards plus the subreddit's own.
+func ListAwardTypes(c *gin.Context) {
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+
+	types, err := models.ListAwardTypes(&subreddit.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"awards": types})
+}
+
+// CreateAwardType handles POST /api/subreddits/:name/awards
+func CreateAwardType(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+	if !requireModerator(c, subreddit.ID, userID) {
+		return
+	}
+
+	var payload AwardTypePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slug := strings.ToLower(strings.TrimSpace(payload.Slug))
+	if !models.IsAwardSlug(slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must be 1-32 lowercase letters, digits, - or _"})
+		return
+	}
+
+	awardType := &models.AwardType{
+		SubredditID: &subreddit.ID,
+		Slug:        slug,
+		Name:        strings.TrimSpace(payload.Name),
+		IconURL:     payload.IconURL,
+		Cost:        payload.Cost,
+	}
+	err = models.CreateAwardType(awardType)
+	if errors.Is(err, models.ErrAwardTypeExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Award created",
+		"data":    awardType,
+	})
+}
+
+// RetireAwardType handles DELETE /api/awards/:id
+//
+// Moderators retire their subreddit's awards; site-wide awards need an
+// admin. Awards already given stay on their posts.
+func RetireAwardType(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	awardType, ok := awardTypeFromParam(c)
+	if !ok {
+		return
+	}
+
+	if awardType.SubredditID != nil {
+		if !requireModerator(c, *awardType.SubredditID, userID) {
+			return
+		}
+	} else {
+		isAdmin, err := models.IsAdmin(userID)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+		if !isAdmin {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can retire site-wide awards"})
+			return
+		}
+	}
+
+	if err := models.RetireAwardType(awardType.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Award retired"})
+}
+
+// GetAwardBalance handles GET /api/me/awards/balance
+func GetAwardBalance(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	balance, err := models.GetAwardBalance(userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": balance})
+}
+
+// GiveAward handles POST /api/posts/:id/awards
+func GiveAward(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload GiveAwardPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	awardType, err := models.GetAwardType(payload.AwardID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if awardType == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Award not found"})
+		return
+	}
+
+	award, err := models.GiveAward(post, awardType, userID, payload.Anonymous)
+	switch {
+	case errors.Is(err, models.ErrAwardUnavailable), errors.Is(err, models.ErrAwardOwnPost):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrInsufficientCoins):
+		c.JSON(http.StatusPaymentRequired, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrAlreadyReacted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrPostArchived):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Award given",
+		"data":    award,
+	})
+}
+
+// awardTypeFromParam loads the :id award type. On failure it writes the
+// error response and returns false.
+func awardTypeFromParam(c *gin.Context) (*models.AwardType, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid award ID"})
+		return nil, false
+	}
+
+	awardType, err := models.GetAwardType(id)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return nil, false
+	}
+	if awardType == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Award not found"})
+		return nil, false
+	}
+	return awardType, true
+}
@@ -0,0 +1,75 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// BlockUser handles POST /api/users/:username/block
+func BlockUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	target, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+	if target.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot block yourself"})
+		return
+	}
+
+	if err := models.BlockUser(userID, target.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to block user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User blocked"})
+}
+
+// UnblockUser handles DELETE /api/users/:username/block
+func UnblockUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	target, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.UnblockUser(userID, target.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unblock user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User unblocked"})
+}
+
+// ListBlockedUsers handles GET /api/me/blocks
+func ListBlockedUsers(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	users, err := models.ListBlockedUsers(userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"users": users})
+}
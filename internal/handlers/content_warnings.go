@@ -0,0 +1,265 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type ContentWarningPayload struct {
+	Slug        string  `json:"slug"`
+	Label       string  `json:"label" binding:"required,max=64"`
+	Description *string `json:"description"`
+}
+
+type ContentLabelsPayload struct {
+	IsSpoiler       bool     `json:"is_spoiler"`
+	ContentWarnings []string `json:"content_warnings"` // Slugs of the subreddit's content warnings
+}
+
+// contentFilterParams reads the listing filters for content labels:
+// hide_spoilers=true and exclude_warnings=slug,slug.
+func contentFilterParams(c *gin.Context, filter *models.PostFilter) {
+	filter.ExcludeSpoilers, _ = strconv.ParseBool(c.Query("hide_spoilers"))
+	for _, slug := range strings.Split(c.Query("exclude_warnings"), ",") {
+		if slug = strings.TrimSpace(slug); slug != "" {
+			filter.ExcludeWarnings = append(filter.ExcludeWarnings, slug)
+		}
+	}
+}
+
+// ListContentWarnings handles GET /api/subreddits/:name/content-warnings
+func ListContentWarnings(c *gin.Context) {
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+
+	warnings, err := models.ListContentWarnings(subreddit.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"content_warnings": warnings})
+}
+
+// CreateContentWarning handles POST /api/subreddits/:name/content-warnings
+func CreateContentWarning(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+	if !requireModerator(c, subreddit.ID, userID) {
+		return
+	}
+
+	var payload ContentWarningPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	slug := strings.ToLower(strings.TrimSpace(payload.Slug))
+	if !models.IsContentWarningSlug(slug) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "slug must be 1-32 lowercase letters, digits, - or _, and not nsfw or spoiler"})
+		return
+	}
+
+	warning := &models.ContentWarning{
+		SubredditID: subreddit.ID,
+		Slug:        slug,
+		Label:       strings.TrimSpace(payload.Label),
+		Description: payload.Description,
+	}
+	err = models.CreateContentWarning(warning)
+	if errors.Is(err, models.ErrContentWarningExists) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Content warning created",
+		"data":    warning,
+	})
+}
+
+// UpdateContentWarning handles PUT /api/content-warnings/:id
+func UpdateContentWarning(c *gin.Context) {
+	warning, ok := contentWarningFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload ContentWarningPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	warning.Label = strings.TrimSpace(payload.Label)
+	warning.Description = payload.Description
+	if err := models.UpdateContentWarning(warning); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content warning updated",
+		"data":    warning,
+	})
+}
+
+// DeleteContentWarning handles DELETE /api/content-warnings/:id
+func DeleteContentWarning(c *gin.Context) {
+	warning, ok := contentWarningFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteContentWarning(warning.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Content warning deleted"})
+}
+
+// SetPostContentLabels handles PUT /api/posts/:id/content-labels
+//
+// The author or a moderator can change the spoiler flag and content
+// warnings of a post. Only moderators can remove labels; authors can add
+// them.
+func SetPostContentLabels(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+	isMod, err := models.IsSubredditModerator(post.SubredditID, userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if post.AuthorID != userID && !isMod {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or a moderator can change content labels"})
+		return
+	}
+
+	var payload ContentLabelsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	warnings, err := models.ResolveContentWarnings(post.SubredditID, payload.ContentWarnings)
+	if errors.Is(err, models.ErrUnknownContentWarning) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err == nil {
+		err = models.SetPostContentLabels(post, payload.IsSpoiler, warnings, isMod)
+	}
+	if errors.Is(err, models.ErrContentLabelRemoval) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	if err := models.LoadPostDetails([]*models.Post{post}, &userID); err != nil {
+		log.Println(err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Content labels updated",
+		"data":    post,
+	})
+}
+
+// requireModerator checks that userID moderates subredditID. On failure it
+// writes the error response and returns false.
+func requireModerator(c *gin.Context, subredditID, userID int) bool {
+	isMod, err := models.IsSubredditModerator(subredditID, userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return false
+	}
+	if !isMod {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can do this"})
+		return false
+	}
+	return true
+}
+
+// contentWarningFromParam loads the :id content warning and checks that
+// the caller moderates its subreddit. On failure it writes the error
+// response and returns false.
+func contentWarningFromParam(c *gin.Context) (*models.ContentWarning, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return nil, false
+	}
+
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid content warning ID"})
+		return nil, false
+	}
+
+	warning, err := models.GetContentWarning(id)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return nil, false
+	}
+	if warning == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Content warning not found"})
+		return nil, false
+	}
+	if !requireModerator(c, warning.SubredditID, userID) {
+		return nil, false
+	}
+	return warning, true
+}
@@ -0,0 +1,112 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type CrosspostPayload struct {
+	SubredditID int     `json:"subreddit_id" binding:"required"`
+	Title       *string `json:"title" binding:"omitempty,min=3,max=300"` // Defaults to the original's title
+	IsNSFW      bool    `json:"is_nsfw"`
+}
+
+// CreateCrosspost handles POST /api/posts/:id/crosspost
+func CreateCrosspost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	original, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload CrosspostPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	target, err := models.GetSubredditByID(payload.SubredditID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if target == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+
+	title := original.Title
+	if payload.Title != nil && strings.TrimSpace(*payload.Title) != "" {
+		title = strings.TrimSpace(*payload.Title)
+	}
+
+	post, err := models.CreateCrosspost(original, target, userID, title, payload.IsNSFW)
+	switch {
+	case errors.Is(err, models.ErrCrosspostSameSubreddit):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrCrosspostPrivateSource),
+		errors.Is(err, models.ErrCrosspostPrivateTarget),
+		errors.Is(err, models.ErrCrosspostNSFW),
+		errors.Is(err, models.ErrCrosspostPoll),
+		errors.Is(err, models.ErrInsufficientKarma):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	if err := models.LoadPostDetails([]*models.Post{post}, &userID); err != nil {
+		log.Println(err)
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Crosspost Created Successfully",
+		"data":    post,
+	})
+}
+
+// ListOtherDiscussions handles GET /api/posts/:id/duplicates
+//
+// Other posts of the same original or the same link URL.
+func ListOtherDiscussions(c *gin.Context) {
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	posts, err := models.ListOtherDiscussions(post, viewerID(c), prefs.ShowNSFW, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(posts),
+		},
+	})
+}
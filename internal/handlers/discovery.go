@@ -0,0 +1,151 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// ListCategories handles GET /api/discover/categories
+func ListCategories(c *gin.Context) {
+	categories, err := models.ListCategories()
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+// ListTrendingSubreddits handles GET /api/discover/trending
+//
+// Public subreddits growing fastest over the last day. Accepts the same
+// filters as the subreddit listing.
+func ListTrendingSubreddits(c *gin.Context) {
+	filter, ok := subredditFilterParams(c)
+	if !ok {
+		return
+	}
+	limit := discoveryLimit(c)
+
+	trending, err := models.ListTrendingSubreddits(limit, filter)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	for _, t := range trending {
+		withLiveActiveUsers(t.Subreddit)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subreddits": trending})
+}
+
+// ListSimilarSubreddits handles GET /api/subreddits/:name/similar
+func ListSimilarSubreddits(c *gin.Context) {
+	subreddit, ok := subredditFromParam(c)
+	if !ok {
+		return
+	}
+	includeNSFW, ok := includeNSFWParam(c)
+	if !ok {
+		return
+	}
+	limit := discoveryLimit(c)
+
+	similar, err := models.ListSimilarSubreddits(subreddit, limit, includeNSFW)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	for _, s := range similar {
+		withLiveActiveUsers(s.Subreddit)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subreddits": similar})
+}
+
+// discoveryLimit reads the limit query parameter, 10 by default and at
+// most 50.
+func discoveryLimit(c *gin.Context) int {
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 50 {
+		limit = 10
+	}
+	return limit
+}
+
+// subredditFilterParams reads the subreddit listing filters: nsfw,
+// category, language and min_members. On failure it writes the error
+// response and returns false.
+func subredditFilterParams(c *gin.Context) (models.SubredditFilter, bool) {
+	var filter models.SubredditFilter
+
+	includeNSFW, ok := includeNSFWParam(c)
+	if !ok {
+		return filter, false
+	}
+	filter.IncludeNSFW = includeNSFW
+	filter.Category = strings.ToLower(strings.TrimSpace(c.Query("category")))
+
+	if language := strings.TrimSpace(c.Query("language")); language != "" {
+		if !models.IsLanguageTag(language) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "language must be a language tag such as en or pt-BR"})
+			return filter, false
+		}
+		filter.Language = language
+	}
+
+	if minMembers := c.Query("min_members"); minMembers != "" {
+		n, err := strconv.Atoi(minMembers)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "min_members must be a non-negative number"})
+			return filter, false
+		}
+		filter.MinMembers = n
+	}
+
+	return filter, true
+}
+
+// categoriesParam normalizes the category slugs of a subreddit payload and
+// checks them against the catalogue. On failure it writes the error
+// response and returns false.
+func categoriesParam(c *gin.Context, slugs []string) ([]string, bool) {
+	catalogue, err := models.ListCategories()
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return nil, false
+	}
+	known := make(map[string]bool, len(catalogue))
+	for _, category := range catalogue {
+		known[category.Slug] = true
+	}
+
+	categories := []string{}
+	seen := make(map[string]bool)
+	for _, slug := range slugs {
+		slug = strings.ToLower(strings.TrimSpace(slug))
+		if !known[slug] {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "unknown category: " + slug})
+			return nil, false
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			categories = append(categories, slug)
+		}
+	}
+	if len(categories) > models.MaxSubredditCategories {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d categories are allowed", models.MaxSubredditCategories)})
+		return nil, false
+	}
+	return categories, true
+}
@@ -0,0 +1,368 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type DraftPayload struct {
+	SubredditID *int       `json:"subreddit_id"`
+	Title       string     `json:"title" binding:"max=300"`
+	Content     *string    `json:"content"`
+	PostType    string     `json:"post_type"`
+	LinkURL     *string    `json:"link_url"`
+	ImageURL    *string    `json:"image_url"`
+	IsNSFW      bool       `json:"is_nsfw"`
+	IsSpoiler   bool       `json:"is_spoiler"`
+	PublishAt   *time.Time `json:"publish_at"` // Schedules the draft; omit to keep it unscheduled
+	Recurrence  *string    `json:"recurrence"` // daily, weekly or monthly; moderators only
+
+	ContentWarnings []string `json:"content_warnings"` // Slugs of the subreddit's content warnings
+}
+
+// ListDrafts handles GET /api/me/drafts?status=draft|scheduled|published|cancelled|failed
+func ListDrafts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	status := c.Query("status")
+	switch status {
+	case "", models.DraftStatusDraft, models.DraftStatusScheduled, models.DraftStatusPublished,
+		models.DraftStatusCancelled, models.DraftStatusFailed:
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown draft status: " + status})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	drafts, err := models.ListDrafts(userID, status, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"drafts": drafts,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(drafts),
+		},
+	})
+}
+
+// CreateDraft handles POST /api/me/drafts
+func CreateDraft(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	var payload DraftPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	draft := &models.PostDraft{AuthorID: userID}
+	if !applyDraftPayload(c, draft, &payload) {
+		return
+	}
+
+	draft, err := models.CreateDraft(draft)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save draft"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Draft saved",
+		"data":    draft,
+	})
+}
+
+// GetDraft handles GET /api/me/drafts/:id
+func GetDraft(c *gin.Context) {
+	draft, ok := draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"data": draft})
+}
+
+// UpdateDraft handles PUT /api/me/drafts/:id
+//
+// The body replaces the draft. Setting publish_at schedules it; leaving it
+// out turns a scheduled draft back into an unscheduled one.
+func UpdateDraft(c *gin.Context) {
+	draft, ok := draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload DraftPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !applyDraftPayload(c, draft, &payload) {
+		return
+	}
+
+	err := models.UpdateDraft(draft)
+	if errors.Is(err, models.ErrDraftNotEditable) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save draft"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Draft saved",
+		"data":    draft,
+	})
+}
+
+// CancelDraft handles POST /api/me/drafts/:id/cancel
+//
+// Stops a scheduled (or recurring) draft from publishing.
+func CancelDraft(c *gin.Context) {
+	draft, ok := draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	cancelled, err := models.CancelDraft(draft.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !cancelled {
+		c.JSON(http.StatusConflict, gin.H{"error": "Only scheduled drafts can be cancelled"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Scheduled post cancelled"})
+}
+
+// PublishDraft handles POST /api/me/drafts/:id/publish
+func PublishDraft(c *gin.Context) {
+	draft, ok := draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	switch draft.Status {
+	case models.DraftStatusPublished, models.DraftStatusCancelled:
+		c.JSON(http.StatusConflict, gin.H{"error": models.ErrDraftNotEditable.Error()})
+		return
+	}
+	if !checkDraftPublishable(c, draft) {
+		return
+	}
+
+	post, err := models.PublishDraft(draft)
+	if errors.Is(err, models.ErrUnknownContentWarning) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrDraftChanged) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, models.ErrInsufficientKarma) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Post Created Successfully",
+		"data":    post,
+		"draft":   draft,
+	})
+}
+
+// DeleteDraft handles DELETE /api/me/drafts/:id
+func DeleteDraft(c *gin.Context) {
+	draft, ok := draftFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.DeleteDraft(draft.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Draft deleted"})
+}
+
+// applyDraftPayload validates payload and copies it onto draft, deriving
+// the status from publish_at. On failure it writes the error response and
+// returns false.
+func applyDraftPayload(c *gin.Context, draft *models.PostDraft, payload *DraftPayload) bool {
+	draft.SubredditID = payload.SubredditID
+	draft.Title = strings.TrimSpace(payload.Title)
+	draft.Content = payload.Content
+	draft.PostType = payload.PostType
+	draft.LinkURL = payload.LinkURL
+	draft.ImageURL = payload.ImageURL
+	draft.IsNSFW = payload.IsNSFW
+	draft.IsSpoiler = payload.IsSpoiler
+	draft.ContentWarnings = payload.ContentWarnings
+	if draft.ContentWarnings == nil {
+		draft.ContentWarnings = []string{}
+	}
+	draft.PublishAt = payload.PublishAt
+	draft.Recurrence = payload.Recurrence
+	draft.Status = models.DraftStatusDraft
+
+	if draft.Content != nil && len(*draft.Content) > models.MaxPostContentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content must be at most 40000 bytes"})
+		return false
+	}
+
+	if draft.PostType == "" {
+		draft.PostType = "text"
+	}
+	switch draft.PostType {
+	case "text", "link", "image":
+	case models.PostTypePoll, models.PostTypeGallery, models.PostTypeVideo:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Polls, galleries and videos can't be saved as drafts or scheduled; post them directly"})
+		return false
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "post_type must be one of: text, link, image"})
+		return false
+	}
+
+	if len(draft.ContentWarnings) > 0 {
+		if draft.SubredditID == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "content_warnings require subreddit_id"})
+			return false
+		}
+		_, err := models.ResolveContentWarnings(*draft.SubredditID, draft.ContentWarnings)
+		if errors.Is(err, models.ErrUnknownContentWarning) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return false
+		}
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return false
+		}
+	}
+
+	if draft.PublishAt == nil {
+		if draft.Recurrence != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "recurrence requires publish_at"})
+			return false
+		}
+		return true
+	}
+
+	if !draft.PublishAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "publish_at must be in the future"})
+		return false
+	}
+	if !checkDraftPublishable(c, draft) {
+		return false
+	}
+
+	if draft.Recurrence != nil {
+		if !models.IsRecurrence(*draft.Recurrence) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "recurrence must be one of: daily, weekly, monthly"})
+			return false
+		}
+		isMod, err := models.IsSubredditModerator(*draft.SubredditID, draft.AuthorID)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return false
+		}
+		if !isMod {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can schedule recurring posts"})
+			return false
+		}
+	}
+
+	draft.Status = models.DraftStatusScheduled
+	return true
+}
+
+// checkDraftPublishable checks a draft has what a post needs. On failure it
+// writes the error response and returns false.
+func checkDraftPublishable(c *gin.Context, draft *models.PostDraft) bool {
+	if utf8.RuneCountInString(draft.Title) < 3 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Title must be at least 3 characters"})
+		return false
+	}
+	if draft.SubredditID == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "subreddit_id is required"})
+		return false
+	}
+
+	subreddit, err := models.GetSubredditByID(*draft.SubredditID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return false
+	}
+	return true
+}
+
+// draftFromParam loads the caller's draft named by the :id route
+// parameter. On failure it writes the error response and returns false.
+func draftFromParam(c *gin.Context) (*models.PostDraft, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return nil, false
+	}
+
+	draftID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid draft ID"})
+		return nil, false
+	}
+
+	draft, err := models.GetDraft(draftID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if draft == nil || draft.AuthorID != userID {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Draft not found"})
+		return nil, false
+	}
+	return draft, true
+}
@@ -0,0 +1,147 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// FollowUser handles POST /api/users/:username/follow
+func FollowUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	target, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+	if target.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot follow yourself"})
+		return
+	}
+
+	err := models.FollowUser(userID, target.ID)
+	if errors.Is(err, models.ErrFollowNotAllowed) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to follow user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Following u/" + target.Username})
+}
+
+// UnfollowUser handles DELETE /api/users/:username/follow
+func UnfollowUser(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	target, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.UnfollowUser(userID, target.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unfollow user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Unfollowed u/" + target.Username})
+}
+
+// ListFollowers handles GET /api/users/:username/followers
+func ListFollowers(c *gin.Context) {
+	listFollows(c, models.ListFollowers)
+}
+
+// ListFollowing handles GET /api/users/:username/following
+func ListFollowing(c *gin.Context) {
+	listFollows(c, models.ListFollowing)
+}
+
+func listFollows(c *gin.Context, list func(userID, limit, offset int) ([]*models.FollowEntry, error)) {
+	user, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+	if !checkProfileVisible(c, user) {
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	users, err := list(user.ID, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"users": users,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(users),
+		},
+	})
+}
+
+// GetFollowingFeed handles GET /api/me/feed?sort=new|top
+//
+// Posts by the users the caller follows, newest first by default.
+func GetFollowingFeed(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "new")
+	if sort != "top" && sort != "new" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: top, new"})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	filter := models.PostFilter{
+		FollowedBy:  &userID,
+		ViewerID:    &userID,
+		IncludeNSFW: prefs.ShowNSFW,
+		Sort:        sort,
+	}
+	contentFilterParams(c, &filter)
+
+	posts, err := models.ListPosts(limit, offset, filter)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(posts),
+		},
+	})
+}
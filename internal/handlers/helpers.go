@@ -0,0 +1,117 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// parsePagination reads either page/per_page or limit/offset query
+// parameters and clamps them to sane bounds.
+func parsePagination(c *gin.Context) (limit, offset int) {
+	pageStr := c.Query("page")
+	if pageStr != "" {
+		page, _ := strconv.Atoi(pageStr)
+		perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+
+		if page < 1 {
+			page = 1
+		}
+
+		offset = (page - 1) * perPage
+		limit = perPage
+	} else {
+		limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
+		offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
+	}
+
+	if limit < 1 {
+		limit = 20
+	}
+	if limit > 100 {
+		limit = 100
+	}
+	if offset < 0 {
+		offset = 0
+	}
+	return limit, offset
+}
+
+// currentUserID returns the authenticated user's ID as set by the auth
+// middleware.
+func currentUserID(c *gin.Context) (int, bool) {
+	userID, exists := c.Get("user_id")
+	if !exists {
+		return 0, false
+	}
+	id, ok := userID.(int)
+	return id, ok
+}
+
+// viewerID returns the authenticated user's ID on routes where
+// authentication is optional, or nil for anonymous requests.
+func viewerID(c *gin.Context) *int {
+	if id, ok := currentUserID(c); ok {
+		return &id
+	}
+	return nil
+}
+
+// postFromParam loads the post named by the :id route parameter. On failure
+// it writes the error response and returns false.
+func postFromParam(c *gin.Context) (*models.Post, bool) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return nil, false
+	}
+
+	post, err := models.GetPostByID(postID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return nil, false
+	}
+	if post == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+		return nil, false
+	}
+	return post, true
+}
+
+// subredditFromParam loads the subreddit named by the :name route
+// parameter. On failure it writes the error response and returns false.
+func subredditFromParam(c *gin.Context) (*models.Subreddit, bool) {
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return nil, false
+	}
+	return subreddit, true
+}
+
+// viewerPreferences returns the authenticated user's preferences, or the
+// defaults for anonymous requests. On failure it writes the error response
+// and returns false.
+func viewerPreferences(c *gin.Context) (*models.Preferences, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return models.DefaultPreferences(), true
+	}
+
+	prefs, err := models.GetUserPreferences(userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	return prefs, true
+}
@@ -0,0 +1,86 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// GetUserKarma handles GET /api/users/:username/karma
+func GetUserKarma(c *gin.Context) {
+	user, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	breakdown, err := models.GetSubredditKarmaBreakdown(user.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post_karma":    user.PostKarma,
+		"comment_karma": user.CommentKarma,
+		"total":         user.PostKarma + user.CommentKarma,
+		"subreddits":    breakdown,
+	})
+}
+
+// ListKarmaLedger handles GET /api/me/karma/ledger
+func ListKarmaLedger(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+
+	entries, err := models.ListKarmaLedger(userID, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"entries": entries,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(entries),
+		},
+	})
+}
+
+// RecomputeKarma handles POST /api/me/karma/recompute, rebuilding the
+// caller's cached karma from the ledger.
+func RecomputeKarma(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	if err := models.RecomputeUserKarma(userID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to recompute karma"})
+		return
+	}
+
+	user, err := models.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post_karma":    user.PostKarma,
+		"comment_karma": user.CommentKarma,
+		"total":         user.PostKarma + user.CommentKarma,
+	})
+}
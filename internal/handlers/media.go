@@ -0,0 +1,134 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/media"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+const (
+	maxGalleryCaptionLength = 180
+	videoProbeTimeout       = 20 * time.Second
+)
+
+var errIncompleteVideo = errors.New("video is missing its duration or dimensions")
+
+type GalleryItemPayload struct {
+	MediaURL    string  `json:"media_url"`
+	Caption     *string `json:"caption"`
+	OutboundURL *string `json:"outbound_url"`
+}
+
+// buildGallery validates gallery items, keeping their order.
+func buildGallery(payload []GalleryItemPayload) ([]*models.GalleryItem, error) {
+	if len(payload) < models.MinGalleryItems || len(payload) > models.MaxGalleryItems {
+		return nil, fmt.Errorf("a gallery needs between %d and %d items", models.MinGalleryItems, models.MaxGalleryItems)
+	}
+
+	items := make([]*models.GalleryItem, 0, len(payload))
+	for i, p := range payload {
+		item := &models.GalleryItem{MediaURL: strings.TrimSpace(p.MediaURL)}
+		if media.ValidateURL(item.MediaURL) != nil {
+			return nil, fmt.Errorf("gallery item %d: media_url must be an http(s) URL", i+1)
+		}
+
+		if p.Caption != nil {
+			if caption := strings.TrimSpace(*p.Caption); caption != "" {
+				if len([]rune(caption)) > maxGalleryCaptionLength {
+					return nil, fmt.Errorf("gallery item %d: captions can be at most %d characters", i+1, maxGalleryCaptionLength)
+				}
+				item.Caption = &caption
+			}
+		}
+
+		if p.OutboundURL != nil {
+			if link := strings.TrimSpace(*p.OutboundURL); link != "" {
+				if media.ValidateURL(link) != nil {
+					return nil, fmt.Errorf("gallery item %d: outbound_url must be an http(s) URL", i+1)
+				}
+				item.OutboundURL = &link
+			}
+		}
+
+		items = append(items, item)
+	}
+	return items, nil
+}
+
+// buildVideo fetches the video at videoURL, extracts its metadata and
+// renders its thumbnail.
+func buildVideo(ctx context.Context, videoURL string) (*models.Video, error) {
+	ctx, cancel := context.WithTimeout(ctx, videoProbeTimeout)
+	defer cancel()
+
+	videoURL = strings.TrimSpace(videoURL)
+	info, err := media.ProbeURL(ctx, videoURL)
+	if err != nil {
+		return nil, err
+	}
+	if info.Width == 0 || info.Height == 0 || info.Duration <= 0 {
+		return nil, errIncompleteVideo
+	}
+
+	thumbnail, source, err := media.Thumbnail(info)
+	if err != nil {
+		return nil, err
+	}
+
+	return &models.Video{
+		VideoURL:        videoURL,
+		DurationMS:      int(info.Duration.Milliseconds()),
+		Width:           info.Width,
+		Height:          info.Height,
+		Thumbnail:       thumbnail,
+		ThumbnailSource: source,
+	}, nil
+}
+
+// respondVideoError reports why a video couldn't be used. Fetch errors are
+// logged rather than echoed, since they describe the remote host.
+func respondVideoError(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, media.ErrInvalidMediaURL):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+	case errors.Is(err, media.ErrUnsupportedVideo),
+		errors.Is(err, media.ErrNoVideoTrack),
+		errors.Is(err, media.ErrVideoTooLarge),
+		errors.Is(err, errIncompleteVideo):
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": err.Error()})
+	default:
+		log.Println(err)
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"error": "Could not fetch the video"})
+	}
+}
+
+// GetVideoThumbnail handles GET /api/posts/:id/video/thumbnail
+func GetVideoThumbnail(c *gin.Context) {
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	thumbnail, err := models.GetVideoThumbnail(post.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if thumbnail == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Post has no video"})
+		return
+	}
+
+	// Thumbnails never change once the post is created.
+	c.Header("Cache-Control", "public, max-age=86400")
+	c.Data(http.StatusOK, "image/png", thumbnail)
+}
@@ -0,0 +1,56 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// ListMentions handles GET /api/me/mentions
+//
+// Posts that currently mention the caller with u/username, newest first.
+func ListMentions(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "new")
+	if sort != "top" && sort != "new" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: top, new"})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	filter := models.PostFilter{
+		MentionedUserID: &userID,
+		ViewerID:        &userID,
+		IncludeNSFW:     prefs.ShowNSFW,
+		Sort:            sort,
+	}
+	contentFilterParams(c, &filter)
+
+	posts, err := models.ListPosts(limit, offset, filter)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(posts),
+		},
+	})
+}
@@ -0,0 +1,238 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+const maxMessageLength = 10000
+
+type SendMessagePayload struct {
+	To   string `json:"to"`
+	Body string `json:"body" binding:"required"`
+}
+
+// ListConversations handles GET /api/conversations
+func ListConversations(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	conversations, err := models.ListConversations(userID, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversations": conversations,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(conversations),
+		},
+	})
+}
+
+// StartConversation handles POST /api/conversations
+//
+// It sends {"to": username, "body": ...}, reusing the existing conversation
+// with that user if there is one.
+func StartConversation(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	var payload SendMessagePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	recipient, err := models.GetUserByUsername(strings.TrimSpace(payload.To))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if recipient == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
+	if recipient.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You cannot message yourself"})
+		return
+	}
+
+	sendMessage(c, userID, recipient.ID, payload.Body)
+}
+
+// GetConversation handles GET /api/conversations/:id
+func GetConversation(c *gin.Context) {
+	userID, conv, ok := conversationFromParam(c)
+	if !ok {
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	messages, err := models.ListMessages(conv.ID, userID, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"conversation": conv,
+		"messages":     messages,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(messages),
+		},
+	})
+}
+
+// ReplyToConversation handles POST /api/conversations/:id/messages
+func ReplyToConversation(c *gin.Context) {
+	userID, conv, ok := conversationFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload SendMessagePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	sendMessage(c, userID, conv.OtherUser.ID, payload.Body)
+}
+
+// MarkConversationRead handles POST /api/conversations/:id/read
+func MarkConversationRead(c *gin.Context) {
+	userID, conv, ok := conversationFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.MarkConversationRead(conv.ID, userID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Conversation marked as read"})
+}
+
+// DeleteMessage handles DELETE /api/messages/:id
+//
+// The message is only removed from the caller's view.
+func DeleteMessage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	messageID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid message ID"})
+		return
+	}
+
+	deleted, err := models.DeleteMessageForUser(messageID, userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if !deleted {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Message not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Message deleted"})
+}
+
+func sendMessage(c *gin.Context, senderID, recipientID int, body string) {
+	body = strings.TrimSpace(body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message body is required"})
+		return
+	}
+	if len(body) > maxMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message is too long"})
+		return
+	}
+
+	msg, err := models.SendDirectMessage(senderID, recipientID, body)
+	if errors.Is(err, models.ErrMessagingBlocked) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't message this user"})
+		return
+	}
+	if !respondMessageError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Message sent",
+		"data":    msg,
+	})
+}
+
+// respondMessageError writes the response for errors shared by direct
+// messages and modmail. It returns true when err is nil.
+func respondMessageError(c *gin.Context, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, models.ErrMessageRateLimited):
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": err.Error()})
+	default:
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send message"})
+	}
+	return false
+}
+
+// conversationFromParam loads the caller's conversation named by the :id
+// route parameter. On failure it writes the error response and returns
+// false.
+func conversationFromParam(c *gin.Context) (int, *models.Conversation, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return 0, nil, false
+	}
+
+	conversationID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid conversation ID"})
+		return 0, nil, false
+	}
+
+	conv, err := models.GetConversation(conversationID, userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return 0, nil, false
+	}
+	if conv == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Conversation not found"})
+		return 0, nil, false
+	}
+	return userID, conv, true
+}
@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type DistinguishPayload struct {
+	As string `json:"as" binding:"required,oneof=moderator admin"`
+}
+
+type SuggestedSortPayload struct {
+	Sort string `json:"sort" binding:"required"`
+}
+
+// moderatedPostFromParam loads the :id post and checks that the caller
+// moderates its subreddit. On failure it writes the error response and
+// returns false.
+func moderatedPostFromParam(c *gin.Context) (*models.Post, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return nil, false
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return nil, false
+	}
+
+	if !requireModerator(c, post.SubredditID, userID) {
+		return nil, false
+	}
+	return post, true
+}
+
+// respondModeratedPost writes the result of a moderation action.
+func respondModeratedPost(c *gin.Context, post *models.Post, err error, message string) {
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message, "data": post})
+}
+
+// LockPost handles POST /api/posts/:id/lock
+func LockPost(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+	respondModeratedPost(c, post, models.SetPostLocked(post, true), "Post locked")
+}
+
+// UnlockPost handles DELETE /api/posts/:id/lock
+func UnlockPost(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+	respondModeratedPost(c, post, models.SetPostLocked(post, false), "Post unlocked")
+}
+
+// StickyPost handles POST /api/posts/:id/sticky
+func StickyPost(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+
+	err := models.StickyPost(post)
+	if errors.Is(err, models.ErrStickyLimit) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	respondModeratedPost(c, post, err, "Post stickied")
+}
+
+// UnstickyPost handles DELETE /api/posts/:id/sticky
+func UnstickyPost(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+	respondModeratedPost(c, post, models.UnstickyPost(post), "Post unstickied")
+}
+
+// EnableContestMode handles POST /api/posts/:id/contest-mode
+func EnableContestMode(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+	respondModeratedPost(c, post, models.SetPostContestMode(post, true), "Contest mode enabled")
+}
+
+// DisableContestMode handles DELETE /api/posts/:id/contest-mode
+func DisableContestMode(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+	respondModeratedPost(c, post, models.SetPostContestMode(post, false), "Contest mode disabled")
+}
+
+// SetSuggestedSort handles PUT /api/posts/:id/suggested-sort
+func SetSuggestedSort(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload SuggestedSortPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.IsSuggestedSort(payload.Sort) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: " + strings.Join(models.SuggestedSorts, ", ")})
+		return
+	}
+
+	respondModeratedPost(c, post, models.SetPostSuggestedSort(post, &payload.Sort), "Suggested sort updated")
+}
+
+// ClearSuggestedSort handles DELETE /api/posts/:id/suggested-sort
+func ClearSuggestedSort(c *gin.Context) {
+	post, ok := moderatedPostFromParam(c)
+	if !ok {
+		return
+	}
+	respondModeratedPost(c, post, models.SetPostSuggestedSort(post, nil), "Suggested sort cleared")
+}
+
+// DistinguishPost handles PUT /api/posts/:id/distinguish
+//
+// Authors distinguish their own posts: as moderator in subreddits they
+// moderate, or as admin if they are a site administrator.
+func DistinguishPost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+	if post.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only distinguish your own posts"})
+		return
+	}
+
+	var payload DistinguishPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	var allowed bool
+	var err error
+	if payload.As == models.DistinguishAdmin {
+		allowed, err = models.IsAdmin(userID)
+	} else {
+		allowed, err = models.IsSubredditModerator(post.SubredditID, userID)
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if !allowed {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't distinguish posts as " + payload.As})
+		return
+	}
+
+	respondModeratedPost(c, post, models.SetPostDistinguished(post, &payload.As), "Post distinguished")
+}
+
+// UndistinguishPost handles DELETE /api/posts/:id/distinguish
+func UndistinguishPost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+	if post.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can only distinguish your own posts"})
+		return
+	}
+
+	respondModeratedPost(c, post, models.SetPostDistinguished(post, nil), "Post undistinguished")
+}
@@ -0,0 +1,246 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type CreateModmailPayload struct {
+	Subject string `json:"subject" binding:"required,max=200"`
+	Body    string `json:"body" binding:"required"`
+}
+
+type ModmailReplyPayload struct {
+	Body     string `json:"body" binding:"required"`
+	Internal bool   `json:"internal"` // Moderator-only note
+}
+
+// CreateModmail handles POST /api/subreddits/:name/modmail
+func CreateModmail(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+
+	var payload CreateModmailPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	subject := strings.TrimSpace(payload.Subject)
+	body := strings.TrimSpace(payload.Body)
+	if subject == "" || body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Subject and body are required"})
+		return
+	}
+	if len(body) > maxMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message is too long"})
+		return
+	}
+
+	thread, err := models.CreateModmailThread(subreddit.ID, userID, subject, body)
+	if !respondMessageError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Message sent to the moderators",
+		"data":    thread,
+	})
+}
+
+// ListModmail handles GET /api/modmail
+//
+// Query parameters:
+//
+//	subreddit  restrict to one subreddit name
+//	archived   list archived threads instead of the inbox when "true"
+func ListModmail(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	var filter models.ModmailFilter
+	filter.Archived, _ = strconv.ParseBool(c.DefaultQuery("archived", "false"))
+	if name := c.Query("subreddit"); name != "" {
+		subreddit, err := models.GetSubredditByName(name)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if subreddit == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+			return
+		}
+		filter.SubredditID = &subreddit.ID
+	}
+
+	limit, offset := parsePagination(c)
+	threads, err := models.ListModmailThreads(userID, filter, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"threads": threads,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(threads),
+		},
+	})
+}
+
+// GetModmail handles GET /api/modmail/:id
+func GetModmail(c *gin.Context) {
+	_, thread, isMod, ok := modmailFromParam(c)
+	if !ok {
+		return
+	}
+
+	messages, err := models.ListModmailMessages(thread.ID, isMod)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"thread":       thread,
+		"messages":     messages,
+		"is_moderator": isMod,
+	})
+}
+
+// ReplyToModmail handles POST /api/modmail/:id/messages
+func ReplyToModmail(c *gin.Context) {
+	userID, thread, isMod, ok := modmailFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload ModmailReplyPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	body := strings.TrimSpace(payload.Body)
+	if body == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message body is required"})
+		return
+	}
+	if len(body) > maxMessageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Message is too long"})
+		return
+	}
+	if payload.Internal && !isMod {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can add internal notes"})
+		return
+	}
+
+	msg, err := models.AddModmailMessage(thread, userID, body, payload.Internal, isMod)
+	if !respondMessageError(c, err) {
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Reply sent",
+		"data":    msg,
+	})
+}
+
+// ArchiveModmail handles POST /api/modmail/:id/archive
+func ArchiveModmail(c *gin.Context) {
+	setModmailArchived(c, true)
+}
+
+// UnarchiveModmail handles DELETE /api/modmail/:id/archive
+func UnarchiveModmail(c *gin.Context) {
+	setModmailArchived(c, false)
+}
+
+func setModmailArchived(c *gin.Context, archived bool) {
+	_, thread, isMod, ok := modmailFromParam(c)
+	if !ok {
+		return
+	}
+	if !isMod {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only moderators can archive modmail"})
+		return
+	}
+
+	if err := models.SetModmailArchived(thread.ID, archived); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	message := "Thread archived"
+	if !archived {
+		message = "Thread unarchived"
+	}
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// modmailFromParam loads the thread named by the :id route parameter and
+// checks the caller is its author or a moderator of its subreddit. On
+// failure it writes the error response and returns false.
+func modmailFromParam(c *gin.Context) (int, *models.ModmailThread, bool, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return 0, nil, false, false
+	}
+
+	threadID, err := strconv.ParseInt(c.Param("id"), 10, 64)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid thread ID"})
+		return 0, nil, false, false
+	}
+
+	thread, err := models.GetModmailThread(threadID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return 0, nil, false, false
+	}
+
+	isMod := false
+	if thread != nil {
+		isMod, err = models.IsSubredditModerator(thread.SubredditID, userID)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return 0, nil, false, false
+		}
+	}
+
+	isAuthor := thread != nil && thread.AuthorID != nil && *thread.AuthorID == userID
+	if thread == nil || (!isMod && !isAuthor) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Thread not found"})
+		return 0, nil, false, false
+	}
+	return userID, thread, isMod, true
+}
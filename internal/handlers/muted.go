@@ -0,0 +1,79 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// ListMutedSubreddits handles GET /api/me/muted-subreddits
+func ListMutedSubreddits(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	subreddits, err := models.ListMutedSubreddits(userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subreddits": subreddits})
+}
+
+// MuteSubreddit handles PUT /api/me/muted-subreddits/:name
+func MuteSubreddit(c *gin.Context) {
+	userID, subreddit, ok := mutedSubredditFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.MuteSubreddit(userID, subreddit.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to mute subreddit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subreddit muted"})
+}
+
+// UnmuteSubreddit handles DELETE /api/me/muted-subreddits/:name
+func UnmuteSubreddit(c *gin.Context) {
+	userID, subreddit, ok := mutedSubredditFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.UnmuteSubreddit(userID, subreddit.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unmute subreddit"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Subreddit unmuted"})
+}
+
+func mutedSubredditFromParam(c *gin.Context) (int, *models.Subreddit, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return 0, nil, false
+	}
+
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return 0, nil, false
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return 0, nil, false
+	}
+	return userID, subreddit, true
+}
@@ -0,0 +1,125 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+const (
+	maxPollOptionLength = 120
+	maxPollDurationDays = 7
+	defPollDurationDays = 3
+)
+
+type PollPayload struct {
+	Options           []string `json:"options"`
+	DurationDays      int      `json:"duration_days"`      // 1-7, defaults to 3
+	ResultsVisibility string   `json:"results_visibility"` // after_vote (default) or after_close
+}
+
+type PollVotePayload struct {
+	OptionID int `json:"option_id"`
+}
+
+// buildPoll validates a poll payload and turns it into a poll closing
+// DurationDays from now.
+func buildPoll(payload *PollPayload) (*models.Poll, error) {
+	if len(payload.Options) < models.MinPollOptions || len(payload.Options) > models.MaxPollOptions {
+		return nil, fmt.Errorf("a poll needs between %d and %d options", models.MinPollOptions, models.MaxPollOptions)
+	}
+
+	poll := &models.Poll{ResultsVisibility: payload.ResultsVisibility}
+	if poll.ResultsVisibility == "" {
+		poll.ResultsVisibility = models.PollResultsAfterVote
+	}
+	if !models.IsPollResultsVisibility(poll.ResultsVisibility) {
+		return nil, errors.New("results_visibility must be one of: after_vote, after_close")
+	}
+
+	days := payload.DurationDays
+	if days == 0 {
+		days = defPollDurationDays
+	}
+	if days < 1 || days > maxPollDurationDays {
+		return nil, fmt.Errorf("duration_days must be between 1 and %d", maxPollDurationDays)
+	}
+	poll.ClosesAt = time.Now().Add(time.Duration(days) * 24 * time.Hour)
+
+	seen := make(map[string]bool)
+	for _, text := range payload.Options {
+		text = strings.TrimSpace(text)
+		if text == "" {
+			return nil, errors.New("poll options can't be empty")
+		}
+		if len([]rune(text)) > maxPollOptionLength {
+			return nil, fmt.Errorf("poll options can be at most %d characters", maxPollOptionLength)
+		}
+		key := strings.ToLower(text)
+		if seen[key] {
+			return nil, errors.New("poll options must be distinct")
+		}
+		seen[key] = true
+		poll.Options = append(poll.Options, &models.PollOption{Text: text})
+	}
+
+	return poll, nil
+}
+
+// VotePoll handles POST /api/posts/:id/poll/vote
+func VotePoll(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+	if post.PostType != models.PostTypePoll {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Post is not a poll"})
+		return
+	}
+
+	var payload PollVotePayload
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
+		return
+	}
+
+	err := models.VotePoll(post.ID, userID, payload.OptionID)
+	switch {
+	case errors.Is(err, models.ErrPollClosed), errors.Is(err, models.ErrPostArchived):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrPollAlreadyVoted):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrPollInvalidOption):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	if err := models.LoadPostDetails([]*models.Post{post}, &userID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vote recorded",
+		"data":    post.Poll,
+	})
+}
@@ -1,10 +1,14 @@
 package handlers
 
 import (
+	"errors"
 	"log"
 	"net/http"
+	"strings"
+	"unicode/utf8"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/analytics"
 	"github.com/kshzz24/gosocial/internal/models"
 )
 
@@ -14,9 +18,24 @@ type CreatePostPayload struct {
 	PostType    string  `json:"post_type"`
 	LinkURL     *string `json:"link_url"`
 	ImageURL    *string `json:"image_url"`
-	IsLocked    bool    `json:"is_locked"`
 	IsNSFW      bool    `json:"is_nsfw"`
+	IsSpoiler   bool    `json:"is_spoiler"`
 	SubredditID int     `json:"subreddit_id"`
+
+	ContentWarnings []string `json:"content_warnings"` // Slugs of the subreddit's content warnings
+
+	Poll     *PollPayload         `json:"poll"`      // Required for post_type poll
+	Gallery  []GalleryItemPayload `json:"gallery"`   // Required for post_type gallery
+	VideoURL *string              `json:"video_url"` // Required for post_type video; MP4 or QuickTime
+}
+
+type VotePayload struct {
+	Direction *int `json:"direction" binding:"required"` // 1 = upvote, -1 = downvote, 0 = clear
+}
+
+type UpdatePostPayload struct {
+	Title   *string `json:"title" binding:"omitempty,min=3,max=300"`
+	Content *string `json:"content"`
 }
 
 // type Post struct {
@@ -64,28 +83,301 @@ func CreatePost(c *gin.Context) {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid JSON payload"})
 		return
 	}
+	if payload.Content != nil && len(*payload.Content) > models.MaxPostContentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content must be at most 40000 bytes"})
+		return
+	}
+
+	subreddit, err := models.GetSubredditByID(payload.SubredditID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+
+	if (payload.PostType == models.PostTypePoll) != (payload.Poll != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "poll is required for, and only allowed on, poll posts"})
+		return
+	}
+	if (payload.PostType == models.PostTypeGallery) != (payload.Gallery != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "gallery is required for, and only allowed on, gallery posts"})
+		return
+	}
+	if (payload.PostType == models.PostTypeVideo) != (payload.VideoURL != nil) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "video_url is required for, and only allowed on, video posts"})
+		return
+	}
 
 	newPost := &models.Post{}
+	newPost.ContentWarnings, err = models.ResolveContentWarnings(subreddit.ID, payload.ContentWarnings)
+	if errors.Is(err, models.ErrUnknownContentWarning) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if payload.Poll != nil {
+		newPost.Poll, err = buildPoll(payload.Poll)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if payload.Gallery != nil {
+		newPost.Gallery, err = buildGallery(payload.Gallery)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if payload.VideoURL != nil {
+		newPost.Video, err = buildVideo(c.Request.Context(), *payload.VideoURL)
+		if err != nil {
+			respondVideoError(c, err)
+			return
+		}
+	}
 	newPost.AuthorID = userID_int
 	newPost.Title = payload.Title
 	newPost.Content = payload.Content
 	newPost.LinkURL = payload.LinkURL
 	newPost.ImageURL = payload.ImageURL
 	newPost.PostType = payload.PostType
-	newPost.IsLocked = payload.IsLocked
 	newPost.IsNSFW = payload.IsNSFW
+	newPost.IsSpoiler = payload.IsSpoiler
 	newPost.SubredditID = payload.SubredditID
 	newPost.Upvotes = 0
 	newPost.Downvotes = 0
 	newPost.CommentCount = 0
 	newPost.Score = 0
 
-	newPost, err := models.CreatePost(newPost)
+	newPost, err = models.SubmitPost(newPost, subreddit)
+	if errors.Is(err, models.ErrInsufficientKarma) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Post Created Successfully",
+		"data":    newPost,
+	})
+
+}
+
+func GetPost(c *gin.Context) {
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.LoadPostDetails([]*models.Post{post}, viewerID(c)); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	analytics.Default.PageView(post.SubredditID, presenceKey(c))
+
+	c.JSON(http.StatusOK, gin.H{"data": post})
+}
+
+func ListPosts(c *gin.Context) {
+	limit, offset := parsePagination(c)
+
+	var subredditID *int
+	if name := c.Query("subreddit"); name != "" {
+		subreddit, err := models.GetSubredditByName(name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if subreddit == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+			return
+		}
+		subredditID = &subreddit.ID
+	}
+
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return
+	}
+
+	sort := c.DefaultQuery("sort", prefs.DefaultFeedSort)
+	if sort != "top" && sort != "new" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: top, new"})
+		return
+	}
+
+	filter := models.PostFilter{
+		SubredditID: subredditID,
+		ViewerID:    viewerID(c),
+		IncludeNSFW: prefs.ShowNSFW,
+		Sort:        sort,
+	}
+	contentFilterParams(c, &filter)
+
+	posts, err := models.ListPosts(limit, offset, filter)
 	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(posts),
+		},
+	})
+}
+
+// UpdatePost handles PUT /api/posts/:id
+//
+// Only the author can edit the title and body. Every change is kept as a
+// revision, see ListPostRevisions.
+func UpdatePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+	if post.AuthorID != userID {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only the author can edit this post"})
+		return
+	}
+
+	var payload UpdatePostPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if payload.Content != nil && len(*payload.Content) > models.MaxPostContentLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content must be at most 40000 bytes"})
+		return
+	}
+
+	if payload.Title != nil {
+		post.Title = strings.TrimSpace(*payload.Title)
+		if utf8.RuneCountInString(post.Title) < 3 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Title must be at least 3 characters"})
+			return
+		}
+	}
+	if payload.Content != nil {
+		post.Content = payload.Content
+	}
+
+	if err := models.UpdatePost(post, userID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	if err := models.LoadPostDetails([]*models.Post{post}, &userID); err != nil {
+		log.Println(err)
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Post Updated Successfully",
+		"data":    post,
+	})
+}
+
+// VotePost handles POST /api/posts/:id/vote
+func VotePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	var payload VotePayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.IsVoteDirection(*payload.Direction) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "direction must be 1, -1 or 0"})
+		return
+	}
+
+	score, err := models.VotePost(post.ID, userID, *payload.Direction)
+	if errors.Is(err, models.ErrPostArchived) {
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Vote recorded",
+		"data":    score,
+	})
+}
+
+// DeletePost handles DELETE /api/posts/:id
+//
+// Authors delete their own posts; moderators remove posts in their
+// subreddits. The final version stays visible to moderators as a revision.
+func DeletePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	reason := models.RevisionDeleted
+	if post.AuthorID != userID {
+		isMod, err := models.IsSubredditModerator(post.SubredditID, userID)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+		if !isMod {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Only the author or a moderator can delete this post"})
+			return
+		}
+		reason = models.RevisionRemoved
+	}
+
+	if err := models.DeletePost(post, userID, reason); err != nil {
 		log.Println(err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
 		return
 	}
-	c.JSON(http.StatusCreated, gin.H{"message": "Post Created Successfully"})
 
+	c.JSON(http.StatusOK, gin.H{"message": "Post Deleted Successfully"})
 }
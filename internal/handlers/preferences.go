@@ -0,0 +1,61 @@
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// GetPreferences handles GET /api/me/preferences
+func GetPreferences(c *gin.Context) {
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"preferences": prefs})
+}
+
+// UpdatePreferences handles PUT /api/me/preferences
+//
+// Only the keys present in the body change; unknown keys are rejected.
+func UpdatePreferences(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return
+	}
+
+	dec := json.NewDecoder(c.Request.Body)
+	dec.DisallowUnknownFields()
+	if err := dec.Decode(prefs); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if prefs.EmailOptOut == nil {
+		prefs.EmailOptOut = []string{}
+	}
+	if err := prefs.Validate(); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if err := models.SaveUserPreferences(userID, prefs); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save preferences"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message":     "Preferences updated",
+		"preferences": prefs,
+	})
+}
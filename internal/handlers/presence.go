@@ -0,0 +1,53 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+	"github.com/kshzz24/gosocial/internal/realtime"
+)
+
+// SubredditHeartbeat handles POST /api/subreddits/:name/presence
+//
+// Clients viewing a subreddit ping this endpoint (at least every couple of
+// minutes) to be counted in active_users. Clients with an open stream on
+// the subreddit topic are counted without pinging.
+func SubredditHeartbeat(c *gin.Context) {
+	subreddit, err := models.GetSubredditByName(c.Param("name"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if subreddit == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+		return
+	}
+
+	realtime.DefaultPresence.Touch(subreddit.ID, presenceKey(c))
+
+	c.JSON(http.StatusOK, gin.H{
+		"active_users": realtime.DefaultPresence.Count(subreddit.ID),
+		"ttl_seconds":  int(realtime.PresenceTTL.Seconds()),
+	})
+}
+
+// presenceKey identifies a viewer. Anonymous viewers are keyed by address,
+// so several logged-out users behind one NAT count once.
+func presenceKey(c *gin.Context) string {
+	if userID, ok := currentUserID(c); ok {
+		return "user:" + strconv.Itoa(userID)
+	}
+	return "anon:" + c.ClientIP()
+}
+
+// withLiveActiveUsers replaces the stored active_users snapshot with the
+// live count.
+func withLiveActiveUsers(subreddits ...*models.Subreddit) {
+	for _, s := range subreddits {
+		s.ActiveUsers = realtime.DefaultPresence.Count(s.ID)
+	}
+}
@@ -0,0 +1,69 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// ListPostRevisions handles GET /api/posts/:id/revisions
+//
+// Anyone can see the history of a live post. Once a post is deleted or
+// removed, its history is only shown to moderators of its subreddit.
+func ListPostRevisions(c *gin.Context) {
+	postID, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid post ID"})
+		return
+	}
+
+	post, err := models.GetPostByID(postID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	revisions, err := models.ListPostRevisions(postID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	if post == nil {
+		if len(revisions) == 0 || !canReviewDeletedPost(c, revisions[0].SubredditID) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Post not found"})
+			return
+		}
+	}
+
+	var editedAt any
+	if post != nil {
+		editedAt = post.EditedAt
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"post_id":   postID,
+		"deleted":   post == nil,
+		"edited_at": editedAt,
+		"versions":  models.PostHistory(revisions, post),
+	})
+}
+
+// canReviewDeletedPost reports whether the viewer moderates subredditID.
+func canReviewDeletedPost(c *gin.Context, subredditID int) bool {
+	userID, ok := currentUserID(c)
+	if !ok {
+		return false
+	}
+	isMod, err := models.IsSubredditModerator(subredditID, userID)
+	if err != nil {
+		log.Println(err)
+		return false
+	}
+	return isMod
+}
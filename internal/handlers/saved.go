@@ -0,0 +1,189 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type SavePostPayload struct {
+	Category *string `json:"category" binding:"omitempty,max=50"`
+}
+
+func SavePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	// The body is optional; an empty body saves without a category.
+	var payload SavePostPayload
+	if c.Request.ContentLength > 0 {
+		if err := c.ShouldBindJSON(&payload); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	}
+	if payload.Category != nil {
+		trimmed := strings.TrimSpace(*payload.Category)
+		payload.Category = &trimmed
+		if trimmed == "" {
+			payload.Category = nil
+		}
+	}
+
+	if err := models.SavePost(userID, post.ID, payload.Category); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post saved"})
+}
+
+func UnsavePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.UnsavePost(userID, post.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unsave post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post unsaved"})
+}
+
+func HidePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.HidePost(userID, post.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hide post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post hidden"})
+}
+
+func UnhidePost(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	post, ok := postFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.UnhidePost(userID, post.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to unhide post"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Post unhidden"})
+}
+
+// ListSavedPosts handles GET /api/me/saved?category=name
+func ListSavedPosts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+
+	var category *string
+	if value, exists := c.GetQuery("category"); exists {
+		category = &value
+	}
+
+	saved, err := models.ListSavedPosts(userID, category, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"saved": saved,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(saved),
+		},
+	})
+}
+
+func ListSavedCategories(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	categories, err := models.ListSavedCategories(userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"categories": categories})
+}
+
+func ListHiddenPosts(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+
+	hidden, err := models.ListHiddenPosts(userID, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"hidden": hidden,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(hidden),
+		},
+	})
+}
@@ -0,0 +1,172 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// Search handles GET /api/search
+//
+// Query parameters:
+//
+//	q          search terms (web search syntax: "quoted phrases", -exclude, or)
+//	type       post or subreddit (default: both)
+//	subreddit  restrict posts to a subreddit name
+//	author     restrict posts to an author username
+//	from, to   date range, RFC 3339 or YYYY-MM-DD
+//	nsfw       include NSFW results (default: the viewer's show_nsfw preference)
+//	sort       relevance (default), new or top
+func Search(c *gin.Context) {
+	query := strings.TrimSpace(c.Query("q"))
+	if query == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Search query is required"})
+		return
+	}
+
+	searchType := c.DefaultQuery("type", "all")
+	if searchType != "all" && searchType != "post" && searchType != "subreddit" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "type must be one of: post, subreddit"})
+		return
+	}
+
+	sort := c.DefaultQuery("sort", "relevance")
+	if sort != "relevance" && sort != "new" && sort != "top" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: relevance, new, top"})
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	includeNSFW, ok := includeNSFWParam(c)
+	if !ok {
+		return
+	}
+
+	params := models.SearchParams{
+		Query:       query,
+		Author:      c.Query("author"),
+		ViewerID:    viewerID(c),
+		IncludeNSFW: includeNSFW,
+		Sort:        sort,
+		Limit:       limit,
+		Offset:      offset,
+	}
+
+	var err error
+	if params.From, err = parseDateParam(c.Query("from"), false); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid from date"})
+		return
+	}
+	if params.To, err = parseDateParam(c.Query("to"), true); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid to date"})
+		return
+	}
+
+	if name := c.Query("subreddit"); name != "" {
+		subreddit, err := models.GetSubredditByName(name)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		if subreddit == nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Subreddit not found"})
+			return
+		}
+		params.SubredditID = &subreddit.ID
+	}
+
+	response := gin.H{
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+		},
+	}
+
+	if searchType == "all" || searchType == "post" {
+		posts, err := models.SearchPosts(params)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		response["posts"] = posts
+	}
+
+	// Subreddit results ignore post-only filters.
+	if (searchType == "all" && params.SubredditID == nil && params.Author == "") || searchType == "subreddit" {
+		subreddits, err := models.SearchSubreddits(params)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+		response["subreddits"] = subreddits
+	}
+
+	c.JSON(http.StatusOK, response)
+}
+
+// AutocompleteSubreddits handles GET /api/search/subreddits?q=prefix
+func AutocompleteSubreddits(c *gin.Context) {
+	prefix := strings.TrimSpace(c.Query("q"))
+	if prefix == "" {
+		c.JSON(http.StatusOK, gin.H{"subreddits": []*models.Subreddit{}})
+		return
+	}
+
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit < 1 || limit > 25 {
+		limit = 10
+	}
+	includeNSFW, ok := includeNSFWParam(c)
+	if !ok {
+		return
+	}
+
+	subreddits, err := models.AutocompleteSubreddits(prefix, includeNSFW, limit)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"subreddits": subreddits})
+}
+
+// includeNSFWParam reads the nsfw query parameter, defaulting to the
+// viewer's show_nsfw preference. On failure it writes the error response and
+// returns false.
+func includeNSFWParam(c *gin.Context) (bool, bool) {
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return false, false
+	}
+	if value, err := strconv.ParseBool(c.Query("nsfw")); err == nil {
+		return value, true
+	}
+	return prefs.ShowNSFW, true
+}
+
+// parseDateParam accepts RFC 3339 timestamps or plain dates. A plain date
+// used as an upper bound covers the whole day.
+func parseDateParam(value string, endOfDay bool) (*time.Time, error) {
+	if value == "" {
+		return nil, nil
+	}
+	if t, err := time.Parse(time.RFC3339, value); err == nil {
+		return &t, nil
+	}
+	t, err := time.Parse("2006-01-02", value)
+	if err != nil {
+		return nil, err
+	}
+	if endOfDay {
+		t = t.Add(24*time.Hour - time.Nanosecond)
+	}
+	return &t, nil
+}
@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"fmt"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+	"github.com/kshzz24/gosocial/internal/realtime"
+)
+
+const (
+	streamKeepAlive   = 25 * time.Second
+	maxStreamTopics   = 50
+	streamRetryMillis = 3000
+)
+
+// Stream handles GET /api/stream?topics=post:12,subreddit:golang,user:me
+//
+// It is a Server-Sent Events endpoint. Supported topics:
+//
+//	post:<id>             new comments and vote count changes on a post
+//	subreddit:<id|name>   new posts in a subreddit
+//	user:me               the caller's notifications
+func Stream(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	raw := strings.Split(c.Query("topics"), ",")
+	topics := make([]string, 0, len(raw))
+	for _, t := range raw {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			continue
+		}
+		topic, status, msg := resolveStreamTopic(t, userID)
+		if status != 0 {
+			c.JSON(status, gin.H{"error": msg})
+			return
+		}
+		topics = append(topics, topic)
+	}
+	if len(topics) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "At least one topic is required"})
+		return
+	}
+	if len(topics) > maxStreamTopics {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Too many topics"})
+		return
+	}
+
+	sub := realtime.DefaultHub.Subscribe(topics...)
+	defer sub.Close()
+
+	// Viewers with a subreddit stream open count as present there. They
+	// expire with PresenceTTL after the stream closes rather than leaving
+	// at once, since the same viewer may have other streams or heartbeats.
+	viewer := presenceKey(c)
+	var present []int
+	for _, t := range topics {
+		if key, ok := strings.CutPrefix(t, "subreddit:"); ok {
+			id, _ := strconv.Atoi(key)
+			present = append(present, id)
+		}
+	}
+	touch := func() {
+		for _, id := range present {
+			realtime.DefaultPresence.Touch(id, viewer)
+		}
+	}
+	touch()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+
+	keepAlive := time.NewTicker(streamKeepAlive)
+	defer keepAlive.Stop()
+
+	fmt.Fprintf(c.Writer, "retry: %d\n\n", streamRetryMillis)
+	c.SSEvent("subscribed", gin.H{"topics": topics})
+	c.Writer.Flush()
+
+	ctx := c.Request.Context()
+	c.Stream(func(w io.Writer) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case e, ok := <-sub.C:
+			if !ok {
+				return false
+			}
+			c.SSEvent(e.Type, e)
+			return true
+		case <-keepAlive.C:
+			io.WriteString(w, ": keep-alive\n\n")
+			touch()
+			return true
+		}
+	})
+}
+
+// resolveStreamTopic validates a client topic and maps it to the hub topic
+// name. A non-zero status means the topic was rejected.
+func resolveStreamTopic(topic string, userID int) (string, int, string) {
+	kind, key, found := strings.Cut(topic, ":")
+	if !found || key == "" {
+		return "", http.StatusBadRequest, "Invalid topic: " + topic
+	}
+
+	switch kind {
+	case "post":
+		postID, err := strconv.Atoi(key)
+		if err != nil {
+			return "", http.StatusBadRequest, "Invalid topic: " + topic
+		}
+		post, err := models.GetPostByID(postID)
+		if err != nil {
+			log.Println(err)
+			return "", http.StatusInternalServerError, "Internal server error"
+		}
+		if post == nil {
+			return "", http.StatusNotFound, "Post not found: " + key
+		}
+		return realtime.PostTopic(post.ID), 0, ""
+
+	case "subreddit":
+		var subreddit *models.Subreddit
+		var err error
+		if id, convErr := strconv.Atoi(key); convErr == nil {
+			subreddit, err = models.GetSubredditByID(id)
+		} else {
+			subreddit, err = models.GetSubredditByName(key)
+		}
+		if err != nil {
+			log.Println(err)
+			return "", http.StatusInternalServerError, "Internal server error"
+		}
+		if subreddit == nil {
+			return "", http.StatusNotFound, "Subreddit not found: " + key
+		}
+		return realtime.SubredditTopic(subreddit.ID), 0, ""
+
+	case "user":
+		if key != "me" && key != strconv.Itoa(userID) {
+			return "", http.StatusForbidden, "You can only subscribe to your own notifications"
+		}
+		return realtime.UserTopic(userID), 0, ""
+	}
+
+	return "", http.StatusBadRequest, "Unknown topic type: " + kind
+}
@@ -9,6 +9,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/analytics"
 	"github.com/kshzz24/gosocial/internal/models"
 )
 
@@ -19,6 +20,8 @@ type CreateSubredditPayload struct {
 	Rules       json.RawMessage `json:"rules"`
 	IsNSFW      bool            `json:"is_nsfw"`
 	IsPrivate   bool            `json:"is_private"`
+	Language    string          `json:"language"`   // Defaults to en
+	Categories  []string        `json:"categories"` // Category slugs, at most 3
 }
 
 type UpdateSubredditPayload struct {
@@ -31,6 +34,14 @@ type UpdateSubredditPayload struct {
 	IsPrivate      bool            `json:"is_private"`
 	Flairs         json.RawMessage `json:"flairs"` // JSONB
 	RulesUpdatedAt *time.Time      `json:"rules_updated_at"`
+
+	MinKarmaToPost          *int `json:"min_karma_to_post" binding:"omitempty,min=0"`           // Unchanged if omitted
+	MinSubredditKarmaToPost *int `json:"min_subreddit_karma_to_post" binding:"omitempty,min=0"` // Unchanged if omitted
+
+	ArchiveAfterDays *int `json:"archive_after_days" binding:"omitempty,min=0,max=3650"` // 0 = never; unchanged if omitted
+
+	Language   *string  `json:"language"`   // Unchanged if omitted
+	Categories []string `json:"categories"` // Category slugs, at most 3; unchanged if omitted
 }
 
 func CreateSubreddit(c *gin.Context) {
@@ -87,6 +98,19 @@ func CreateSubreddit(c *gin.Context) {
 		return
 	}
 
+	language := payload.Language
+	if language == "" {
+		language = "en"
+	}
+	if !models.IsLanguageTag(language) {
+		c.JSON(400, gin.H{"error": "language must be a language tag such as en or pt-BR"})
+		return
+	}
+	categories, ok := categoriesParam(c, payload.Categories)
+	if !ok {
+		return
+	}
+
 	subreddit := &models.Subreddit{
 		Name:         name,
 		DisplayName:  payload.DisplayName,
@@ -97,6 +121,8 @@ func CreateSubreddit(c *gin.Context) {
 		CreatedBy:    userIDInt,
 		ActiveUsers:  0,
 		MembersCount: 1,
+		Language:     language,
+		Categories:   categories,
 	}
 	if len(subreddit.Rules) == 0 {
 		subreddit.Rules = json.RawMessage(`[]`)
@@ -133,6 +159,8 @@ func GetSubreddit(c *gin.Context) {
 		c.JSON(404, gin.H{"error": "Subreddit not found"})
 		return
 	}
+	withLiveActiveUsers(subreddit)
+	analytics.Default.PageView(subreddit.ID, presenceKey(c))
 	c.JSON(200, gin.H{
 		"Success": "Subreddit found",
 		"data":    subreddit,
@@ -141,35 +169,14 @@ func GetSubreddit(c *gin.Context) {
 
 func ListSubreddits(c *gin.Context) {
 
-	var limit, offset int
-
-	pageStr := c.Query("page")
-	if pageStr != "" {
-		page, _ := strconv.Atoi(pageStr)
-		perPage, _ := strconv.Atoi(c.DefaultQuery("per_page", "20"))
+	limit, offset := parsePagination(c)
 
-		if page < 1 {
-			page = 1
-		}
-
-		offset = (page - 1) * perPage
-		limit = perPage
-	} else {
-		limit, _ = strconv.Atoi(c.DefaultQuery("limit", "20"))
-		offset, _ = strconv.Atoi(c.DefaultQuery("offset", "0"))
-	}
-
-	if limit < 1 {
-		limit = 20
-	}
-	if limit > 100 {
-		limit = 100
-	}
-	if offset < 0 {
-		offset = 0
+	filter, ok := subredditFilterParams(c)
+	if !ok {
+		return
 	}
 
-	subreddits, err := models.ListSubreddits(limit, offset)
+	subreddits, err := models.ListSubreddits(limit, offset, filter)
 
 	if err != nil {
 		c.JSON(500, gin.H{
@@ -180,6 +187,7 @@ func ListSubreddits(c *gin.Context) {
 	if subreddits == nil {
 		subreddits = []*models.Subreddit{}
 	}
+	withLiveActiveUsers(subreddits...)
 
 	c.JSON(200, gin.H{
 		"subreddits": subreddits,
@@ -208,7 +216,7 @@ func UpdateSubreddit(c *gin.Context) {
 		return
 	}
 
-	subredditIDStr := c.Param("id")
+	subredditIDStr := c.Param("name") // The subreddit ID, see the route
 
 	subredditID, err := strconv.Atoi(subredditIDStr)
 	if err != nil {
@@ -257,6 +265,32 @@ func UpdateSubreddit(c *gin.Context) {
 		IsPrivate:      payload.IsPrivate,
 		Flairs:         payload.Flairs,
 		RulesUpdatedAt: payload.RulesUpdatedAt,
+
+		MinKarmaToPost:          existingSubreddit.MinKarmaToPost,
+		MinSubredditKarmaToPost: existingSubreddit.MinSubredditKarmaToPost,
+		ArchiveAfterDays:        existingSubreddit.ArchiveAfterDays,
+		Language:                existingSubreddit.Language,
+	}
+	if payload.MinKarmaToPost != nil {
+		updatedSubreddit.MinKarmaToPost = *payload.MinKarmaToPost
+	}
+	if payload.MinSubredditKarmaToPost != nil {
+		updatedSubreddit.MinSubredditKarmaToPost = *payload.MinSubredditKarmaToPost
+	}
+	if payload.ArchiveAfterDays != nil {
+		updatedSubreddit.ArchiveAfterDays = *payload.ArchiveAfterDays
+	}
+	if payload.Language != nil {
+		if !models.IsLanguageTag(*payload.Language) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "language must be a language tag such as en or pt-BR"})
+			return
+		}
+		updatedSubreddit.Language = *payload.Language
+	}
+	if payload.Categories != nil {
+		if updatedSubreddit.Categories, ok = categoriesParam(c, payload.Categories); !ok {
+			return
+		}
 	}
 
 	err = models.UpdateSubreddit(updatedSubreddit)
@@ -289,7 +323,7 @@ func DeleteSubreddit(c *gin.Context) {
 		return
 	}
 
-	subredditIDStr := c.Param("id")
+	subredditIDStr := c.Param("name") // The subreddit ID, see the route
 
 	subredditID, err := strconv.Atoi(subredditIDStr)
 	if err != nil {
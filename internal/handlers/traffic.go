@@ -0,0 +1,135 @@
+package handlers
+
+import (
+	"encoding/csv"
+	"fmt"
+	"log"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// defaultTrafficSpan is how far back a traffic report goes when from is
+// omitted.
+var defaultTrafficSpan = map[string]func(time.Time) time.Time{
+	models.TrafficHour:  func(t time.Time) time.Time { return t.Add(-47 * time.Hour) },
+	models.TrafficDay:   func(t time.Time) time.Time { return t.AddDate(0, 0, -29) },
+	models.TrafficMonth: func(t time.Time) time.Time { return t.AddDate(0, -11, 0) },
+}
+
+// GetSubredditTraffic handles GET /api/subreddits/:name/traffic
+//
+// Moderators get page views, unique visitors, subscriptions, posts and
+// comments per hour, day or month (?interval=), optionally between from
+// and to, as JSON or as CSV with format=csv. Buckets are in UTC.
+func GetSubredditTraffic(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	subreddit, ok := subredditFromParam(c)
+	if !ok {
+		return
+	}
+	if !requireModerator(c, subreddit.ID, userID) {
+		return
+	}
+
+	interval := c.DefaultQuery("interval", models.TrafficDay)
+	if !models.IsTrafficInterval(interval) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "interval must be one of: hour, day, month"})
+		return
+	}
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "csv" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be one of: json, csv"})
+		return
+	}
+
+	to, err := parseDateParam(c.Query("to"), true)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "to must be a date (2006-01-02) or RFC 3339 timestamp"})
+		return
+	}
+	if to == nil {
+		now := time.Now().UTC()
+		to = &now
+	}
+	from, err := parseDateParam(c.Query("from"), false)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be a date (2006-01-02) or RFC 3339 timestamp"})
+		return
+	}
+	if from == nil {
+		start := defaultTrafficSpan[interval](*to)
+		from = &start
+	}
+	if from.After(*to) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "from must be before to"})
+		return
+	}
+	if trafficBucketCount(interval, *from, *to) > models.MaxTrafficBuckets {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("at most %d buckets can be requested at once", models.MaxTrafficBuckets)})
+		return
+	}
+
+	buckets, err := models.GetSubredditTraffic(subreddit.ID, interval, *from, *to)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	if format == "csv" {
+		writeTrafficCSV(c, subreddit.Name, interval, buckets)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"subreddit": subreddit.Name,
+		"interval":  interval,
+		"traffic":   buckets,
+	})
+}
+
+// trafficBucketCount is roughly how many buckets of interval span from to
+// to.
+func trafficBucketCount(interval string, from, to time.Time) int {
+	switch interval {
+	case models.TrafficHour:
+		return int(to.Sub(from).Hours()) + 1
+	case models.TrafficDay:
+		return int(to.Sub(from).Hours()/24) + 1
+	default:
+		return (to.Year()-from.Year())*12 + int(to.Month()-from.Month()) + 1
+	}
+}
+
+func writeTrafficCSV(c *gin.Context, subreddit, interval string, buckets []*models.TrafficBucket) {
+	c.Header("Content-Type", "text/csv; charset=utf-8")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-traffic-%s.csv"`, subreddit, interval))
+	c.Status(http.StatusOK)
+
+	w := csv.NewWriter(c.Writer)
+	w.Write([]string{"start", "page_views", "unique_visitors", "subscriptions", "unsubscriptions", "posts", "comments"})
+	for _, b := range buckets {
+		w.Write([]string{
+			b.Start.Format(time.RFC3339),
+			strconv.Itoa(b.PageViews),
+			strconv.Itoa(b.UniqueVisitors),
+			strconv.Itoa(b.Subscriptions),
+			strconv.Itoa(b.Unsubscriptions),
+			strconv.Itoa(b.Posts),
+			strconv.Itoa(b.Comments),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		log.Println(err)
+	}
+}
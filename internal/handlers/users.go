@@ -0,0 +1,254 @@
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/models"
+	"github.com/kshzz24/gosocial/internal/utils"
+)
+
+const maxBioLength = 500
+
+type UpdateProfilePayload struct {
+	AvatarURL *string `json:"avatar_url" binding:"omitempty,max=500"`
+	Bio       *string `json:"bio"`
+}
+
+// userFromParam loads the user named by the :username route parameter. On
+// failure it writes the error response and returns false.
+func userFromParam(c *gin.Context) (*models.User, bool) {
+	user, err := models.GetUserByUsername(c.Param("username"))
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, false
+	}
+	if user == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return nil, false
+	}
+	return user, true
+}
+
+// GetUserProfile handles GET /api/users/:username
+func GetUserProfile(c *gin.Context) {
+	user, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	counts, err := models.GetFollowCounts(user.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	isFollowing := false
+	if viewer := viewerID(c); viewer != nil && *viewer != user.ID {
+		isFollowing, err = models.IsFollowing(*viewer, user.ID)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+			return
+		}
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"user": gin.H{
+			"id":         user.ID,
+			"username":   user.Username,
+			"avatar_url": user.AvatarURL,
+			"bio":        user.Bio,
+			"cake_day":   user.CreatedAt.Format("2006-01-02"),
+			"created_at": user.CreatedAt,
+			"karma": gin.H{
+				"post":    user.PostKarma,
+				"comment": user.CommentKarma,
+				"total":   user.PostKarma + user.CommentKarma,
+			},
+			"followers_count": counts.Followers,
+			"following_count": counts.Following,
+			"is_following":    isFollowing,
+		},
+	})
+}
+
+// ListUserPosts handles GET /api/users/:username/posts?sort=new|top
+func ListUserPosts(c *gin.Context) {
+	user, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	posts, limit, offset, ok := listUserPosts(c, user)
+	if !ok {
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"posts": posts,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(posts),
+		},
+	})
+}
+
+// ListUserOverview handles GET /api/users/:username/overview. Items are
+// tagged with their type so other kinds of activity can be interleaved.
+func ListUserOverview(c *gin.Context) {
+	user, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	posts, limit, offset, ok := listUserPosts(c, user)
+	if !ok {
+		return
+	}
+
+	items := make([]gin.H, 0, len(posts))
+	for _, post := range posts {
+		items = append(items, gin.H{"type": "post", "data": post})
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"items": items,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(items),
+		},
+	})
+}
+
+func listUserPosts(c *gin.Context, user *models.User) ([]*models.Post, int, int, bool) {
+	limit, offset := parsePagination(c)
+
+	if !checkProfileVisible(c, user) {
+		return nil, 0, 0, false
+	}
+	viewer := viewerID(c)
+
+	prefs, ok := viewerPreferences(c)
+	if !ok {
+		return nil, 0, 0, false
+	}
+
+	sort := c.DefaultQuery("sort", "new")
+	if sort != "top" && sort != "new" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "sort must be one of: top, new"})
+		return nil, 0, 0, false
+	}
+
+	filter := models.PostFilter{
+		AuthorID:    &user.ID,
+		ViewerID:    viewer,
+		IncludeNSFW: prefs.ShowNSFW,
+		Sort:        sort,
+	}
+	contentFilterParams(c, &filter)
+
+	posts, err := models.ListPosts(limit, offset, filter)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return nil, 0, 0, false
+	}
+	return posts, limit, offset, true
+}
+
+// checkProfileVisible rejects requests for a hidden profile's activity from
+// anyone but its owner. On failure it writes the error response and returns
+// false.
+func checkProfileVisible(c *gin.Context, user *models.User) bool {
+	if viewer := viewerID(c); viewer != nil && *viewer == user.ID {
+		return true
+	}
+
+	prefs, err := models.GetUserPreferences(user.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return false
+	}
+	if prefs.ProfileVisibility == models.ProfileHidden {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This user's activity is private"})
+		return false
+	}
+	return true
+}
+
+// UpdateProfile handles PUT /api/me/profile
+func UpdateProfile(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	var payload UpdateProfilePayload
+	if err := c.BindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	user, err := models.GetUserByID(userID)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch user"})
+		return
+	}
+
+	avatarURL := user.AvatarURL
+	if payload.AvatarURL != nil {
+		avatarURL = nil
+		if raw := strings.TrimSpace(*payload.AvatarURL); raw != "" {
+			cleaned, valid := utils.SanitizeURL(raw)
+			lower := strings.ToLower(cleaned)
+			if !valid || !(strings.HasPrefix(lower, "https://") || strings.HasPrefix(lower, "http://")) {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "avatar_url must be an http(s) URL"})
+				return
+			}
+			// Sanitizing can escape characters, so check the stored length too.
+			if utf8.RuneCountInString(cleaned) > 500 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "avatar_url must be at most 500 characters"})
+				return
+			}
+			avatarURL = &cleaned
+		}
+	}
+
+	bio := user.Bio
+	if payload.Bio != nil {
+		bio = nil
+		if trimmed := strings.TrimSpace(*payload.Bio); trimmed != "" {
+			if utf8.RuneCountInString(trimmed) > maxBioLength {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Bio must be at most 500 characters"})
+				return
+			}
+			bio = &trimmed
+		}
+	}
+
+	if err := models.UpdateUserProfile(userID, avatarURL, bio); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update profile"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Profile updated successfully",
+		"user": gin.H{
+			"id":         user.ID,
+			"username":   user.Username,
+			"avatar_url": avatarURL,
+			"bio":        bio,
+		},
+	})
+}
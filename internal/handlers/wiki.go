@@ -0,0 +1,454 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"github.com/kshzz24/gosocial/internal/analytics"
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+type WikiEditPayload struct {
+	Content string  `json:"content"`
+	Reason  *string `json:"reason" binding:"omitempty,max=256"` // Edit summary
+}
+
+type WikiRevertPayload struct {
+	RevisionID int     `json:"revision_id" binding:"required"`
+	Reason     *string `json:"reason" binding:"omitempty,max=256"`
+}
+
+type WikiSettingsPayload struct {
+	EditPermission   string `json:"edit_permission"`
+	MinKarma         int    `json:"min_karma" binding:"min=0"`
+	DiscussionPostID *int   `json:"discussion_post_id"`
+}
+
+// ListWikiPages handles GET /api/subreddits/:name/wiki
+func ListWikiPages(c *gin.Context) {
+	subreddit, ok := subredditFromParam(c)
+	if !ok {
+		return
+	}
+
+	pages, err := models.ListWikiPages(subreddit.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pages": pages})
+}
+
+// GetWikiPage handles GET /api/subreddits/:name/wiki/pages/*path
+func GetWikiPage(c *gin.Context) {
+	subreddit, ok := subredditFromParam(c)
+	if !ok {
+		return
+	}
+	path, ok := models.NormalizeWikiPath(c.Param("path"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wiki page path"})
+		return
+	}
+
+	page, err := models.GetWikiPage(subreddit.ID, path)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+	if page == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wiki page not found"})
+		return
+	}
+	analytics.Default.PageView(subreddit.ID, presenceKey(c))
+
+	c.JSON(http.StatusOK, gin.H{"data": page})
+}
+
+// EditWikiPage handles POST /api/subreddits/:name/wiki/pages/*path
+//
+// Saves a new revision of the page, creating it if needed. A new page takes
+// its parent's edit permission and needs the parent's edit rights;
+// top-level pages are created by moderators.
+func EditWikiPage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	subreddit, ok := subredditFromParam(c)
+	if !ok {
+		return
+	}
+	path, ok := models.NormalizeWikiPath(c.Param("path"))
+	if !ok {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Page paths are up to 255 characters of /-separated segments of lowercase letters, digits, - or _"})
+		return
+	}
+
+	var payload WikiEditPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if len(payload.Content) > models.MaxWikiPageLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "content must be at most 256 KB"})
+		return
+	}
+
+	page, err := models.GetWikiPage(subreddit.ID, path)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	if page != nil {
+		if !requireWikiEditor(c, page, userID) {
+			return
+		}
+		if err := models.EditWikiPage(page, payload.Content, userID, payload.Reason, nil); err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+		c.JSON(http.StatusOK, gin.H{
+			"message": "Wiki page updated",
+			"data":    page,
+		})
+		return
+	}
+
+	page = &models.WikiPage{
+		SubredditID:    subreddit.ID,
+		Path:           path,
+		Content:        payload.Content,
+		EditPermission: models.WikiEditModerators,
+	}
+	if parentPath := models.WikiParentPath(path); parentPath != "" {
+		parent, err := models.GetWikiPage(subreddit.ID, parentPath)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+		if parent == nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": models.ErrWikiParentMissing.Error()})
+			return
+		}
+		if !requireWikiEditor(c, parent, userID) {
+			return
+		}
+		page.EditPermission = parent.EditPermission
+		page.MinKarma = parent.MinKarma
+	} else if !requireModerator(c, subreddit.ID, userID) {
+		return
+	}
+
+	err = models.CreateWikiPage(page, userID, payload.Reason)
+	switch {
+	case errors.Is(err, models.ErrWikiParentMissing):
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	case errors.Is(err, models.ErrWikiPageExists):
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	case err != nil:
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, gin.H{
+		"message": "Wiki page created",
+		"data":    page,
+	})
+}
+
+// ListWikiRevisions handles GET /api/wiki/:id/revisions
+func ListWikiRevisions(c *gin.Context) {
+	page, ok := wikiPageFromParam(c)
+	if !ok {
+		return
+	}
+
+	limit, offset := parsePagination(c)
+	revisions, err := models.ListWikiRevisions(page.ID, limit, offset)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"revisions": revisions,
+		"pagination": gin.H{
+			"limit":  limit,
+			"offset": offset,
+			"count":  len(revisions),
+		},
+	})
+}
+
+// RevertWikiPage handles POST /api/wiki/:id/revert
+//
+// Restores an earlier revision by saving its content as a new revision.
+func RevertWikiPage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	page, ok := wikiPageFromParam(c)
+	if !ok {
+		return
+	}
+	if !requireWikiEditor(c, page, userID) {
+		return
+	}
+
+	var payload WikiRevertPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	revision, err := models.GetWikiRevision(page.ID, payload.RevisionID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+	if revision == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Revision not found"})
+		return
+	}
+
+	if err := models.EditWikiPage(page, revision.Content, userID, payload.Reason, &revision.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wiki page reverted",
+		"data":    page,
+	})
+}
+
+// UpdateWikiPageSettings handles PUT /api/wiki/:id/settings
+//
+// Moderators set who may edit the page and link its discussion post.
+func UpdateWikiPageSettings(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	page, ok := wikiPageFromParam(c)
+	if !ok {
+		return
+	}
+	if !requireModerator(c, page.SubredditID, userID) {
+		return
+	}
+
+	var payload WikiSettingsPayload
+	if err := c.ShouldBindJSON(&payload); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if !models.IsWikiEditPermission(payload.EditPermission) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "edit_permission must be one of: moderators, approved, karma"})
+		return
+	}
+
+	if payload.DiscussionPostID != nil {
+		post, err := models.GetPostByID(*payload.DiscussionPostID)
+		if err != nil {
+			log.Println(err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+			return
+		}
+		if post == nil || post.SubredditID != page.SubredditID {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "The discussion post must be a post in this subreddit"})
+			return
+		}
+	}
+
+	page.EditPermission = payload.EditPermission
+	page.MinKarma = payload.MinKarma
+	page.DiscussionPostID = payload.DiscussionPostID
+	if err := models.UpdateWikiPageSettings(page); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"message": "Wiki page settings updated",
+		"data":    page,
+	})
+}
+
+// DeleteWikiPage handles DELETE /api/wiki/:id
+func DeleteWikiPage(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	page, ok := wikiPageFromParam(c)
+	if !ok {
+		return
+	}
+	if !requireModerator(c, page.SubredditID, userID) {
+		return
+	}
+
+	err := models.DeleteWikiPage(page.ID)
+	if errors.Is(err, models.ErrWikiPageHasChildren) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wiki page deleted"})
+}
+
+// ListWikiEditors handles GET /api/subreddits/:name/wiki/editors
+func ListWikiEditors(c *gin.Context) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return
+	}
+
+	subreddit, ok := subredditFromParam(c)
+	if !ok {
+		return
+	}
+	if !requireModerator(c, subreddit.ID, userID) {
+		return
+	}
+
+	editors, err := models.ListWikiEditors(subreddit.ID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"editors": editors})
+}
+
+// AddWikiEditor handles PUT /api/subreddits/:name/wiki/editors/:username
+func AddWikiEditor(c *gin.Context) {
+	moderatorID, subredditID, ok := wikiEditorsSubreddit(c)
+	if !ok {
+		return
+	}
+	user, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.AddWikiEditor(subredditID, user.ID, moderatorID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wiki editor added"})
+}
+
+// RemoveWikiEditor handles DELETE /api/subreddits/:name/wiki/editors/:username
+func RemoveWikiEditor(c *gin.Context) {
+	_, subredditID, ok := wikiEditorsSubreddit(c)
+	if !ok {
+		return
+	}
+	user, ok := userFromParam(c)
+	if !ok {
+		return
+	}
+
+	if err := models.RemoveWikiEditor(subredditID, user.ID); err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Wiki editor removed"})
+}
+
+// wikiEditorsSubreddit reads the :name subreddit and checks that the caller
+// moderates it. On failure it writes the error response and returns false.
+func wikiEditorsSubreddit(c *gin.Context) (int, int, bool) {
+	userID, ok := currentUserID(c)
+	if !ok {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization is required"})
+		return 0, 0, false
+	}
+
+	subreddit, ok := subredditFromParam(c)
+	if !ok {
+		return 0, 0, false
+	}
+	if !requireModerator(c, subreddit.ID, userID) {
+		return 0, 0, false
+	}
+	return userID, subreddit.ID, true
+}
+
+// requireWikiEditor checks that userID may edit page. On failure it writes
+// the error response and returns false.
+func requireWikiEditor(c *gin.Context, page *models.WikiPage, userID int) bool {
+	canEdit, err := models.CanEditWikiPage(page, userID)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return false
+	}
+	if !canEdit {
+		c.JSON(http.StatusForbidden, gin.H{"error": "You can't edit this wiki page"})
+		return false
+	}
+	return true
+}
+
+// wikiPageFromParam loads the :id wiki page. On failure it writes the error
+// response and returns false.
+func wikiPageFromParam(c *gin.Context) (*models.WikiPage, bool) {
+	id, err := strconv.Atoi(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid wiki page ID"})
+		return nil, false
+	}
+
+	page, err := models.GetWikiPageByID(id)
+	if err != nil {
+		log.Println(err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Something went wrong"})
+		return nil, false
+	}
+	if page == nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Wiki page not found"})
+		return nil, false
+	}
+	return page, true
+}
@@ -0,0 +1,41 @@
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// archiveBatch is how many posts one archive statement flips, keeping each
+// transaction short.
+const archiveBatch = 500
+
+// RunArchiver archives posts past their subreddit's archive age every
+// interval until stop is closed.
+func RunArchiver(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			var total int64
+			for {
+				n, err := models.ArchiveDuePosts(archiveBatch)
+				if err != nil {
+					log.Printf("jobs: archiving posts failed: %v", err)
+					break
+				}
+				total += n
+				if n < archiveBatch {
+					break
+				}
+			}
+			if total > 0 {
+				log.Printf("jobs: archived %d post(s)", total)
+			}
+		}
+	}
+}
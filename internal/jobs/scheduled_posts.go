@@ -0,0 +1,35 @@
+// Package jobs contains background work started by the API server.
+package jobs
+
+import (
+	"log"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/models"
+)
+
+// scheduledPostBatch caps how many drafts one tick publishes so a backlog
+// doesn't hold up the loop.
+const scheduledPostBatch = 50
+
+// RunScheduledPosts publishes due scheduled drafts every interval until
+// stop is closed. Several instances may run it at once; drafts are locked
+// while they are published.
+func RunScheduledPosts(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			n, err := models.PublishDueDrafts(scheduledPostBatch)
+			if err != nil {
+				log.Printf("jobs: publishing scheduled posts failed: %v", err)
+			}
+			if n > 0 {
+				log.Printf("jobs: published %d scheduled post(s)", n)
+			}
+		}
+	}
+}
@@ -0,0 +1,147 @@
+package media
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// MaxDownloadSize caps how much of a video is downloaded when the host
+// doesn't support range requests.
+const MaxDownloadSize = 64 << 20
+
+var (
+	ErrInvalidMediaURL = errors.New("media URL must be an absolute http(s) URL")
+	errBlockedAddress  = errors.New("media host resolves to a private address")
+)
+
+// client refuses to connect to loopback, private and link-local addresses
+// so user-supplied URLs can't reach internal services.
+var client = &http.Client{
+	Timeout: 30 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				ip := net.ParseIP(host)
+				if ip == nil || ip.IsLoopback() || ip.IsPrivate() || ip.IsUnspecified() ||
+					ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() {
+					return errBlockedAddress
+				}
+				return nil
+			},
+		}).DialContext,
+	},
+}
+
+// ValidateURL checks that raw is an absolute http(s) URL.
+func ValidateURL(raw string) error {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return ErrInvalidMediaURL
+	}
+	return nil
+}
+
+// ProbeURL fetches the metadata of the video at rawURL. Hosts that support
+// range requests only serve the parts of the file that are needed.
+func ProbeURL(ctx context.Context, rawURL string) (*VideoInfo, error) {
+	if err := ValidateURL(rawURL); err != nil {
+		return nil, err
+	}
+
+	r := &httpReaderAt{ctx: ctx, url: rawURL}
+	size, ranged, body, err := r.open()
+	if err != nil {
+		return nil, err
+	}
+	if !ranged {
+		return ProbeMP4(bytes.NewReader(body), int64(len(body)))
+	}
+	return ProbeMP4(r, size)
+}
+
+// httpReaderAt reads a remote file with HTTP range requests.
+type httpReaderAt struct {
+	ctx context.Context
+	url string
+}
+
+// open checks whether the host honors range requests. If it doesn't, the
+// whole body (up to MaxDownloadSize) is returned instead.
+func (r *httpReaderAt) open() (size int64, ranged bool, body []byte, err error) {
+	resp, err := r.get("bytes=0-0")
+	if err != nil {
+		return 0, false, nil, err
+	}
+	defer resp.Body.Close()
+
+	switch resp.StatusCode {
+	case http.StatusPartialContent:
+		// Content-Range: bytes 0-0/12345
+		total := resp.Header.Get("Content-Range")
+		if i := strings.LastIndex(total, "/"); i >= 0 {
+			if size, err := strconv.ParseInt(total[i+1:], 10, 64); err == nil {
+				return size, true, nil, nil
+			}
+		}
+		return 0, false, nil, fmt.Errorf("media host sent an invalid Content-Range %q", total)
+	case http.StatusOK:
+		body, err := io.ReadAll(io.LimitReader(resp.Body, MaxDownloadSize+1))
+		if err != nil {
+			return 0, false, nil, fmt.Errorf("failed to download media: %w", err)
+		}
+		if len(body) > MaxDownloadSize {
+			return 0, false, nil, ErrVideoTooLarge
+		}
+		return int64(len(body)), false, body, nil
+	default:
+		return 0, false, nil, fmt.Errorf("media host returned %s", resp.Status)
+	}
+}
+
+func (r *httpReaderAt) ReadAt(p []byte, off int64) (int, error) {
+	if len(p) == 0 {
+		return 0, nil
+	}
+	resp, err := r.get(fmt.Sprintf("bytes=%d-%d", off, off+int64(len(p))-1))
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusPartialContent {
+		return 0, fmt.Errorf("media host returned %s for a range request", resp.Status)
+	}
+	n, err := io.ReadFull(resp.Body, p)
+	if err == io.ErrUnexpectedEOF {
+		err = io.EOF
+	}
+	return n, err
+}
+
+func (r *httpReaderAt) get(byteRange string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(r.ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, ErrInvalidMediaURL
+	}
+	req.Header.Set("Range", byteRange)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("failed to fetch media: %w", err)
+	}
+	return resp, nil
+}
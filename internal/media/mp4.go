@@ -0,0 +1,260 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+)
+
+var (
+	ErrUnsupportedVideo = errors.New("unsupported video format, only MP4 and QuickTime are supported")
+	ErrNoVideoTrack     = errors.New("file has no video track")
+	ErrVideoTooLarge    = errors.New("video metadata is too large")
+)
+
+// maxMoovSize caps the metadata box read into memory.
+const maxMoovSize = 32 << 20
+
+// maxBoxes caps the boxes listed at one level. Each top-level box header
+// costs a ranged request, so a file padded with tiny boxes is rejected.
+const maxBoxes = 256
+
+// VideoInfo is the metadata extracted from a video file.
+type VideoInfo struct {
+	Duration time.Duration
+	Width    int // Display size, after rotation
+	Height   int
+	Cover    []byte // Embedded cover art (JPEG or PNG), if any
+}
+
+type box struct {
+	typ   string
+	start int64 // Start of the body, after the header
+	end   int64
+}
+
+// readBoxes lists the ISO BMFF boxes between start and end.
+func readBoxes(r io.ReaderAt, start, end int64) ([]box, error) {
+	var boxes []box
+	var hdr [16]byte
+	for pos := start; pos+8 <= end; {
+		if _, err := r.ReadAt(hdr[:8], pos); err != nil {
+			return nil, fmt.Errorf("failed to read box header: %w", err)
+		}
+		size := int64(binary.BigEndian.Uint32(hdr[:4]))
+		typ := string(hdr[4:8])
+		headerLen := int64(8)
+
+		switch size {
+		case 0: // Extends to the end of the enclosing box
+			size = end - pos
+		case 1: // 64-bit size follows the type
+			if _, err := r.ReadAt(hdr[8:16], pos+8); err != nil {
+				return nil, fmt.Errorf("failed to read box header: %w", err)
+			}
+			size = int64(binary.BigEndian.Uint64(hdr[8:16]))
+			headerLen = 16
+		}
+		if size < headerLen || pos+size > end {
+			return nil, fmt.Errorf("malformed %q box", typ)
+		}
+
+		if len(boxes) == maxBoxes {
+			return nil, fmt.Errorf("more than %d boxes", maxBoxes)
+		}
+		boxes = append(boxes, box{typ: typ, start: pos + headerLen, end: pos + size})
+		pos += size
+	}
+	return boxes, nil
+}
+
+func findBox(boxes []box, typ string) (box, bool) {
+	for _, b := range boxes {
+		if b.typ == typ {
+			return b, true
+		}
+	}
+	return box{}, false
+}
+
+// ProbeMP4 reads duration, dimensions and cover art from an MP4 or
+// QuickTime file of the given size. Only the moov box is read in full, so r
+// can be backed by ranged HTTP requests.
+func ProbeMP4(r io.ReaderAt, size int64) (*VideoInfo, error) {
+	top, err := readBoxes(r, 0, size)
+	if err != nil || len(top) == 0 || (top[0].typ != "ftyp" && top[0].typ != "moov" && top[0].typ != "wide" && top[0].typ != "mdat") {
+		return nil, ErrUnsupportedVideo
+	}
+
+	moovBox, ok := findBox(top, "moov")
+	if !ok {
+		return nil, ErrUnsupportedVideo
+	}
+	if moovBox.end-moovBox.start > maxMoovSize {
+		return nil, ErrVideoTooLarge
+	}
+
+	moov := make([]byte, moovBox.end-moovBox.start)
+	if _, err := r.ReadAt(moov, moovBox.start); err != nil {
+		return nil, fmt.Errorf("failed to read moov box: %w", err)
+	}
+	return parseMoov(moov)
+}
+
+func parseMoov(moov []byte) (*VideoInfo, error) {
+	r := bytes.NewReader(moov)
+	children, err := readBoxes(r, 0, int64(len(moov)))
+	if err != nil {
+		return nil, err
+	}
+
+	info := &VideoInfo{}
+	if mvhd, ok := findBox(children, "mvhd"); ok {
+		info.Duration = parseMvhd(moov[mvhd.start:mvhd.end])
+	}
+
+	foundVideo := false
+	for _, trak := range children {
+		if trak.typ != "trak" || foundVideo {
+			continue
+		}
+		w, h, isVideo, err := parseTrak(moov[trak.start:trak.end])
+		if err != nil {
+			return nil, err
+		}
+		if isVideo {
+			info.Width, info.Height = w, h
+			foundVideo = true
+		}
+	}
+	if !foundVideo {
+		return nil, ErrNoVideoTrack
+	}
+
+	if udta, ok := findBox(children, "udta"); ok {
+		info.Cover = findCoverArt(moov[udta.start:udta.end])
+	}
+	return info, nil
+}
+
+// parseMvhd returns the movie duration from a movie header box.
+func parseMvhd(b []byte) time.Duration {
+	var timescale, duration uint64
+	switch {
+	case len(b) >= 32 && b[0] == 1:
+		timescale = uint64(binary.BigEndian.Uint32(b[20:24]))
+		duration = binary.BigEndian.Uint64(b[24:32])
+	case len(b) >= 20:
+		timescale = uint64(binary.BigEndian.Uint32(b[12:16]))
+		duration = uint64(binary.BigEndian.Uint32(b[16:20]))
+	}
+	if timescale == 0 {
+		return 0
+	}
+	return time.Duration(float64(duration) / float64(timescale) * float64(time.Second))
+}
+
+// parseTrak reports whether a track is a video track and, if so, its
+// display dimensions from the track header.
+func parseTrak(trak []byte) (width, height int, isVideo bool, err error) {
+	r := bytes.NewReader(trak)
+	children, err := readBoxes(r, 0, int64(len(trak)))
+	if err != nil {
+		return 0, 0, false, err
+	}
+
+	mdia, ok := findBox(children, "mdia")
+	if !ok {
+		return 0, 0, false, nil
+	}
+	mdiaChildren, err := readBoxes(r, mdia.start, mdia.end)
+	if err != nil {
+		return 0, 0, false, err
+	}
+	hdlr, ok := findBox(mdiaChildren, "hdlr")
+	if !ok || hdlr.end-hdlr.start < 12 || string(trak[hdlr.start+8:hdlr.start+12]) != "vide" {
+		return 0, 0, false, nil
+	}
+
+	tkhd, ok := findBox(children, "tkhd")
+	if !ok {
+		return 0, 0, true, nil
+	}
+	width, height = parseTkhd(trak[tkhd.start:tkhd.end])
+	return width, height, true, nil
+}
+
+// parseTkhd returns the track's display size, swapping width and height
+// when the transformation matrix rotates by 90 or 270 degrees.
+func parseTkhd(b []byte) (width, height int) {
+	// version/flags, then times, track ID and duration (larger in version 1)
+	off := 4 + 4 + 4 + 4 + 4 + 4
+	if len(b) > 0 && b[0] == 1 {
+		off = 4 + 8 + 8 + 4 + 4 + 8
+	}
+	off += 8 + 2 + 2 + 2 + 2 // reserved, layer, alternate group, volume, reserved
+	if len(b) < off+36+8 {
+		return 0, 0
+	}
+
+	matrix := b[off : off+36]
+	width = int(binary.BigEndian.Uint32(b[off+36:off+40]) >> 16)
+	height = int(binary.BigEndian.Uint32(b[off+40:off+44]) >> 16)
+
+	a := int32(binary.BigEndian.Uint32(matrix[0:4]))
+	bb := int32(binary.BigEndian.Uint32(matrix[4:8]))
+	if a == 0 && bb != 0 {
+		width, height = height, width
+	}
+	return width, height
+}
+
+// findCoverArt returns the iTunes-style cover art stored under
+// udta/meta/ilst/covr, if present.
+func findCoverArt(udta []byte) []byte {
+	r := bytes.NewReader(udta)
+	children, err := readBoxes(r, 0, int64(len(udta)))
+	if err != nil {
+		return nil
+	}
+	meta, ok := findBox(children, "meta")
+	if !ok {
+		return nil
+	}
+	// In MP4 files meta is a full box with 4 bytes of version and flags;
+	// QuickTime writes it without them.
+	start := meta.start
+	if meta.end-start >= 8 && string(udta[start+4:start+8]) != "hdlr" {
+		start += 4
+	}
+
+	metaChildren, err := readBoxes(r, start, meta.end)
+	if err != nil {
+		return nil
+	}
+	ilst, ok := findBox(metaChildren, "ilst")
+	if !ok {
+		return nil
+	}
+	items, err := readBoxes(r, ilst.start, ilst.end)
+	if err != nil {
+		return nil
+	}
+	covr, ok := findBox(items, "covr")
+	if !ok {
+		return nil
+	}
+	values, err := readBoxes(r, covr.start, covr.end)
+	if err != nil {
+		return nil
+	}
+	data, ok := findBox(values, "data")
+	// type indicator and locale precede the image bytes
+	if !ok || data.end-data.start <= 8 {
+		return nil
+	}
+	return udta[data.start+8 : data.end]
+}
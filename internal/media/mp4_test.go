@@ -0,0 +1,181 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"errors"
+	"testing"
+	"time"
+)
+
+func mkbox(typ string, body ...[]byte) []byte {
+	b := make([]byte, 8)
+	copy(b[4:], typ)
+	for _, part := range body {
+		b = append(b, part...)
+	}
+	binary.BigEndian.PutUint32(b, uint32(len(b)))
+	return b
+}
+
+func u32(v uint32) []byte { return binary.BigEndian.AppendUint32(nil, v) }
+func u64(v uint64) []byte { return binary.BigEndian.AppendUint64(nil, v) }
+
+func mvhd(version byte, timescale uint32, duration uint64) []byte {
+	body := []byte{version, 0, 0, 0}
+	if version == 1 {
+		body = append(body, u64(0)...) // creation time
+		body = append(body, u64(0)...) // modification time
+		body = append(body, u32(timescale)...)
+		body = append(body, u64(duration)...)
+	} else {
+		body = append(body, u32(0)...)
+		body = append(body, u32(0)...)
+		body = append(body, u32(timescale)...)
+		body = append(body, u32(uint32(duration))...)
+	}
+	return mkbox("mvhd", body, make([]byte, 80))
+}
+
+var (
+	identity  = [9]uint32{0x10000, 0, 0, 0, 0x10000, 0, 0, 0, 0x40000000}
+	rotate90  = [9]uint32{0, 0x10000, 0, 0xffff0000, 0, 0, 0, 0, 0x40000000}
+	rotate180 = [9]uint32{0xffff0000, 0, 0, 0, 0xffff0000, 0, 0, 0, 0x40000000}
+)
+
+func tkhd(version byte, matrix [9]uint32, width, height uint32) []byte {
+	body := []byte{version, 0, 0, 0}
+	if version == 1 {
+		body = append(body, make([]byte, 8+8+4+4+8)...) // times, track ID, reserved, duration
+	} else {
+		body = append(body, make([]byte, 4+4+4+4+4)...)
+	}
+	body = append(body, make([]byte, 8+2+2+2+2)...) // reserved, layer, alternate group, volume, reserved
+	for _, v := range matrix {
+		body = append(body, u32(v)...)
+	}
+	body = append(body, u32(width<<16)...)
+	body = append(body, u32(height<<16)...)
+	return mkbox("tkhd", body)
+}
+
+func trak(handler string, header []byte) []byte {
+	hdlr := mkbox("hdlr", u32(0), u32(0), []byte(handler), make([]byte, 12))
+	return mkbox("trak", header, mkbox("mdia", hdlr))
+}
+
+func coverUdta(img []byte) []byte {
+	data := mkbox("data", u32(13), u32(0), img) // type indicator (JPEG) and locale
+	ilst := mkbox("ilst", mkbox("covr", data))
+	return mkbox("udta", mkbox("meta", u32(0), ilst))
+}
+
+func mp4(moov ...[]byte) []byte {
+	ftyp := mkbox("ftyp", []byte("isom"), u32(0x200), []byte("isommp41"))
+	return append(ftyp, mkbox("moov", moov...)...)
+}
+
+func TestProbeMP4(t *testing.T) {
+	tests := []struct {
+		name     string
+		file     []byte
+		duration time.Duration
+		width    int
+		height   int
+		cover    string
+		err      error
+	}{
+		{
+			name:     "version 0 headers",
+			file:     mp4(mvhd(0, 1000, 12500), trak("vide", tkhd(0, identity, 1920, 1080))),
+			duration: 12500 * time.Millisecond, width: 1920, height: 1080,
+		},
+		{
+			name:     "version 1 headers",
+			file:     mp4(mvhd(1, 90000, 90000*60), trak("vide", tkhd(1, identity, 1280, 720))),
+			duration: time.Minute, width: 1280, height: 720,
+		},
+		{
+			name:     "rotated 90 degrees",
+			file:     mp4(mvhd(0, 600, 600), trak("vide", tkhd(0, rotate90, 1920, 1080))),
+			duration: time.Second, width: 1080, height: 1920,
+		},
+		{
+			name:     "rotated 90 degrees, version 1",
+			file:     mp4(mvhd(1, 600, 600), trak("vide", tkhd(1, rotate90, 640, 480))),
+			duration: time.Second, width: 480, height: 640,
+		},
+		{
+			name:     "rotated 180 degrees",
+			file:     mp4(mvhd(0, 600, 600), trak("vide", tkhd(0, rotate180, 1920, 1080))),
+			duration: time.Second, width: 1920, height: 1080,
+		},
+		{
+			name: "audio track first",
+			file: mp4(mvhd(0, 1000, 1000),
+				trak("soun", tkhd(0, identity, 0, 0)),
+				trak("vide", tkhd(0, identity, 640, 360))),
+			duration: time.Second, width: 640, height: 360,
+		},
+		{
+			name: "cover art",
+			file: mp4(mvhd(0, 1000, 1000), trak("vide", tkhd(0, identity, 640, 360)),
+				coverUdta([]byte("\xff\xd8jpeg"))),
+			duration: time.Second, width: 640, height: 360, cover: "\xff\xd8jpeg",
+		},
+		{
+			name: "no video track",
+			file: mp4(mvhd(0, 1000, 1000), trak("soun", tkhd(0, identity, 0, 0))),
+			err:  ErrNoVideoTrack,
+		},
+		{
+			name: "not an MP4",
+			file: []byte("\x89PNG\r\n\x1a\n\x00\x00\x00\x0dIHDR"),
+			err:  ErrUnsupportedVideo,
+		},
+		{
+			name: "no moov box",
+			file: mkbox("ftyp", []byte("isom")),
+			err:  ErrUnsupportedVideo,
+		},
+		{
+			name: "box overruns file",
+			file: append(mkbox("ftyp", []byte("isom")), 0, 0, 1, 0, 'm', 'o', 'o', 'v'),
+			err:  ErrUnsupportedVideo,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := ProbeMP4(bytes.NewReader(tt.file), int64(len(tt.file)))
+			if tt.err != nil {
+				if !errors.Is(err, tt.err) {
+					t.Fatalf("ProbeMP4() error = %v, want %v", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ProbeMP4() error = %v", err)
+			}
+			if info.Duration != tt.duration || info.Width != tt.width || info.Height != tt.height {
+				t.Errorf("ProbeMP4() = %v %dx%d, want %v %dx%d",
+					info.Duration, info.Width, info.Height, tt.duration, tt.width, tt.height)
+			}
+			if string(info.Cover) != tt.cover {
+				t.Errorf("ProbeMP4() cover = %q, want %q", info.Cover, tt.cover)
+			}
+		})
+	}
+}
+
+func TestReadBoxesLimit(t *testing.T) {
+	for _, n := range []int{maxBoxes, maxBoxes + 1} {
+		file := bytes.Repeat(mkbox("free"), n)
+		boxes, err := readBoxes(bytes.NewReader(file), 0, int64(len(file)))
+		if n <= maxBoxes && (err != nil || len(boxes) != n) {
+			t.Errorf("readBoxes(%d boxes) = %d boxes, %v", n, len(boxes), err)
+		}
+		if n > maxBoxes && err == nil {
+			t.Errorf("readBoxes(%d boxes) succeeded, want an error", n)
+		}
+	}
+}
@@ -0,0 +1,123 @@
+package media
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/draw"
+	_ "image/jpeg" // Cover art is usually JPEG
+	"image/png"
+)
+
+// Thumbnail sources
+const (
+	ThumbnailCoverArt    = "cover_art"
+	ThumbnailPlaceholder = "placeholder"
+)
+
+// MaxThumbnailWidth bounds the width of generated thumbnails.
+const MaxThumbnailWidth = 640
+
+// maxCoverPixels caps the cover art decoded into memory (4096x4096).
+const maxCoverPixels = 4096 * 4096
+
+var (
+	placeholderBackground = color.RGBA{0x1a, 0x1a, 0x1b, 0xff}
+	placeholderForeground = color.RGBA{0xd7, 0xda, 0xdc, 0xff}
+)
+
+// Thumbnail renders a PNG thumbnail for a video without decoding any video
+// frames: the embedded cover art is used when the file has one; otherwise a
+// play-button placeholder with the video's aspect ratio is drawn.
+func Thumbnail(info *VideoInfo) (png []byte, source string, err error) {
+	if cover := decodeCover(info.Cover); cover != nil {
+		data, err := encodePNG(scaleToWidth(cover, MaxThumbnailWidth))
+		return data, ThumbnailCoverArt, err
+	}
+
+	data, err := encodePNG(placeholder(info.Width, info.Height))
+	return data, ThumbnailPlaceholder, err
+}
+
+// decodeCover decodes embedded cover art, or returns nil when there is none,
+// it cannot be decoded or its header claims more than maxCoverPixels. The
+// header is checked first because the decoder allocates the full image
+// before reading any pixel data.
+func decodeCover(data []byte) image.Image {
+	if len(data) == 0 {
+		return nil
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil || cfg.Width <= 0 || cfg.Height <= 0 || cfg.Width > maxCoverPixels/cfg.Height {
+		return nil
+	}
+	cover, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil
+	}
+	return cover
+}
+
+func encodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, fmt.Errorf("failed to encode thumbnail: %w", err)
+	}
+	return buf.Bytes(), nil
+}
+
+// scaleToWidth shrinks img to at most maxWidth pixels wide, averaging the
+// source pixels that fall into each destination pixel.
+func scaleToWidth(img image.Image, maxWidth int) image.Image {
+	src := img.Bounds()
+	if src.Dx() <= maxWidth {
+		return img
+	}
+	w := maxWidth
+	h := max(1, src.Dy()*w/src.Dx())
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+
+	for y := 0; y < h; y++ {
+		y0 := src.Min.Y + y*src.Dy()/h
+		y1 := max(y0+1, src.Min.Y+(y+1)*src.Dy()/h)
+		for x := 0; x < w; x++ {
+			x0 := src.Min.X + x*src.Dx()/w
+			x1 := max(x0+1, src.Min.X+(x+1)*src.Dx()/w)
+
+			var r, g, b, a, n uint32
+			for sy := y0; sy < y1; sy++ {
+				for sx := x0; sx < x1; sx++ {
+					cr, cg, cb, ca := img.At(sx, sy).RGBA()
+					r, g, b, a, n = r+cr, g+cg, b+cb, a+ca, n+1
+				}
+			}
+			dst.Set(x, y, color.RGBA64{uint16(r / n), uint16(g / n), uint16(b / n), uint16(a / n)})
+		}
+	}
+	return dst
+}
+
+// placeholder draws a centered play triangle on a dark background sized to
+// the video's aspect ratio (16:9 if unknown).
+func placeholder(width, height int) image.Image {
+	if width <= 0 || height <= 0 {
+		width, height = 16, 9
+	}
+	w := MaxThumbnailWidth
+	h := max(1, min(w*height/width, 2*MaxThumbnailWidth))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.Draw(img, img.Bounds(), image.NewUniform(placeholderBackground), image.Point{}, draw.Src)
+
+	size := min(w, h) / 4
+	cx, cy := w/2, h/2
+	left := cx - size/3
+	for x := 0; x < size; x++ {
+		// The triangle narrows linearly from its left edge to the tip.
+		half := size / 2 * (size - x) / size
+		for y := cy - half; y <= cy+half; y++ {
+			img.Set(left+x, y, placeholderForeground)
+		}
+	}
+	return img
+}
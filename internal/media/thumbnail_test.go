@@ -0,0 +1,81 @@
+package media
+
+import (
+	"bytes"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"runtime"
+	"testing"
+)
+
+// pngWithSize encodes a 1x1 PNG and rewrites its header to claim the given
+// size, with a valid checksum so only the size check can reject it.
+func pngWithSize(t *testing.T, width, height uint32) []byte {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	data := buf.Bytes()
+	// Signature (8), chunk length (4), "IHDR" (4), then width and height
+	ihdr := data[12 : 12+4+13]
+	binary.BigEndian.PutUint32(ihdr[4:8], width)
+	binary.BigEndian.PutUint32(ihdr[8:12], height)
+	binary.BigEndian.PutUint32(data[12+4+13:], crc32.ChecksumIEEE(ihdr))
+	return data
+}
+
+func TestDecodeCover(t *testing.T) {
+	tests := []struct {
+		name  string
+		data  []byte
+		valid bool
+	}{
+		{"small image", pngWithSize(t, 1, 1), true},
+		{"huge header", pngWithSize(t, 30000, 30000), false},
+		{"just over the cap", pngWithSize(t, 4097, 4096), false},
+		{"not an image", []byte("\xff\xd8jpeg"), false},
+		{"empty", nil, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := decodeCover(tt.data); (got != nil) != tt.valid {
+				t.Errorf("decodeCover() = %v, want decoded %v", got, tt.valid)
+			}
+		})
+	}
+}
+
+// TestDecodeCoverHugeHeader checks the size is rejected before the decoder
+// allocates the image (30000x30000 grayscale would be 900 MB).
+func TestDecodeCoverHugeHeader(t *testing.T) {
+	data := pngWithSize(t, 30000, 30000)
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	decodeCover(data)
+	runtime.ReadMemStats(&after)
+
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 1<<20 {
+		t.Errorf("decodeCover() allocated %d bytes", allocated)
+	}
+}
+
+func TestThumbnailPlaceholder(t *testing.T) {
+	data, source, err := Thumbnail(&VideoInfo{Width: 1080, Height: 1920, Cover: pngWithSize(t, 30000, 30000)})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if source != ThumbnailPlaceholder {
+		t.Errorf("Thumbnail() source = %q, want %q", source, ThumbnailPlaceholder)
+	}
+	cfg, err := png.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// The placeholder keeps the video's portrait aspect ratio
+	if want := MaxThumbnailWidth * 1920 / 1080; cfg.Width != MaxThumbnailWidth || cfg.Height != want {
+		t.Errorf("Thumbnail() size = %dx%d, want %dx%d", cfg.Width, cfg.Height, MaxThumbnailWidth, want)
+	}
+}
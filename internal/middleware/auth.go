@@ -17,15 +17,8 @@ func OptionalAuth() gin.HandlerFunc {
 			c.Next()
 			return
 		}
-		parts := strings.Split(auth_header, " ")
-		if len(parts) != 2 || parts[0] != "Bearer" {
-
-			c.Set("user_id", nil)
-			c.Set("is_authenticated", false)
-			c.Next()
-			return
-		}
-		token := parts[1]
+		// Accept both "Bearer <token>" and the bare token RequireAuth expects
+		token := strings.TrimPrefix(auth_header, "Bearer ")
 
 		// Validate token
 		claims, err := utils.ValidateJWT(token)
@@ -0,0 +1,32 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+var ErrPostArchived = errors.New("this post is archived and can no longer be voted on")
+
+// ArchiveDuePosts archives up to limit posts that are older than their
+// subreddit's archive age and returns how many it archived. Archiving is
+// one-way: raising the archive age later doesn't reopen posts.
+func ArchiveDuePosts(limit int) (int64, error) {
+	res, err := database.DB.Exec(`
+		UPDATE posts SET is_archived = TRUE, archived_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT p.id FROM posts p
+			JOIN subreddits s ON s.id = p.subreddit_id
+			WHERE NOT p.is_archived
+			  AND s.archive_after_days > 0
+			  AND p.created_at < CURRENT_TIMESTAMP - s.archive_after_days * INTERVAL '1 day'
+			LIMIT $1
+			FOR UPDATE OF p SKIP LOCKED
+		)
+	`, limit)
+	if err != nil {
+		return 0, fmt.Errorf("failed to archive posts: %w", err)
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,391 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+	"github.com/lib/pq"
+)
+
+// Allowance defaults, overridable with AWARD_ALLOWANCE and
+// AWARD_ALLOWANCE_DAYS.
+const (
+	defaultAwardAllowance     = 100
+	defaultAwardAllowanceDays = 7
+
+	// Unspent allowance stops accruing at this many periods' worth, or
+	// at the cost of the dearest active award if that is higher.
+	awardBalanceCapPeriods = 5
+)
+
+var (
+	ErrAwardTypeExists   = errors.New("an award with this slug already exists")
+	ErrAwardUnavailable  = errors.New("this award can't be given here")
+	ErrAwardOwnPost      = errors.New("you can't award your own post")
+	ErrInsufficientCoins = errors.New("not enough coins for this award")
+	ErrAlreadyReacted    = errors.New("you already gave this reaction")
+)
+
+type AwardType struct {
+	ID          int       `json:"id"`
+	SubredditID *int      `json:"subreddit_id"` // nil for site-wide awards
+	Slug        string    `json:"slug"`
+	Name        string    `json:"name"`
+	IconURL     *string   `json:"icon_url"`
+	Cost        int       `json:"cost"` // 0 = free reaction
+	IsActive    bool      `json:"is_active"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type Award struct {
+	ID          int       `json:"id"`
+	AwardTypeID int       `json:"award_type_id"`
+	PostID      int       `json:"post_id"`
+	GiverID     *int      `json:"giver_id"` // nil when anonymous
+	RecipientID int       `json:"recipient_id"`
+	Cost        int       `json:"cost"`
+	IsAnonymous bool      `json:"is_anonymous"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// AwardCount is how many times a post received an award type.
+type AwardCount struct {
+	AwardTypeID int     `json:"award_type_id"`
+	Slug        string  `json:"slug"`
+	Name        string  `json:"name"`
+	IconURL     *string `json:"icon_url"`
+	Count       int     `json:"count"`
+}
+
+type AwardBalance struct {
+	Balance             int `json:"balance"`
+	Allowance           int `json:"allowance"`             // Coins credited per period
+	AllowancePeriodDays int `json:"allowance_period_days"` // Length of a period
+}
+
+// awardAllowance returns the configured coins per allowance period and the
+// period length.
+func awardAllowance() (int, int) {
+	amount, days := defaultAwardAllowance, defaultAwardAllowanceDays
+	if v, err := strconv.Atoi(os.Getenv("AWARD_ALLOWANCE")); err == nil && v >= 0 {
+		amount = v
+	}
+	if v, err := strconv.Atoi(os.Getenv("AWARD_ALLOWANCE_DAYS")); err == nil && v > 0 {
+		days = v
+	}
+	return amount, days
+}
+
+// awardBalanceCap is the balance above which allowance stops accruing. It
+// never falls below maxCost so every award stays within reach.
+func awardBalanceCap(amount, maxCost int) int {
+	return max(awardBalanceCapPeriods*amount, maxCost)
+}
+
+// creditAllowance returns balance after periods allowances of amount,
+// stopping at limit. A balance already above limit is kept.
+func creditAllowance(balance, periods, amount, limit int) int {
+	if periods <= 0 || amount <= 0 || balance >= limit {
+		return balance
+	}
+	if periods > limit/amount+1 {
+		periods = limit/amount + 1
+	}
+	return min(balance+periods*amount, limit)
+}
+
+var awardSlug = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// IsAwardSlug reports whether slug can name an award type.
+func IsAwardSlug(slug string) bool {
+	return awardSlug.MatchString(slug)
+}
+
+const awardTypeColumns = `id, subreddit_id, slug, name, icon_url, cost, is_active, created_at`
+
+func scanAwardType(row rowScanner) (*AwardType, error) {
+	t := &AwardType{}
+	err := row.Scan(&t.ID, &t.SubredditID, &t.Slug, &t.Name, &t.IconURL, &t.Cost, &t.IsActive, &t.CreatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// ListAwardTypes returns the active site-wide awards plus those of
+// subredditID, if given, cheapest first.
+func ListAwardTypes(subredditID *int) ([]*AwardType, error) {
+	rows, err := database.DB.Query(`SELECT `+awardTypeColumns+` FROM award_types
+		WHERE is_active AND (subreddit_id IS NULL OR subreddit_id = $1)
+		ORDER BY cost, name`, subredditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list award types: %w", err)
+	}
+	defer rows.Close()
+
+	types := []*AwardType{}
+	for rows.Next() {
+		t, err := scanAwardType(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan award type: %w", err)
+		}
+		types = append(types, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating award types: %w", err)
+	}
+
+	return types, nil
+}
+
+// GetAwardType returns an award type by ID, or nil if not found.
+func GetAwardType(id int) (*AwardType, error) {
+	t, err := scanAwardType(database.DB.QueryRow(`SELECT `+awardTypeColumns+` FROM award_types WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get award type: %w", err)
+	}
+	return t, nil
+}
+
+// CreateAwardType adds an award to the catalogue.
+func CreateAwardType(t *AwardType) error {
+	err := database.DB.QueryRow(`
+		INSERT INTO award_types (subreddit_id, slug, name, icon_url, cost)
+		VALUES ($1, $2, $3, $4, $5)
+		RETURNING id, is_active, created_at
+	`, t.SubredditID, t.Slug, t.Name, t.IconURL, t.Cost).Scan(&t.ID, &t.IsActive, &t.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrAwardTypeExists
+		}
+		return fmt.Errorf("failed to create award type: %w", err)
+	}
+	return nil
+}
+
+// RetireAwardType stops an award from being given. Awards already given
+// keep showing on posts.
+func RetireAwardType(id int) error {
+	if _, err := database.DB.Exec(`UPDATE award_types SET is_active = FALSE WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to retire award type: %w", err)
+	}
+	return nil
+}
+
+// lockAwardBalance credits any allowance due to userID and returns the
+// balance, locking the row for the rest of tx. New users start with one
+// period's allowance.
+func lockAwardBalance(tx *sql.Tx, userID int) (int, error) {
+	amount, days := awardAllowance()
+
+	_, err := tx.Exec(`
+		INSERT INTO user_award_balances (user_id, balance) VALUES ($1, $2)
+		ON CONFLICT (user_id) DO NOTHING
+	`, userID, amount)
+	if err != nil {
+		return 0, fmt.Errorf("failed to create award balance: %w", err)
+	}
+
+	var balance, periods int
+	err = tx.QueryRow(`
+		SELECT balance, FLOOR(EXTRACT(EPOCH FROM (CURRENT_TIMESTAMP - last_allowance_at)) / 86400 / $2)::int
+		FROM user_award_balances WHERE user_id = $1 FOR UPDATE
+	`, userID, days).Scan(&balance, &periods)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get award balance: %w", err)
+	}
+	if periods <= 0 {
+		return balance, nil
+	}
+
+	var maxCost int
+	err = tx.QueryRow(`SELECT COALESCE(MAX(cost), 0) FROM award_types WHERE is_active`).Scan(&maxCost)
+	if err != nil {
+		return 0, fmt.Errorf("failed to get award costs: %w", err)
+	}
+	balance = creditAllowance(balance, periods, amount, awardBalanceCap(amount, maxCost))
+
+	_, err = tx.Exec(`
+		UPDATE user_award_balances
+		SET balance = $2, last_allowance_at = last_allowance_at + $3 * INTERVAL '1 day'
+		WHERE user_id = $1
+	`, userID, balance, periods*days)
+	if err != nil {
+		return 0, fmt.Errorf("failed to credit award allowance: %w", err)
+	}
+	return balance, nil
+}
+
+// GetAwardBalance returns userID's coins after crediting due allowance.
+func GetAwardBalance(userID int) (*AwardBalance, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	balance, err := lockAwardBalance(tx, userID)
+	if err != nil {
+		return nil, err
+	}
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit award balance: %w", err)
+	}
+
+	amount, days := awardAllowance()
+	return &AwardBalance{Balance: balance, Allowance: amount, AllowancePeriodDays: days}, nil
+}
+
+// GiveAward spends the award's cost from the giver's balance and records
+// the award on post. The balance row is locked for the whole transaction,
+// so concurrent awards can't overspend it.
+func GiveAward(post *Post, awardType *AwardType, giverID int, anonymous bool) (*Award, error) {
+	if !awardType.IsActive || (awardType.SubredditID != nil && *awardType.SubredditID != post.SubredditID) {
+		return nil, ErrAwardUnavailable
+	}
+	if post.AuthorID == giverID {
+		return nil, ErrAwardOwnPost
+	}
+	if post.IsArchived {
+		return nil, ErrPostArchived
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if awardType.Cost > 0 {
+		balance, err := lockAwardBalance(tx, giverID)
+		if err != nil {
+			return nil, err
+		}
+		if balance < awardType.Cost {
+			return nil, ErrInsufficientCoins
+		}
+		_, err = tx.Exec(`UPDATE user_award_balances SET balance = balance - $2 WHERE user_id = $1`, giverID, awardType.Cost)
+		if err != nil {
+			return nil, fmt.Errorf("failed to spend coins: %w", err)
+		}
+	}
+
+	award := &Award{
+		AwardTypeID: awardType.ID,
+		PostID:      post.ID,
+		GiverID:     &giverID,
+		RecipientID: post.AuthorID,
+		Cost:        awardType.Cost,
+		IsAnonymous: anonymous,
+	}
+	err = tx.QueryRow(`
+		INSERT INTO awards (award_type_id, post_id, giver_id, recipient_id, cost, is_anonymous)
+		VALUES ($1, $2, $3, $4, $5, $6)
+		RETURNING id, created_at
+	`, award.AwardTypeID, award.PostID, giverID, award.RecipientID, award.Cost, anonymous).Scan(&award.ID, &award.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return nil, ErrAlreadyReacted
+		}
+		return nil, fmt.Errorf("failed to give award: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit award: %w", err)
+	}
+
+	if anonymous {
+		award.GiverID = nil
+	}
+	if err := notifyAward(post, awardType, award); err != nil {
+		log.Println(err)
+	}
+	realtime.Publish(realtime.PostTopic(post.ID), "award", map[string]any{"award_type_id": awardType.ID, "slug": awardType.Slug})
+	return award, nil
+}
+
+// notifyAward tells the recipient about an award, without naming anonymous
+// givers.
+func notifyAward(post *Post, awardType *AwardType, award *Award) error {
+	giver := "Someone"
+	if award.GiverID != nil {
+		user, err := GetUserByID(*award.GiverID)
+		if err != nil {
+			return err
+		}
+		if user != nil {
+			giver = "u/" + user.Username
+		}
+	}
+
+	data, err := json.Marshal(map[string]any{"award_type_id": awardType.ID, "award": awardType.Slug})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+
+	return Notify(&Notification{
+		UserID:      award.RecipientID,
+		Type:        NotificationAward,
+		ActorID:     award.GiverID,
+		PostID:      &post.ID,
+		SubredditID: &post.SubredditID,
+		Message:     giver + " gave your post " + awardType.Name + ": " + post.Title,
+		Data:        data,
+	})
+}
+
+// loadAwardCounts attaches award counts to posts, most valuable first.
+func loadAwardCounts(posts []*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	byID := make(map[int][]*Post)
+	ids := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		p.Awards = []*AwardCount{}
+		if _, ok := byID[p.ID]; !ok {
+			ids = append(ids, int64(p.ID))
+		}
+		byID[p.ID] = append(byID[p.ID], p)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT a.post_id, t.id, t.slug, t.name, t.icon_url, COUNT(*)
+		FROM awards a JOIN award_types t ON t.id = a.award_type_id
+		WHERE a.post_id = ANY($1)
+		GROUP BY a.post_id, t.id
+		ORDER BY a.post_id, t.cost DESC, COUNT(*) DESC
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load awards: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		ac := &AwardCount{}
+		if err := rows.Scan(&postID, &ac.AwardTypeID, &ac.Slug, &ac.Name, &ac.IconURL, &ac.Count); err != nil {
+			return fmt.Errorf("failed to scan award count: %w", err)
+		}
+		for _, p := range byID[postID] {
+			p.Awards = append(p.Awards, ac)
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,68 @@
+package models
+
+import "testing"
+
+func TestAwardBalanceCap(t *testing.T) {
+	tests := []struct {
+		amount, maxCost, want int
+	}{
+		{100, 500, 500},   // five periods' worth
+		{100, 1800, 1800}, // dearest award above that
+		{1000, 1800, 5000},
+		{0, 1800, 1800},
+		{100, 0, 500},
+	}
+	for _, tt := range tests {
+		if got := awardBalanceCap(tt.amount, tt.maxCost); got != tt.want {
+			t.Errorf("awardBalanceCap(%d, %d) = %d, want %d", tt.amount, tt.maxCost, got, tt.want)
+		}
+	}
+}
+
+func TestCreditAllowance(t *testing.T) {
+	tests := []struct {
+		name                            string
+		balance, periods, amount, limit int
+		want                            int
+	}{
+		{"one period", 100, 1, 100, 500, 200},
+		{"several periods", 0, 3, 100, 500, 300},
+		{"stops at limit", 450, 1, 100, 500, 500},
+		{"reaches dearest award", 0, 18, 100, 1800, 1800},
+		{"long absence", 0, 1 << 40, 100, 1800, 1800},
+		{"already above limit", 2000, 4, 100, 1800, 2000},
+		{"no periods due", 100, 0, 100, 500, 100},
+		{"no allowance", 100, 3, 0, 1800, 100},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := creditAllowance(tt.balance, tt.periods, tt.amount, tt.limit)
+			if got != tt.want {
+				t.Errorf("creditAllowance(%d, %d, %d, %d) = %d, want %d",
+					tt.balance, tt.periods, tt.amount, tt.limit, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAwardAllowance(t *testing.T) {
+	tests := []struct {
+		amount, days         string
+		wantAmount, wantDays int
+	}{
+		{"", "", defaultAwardAllowance, defaultAwardAllowanceDays},
+		{"250", "14", 250, 14},
+		{"0", "1", 0, 1},
+		{"-5", "0", defaultAwardAllowance, defaultAwardAllowanceDays},
+		{"lots", "weekly", defaultAwardAllowance, defaultAwardAllowanceDays},
+	}
+	for _, tt := range tests {
+		t.Setenv("AWARD_ALLOWANCE", tt.amount)
+		t.Setenv("AWARD_ALLOWANCE_DAYS", tt.days)
+		amount, days := awardAllowance()
+		if amount != tt.wantAmount || days != tt.wantDays {
+			t.Errorf("awardAllowance() with %q, %q = %d, %d; want %d, %d",
+				tt.amount, tt.days, amount, days, tt.wantAmount, tt.wantDays)
+		}
+	}
+}
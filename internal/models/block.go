@@ -0,0 +1,98 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+type BlockedUser struct {
+	UserSummary
+	BlockedAt time.Time `json:"blocked_at"`
+}
+
+// BlockUser is idempotent. Any follow between the two users is removed.
+func BlockUser(blockerID, blockedID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		INSERT INTO user_blocks (blocker_id, blocked_id)
+		VALUES ($1, $2)
+		ON CONFLICT (blocker_id, blocked_id) DO NOTHING
+	`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to block user: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		DELETE FROM user_follows
+		WHERE (follower_id = $1 AND followed_id = $2)
+		   OR (follower_id = $2 AND followed_id = $1)
+	`, blockerID, blockedID)
+	if err != nil {
+		return fmt.Errorf("failed to remove follows: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+func UnblockUser(blockerID, blockedID int) error {
+	query := `DELETE FROM user_blocks WHERE blocker_id = $1 AND blocked_id = $2`
+	if _, err := database.DB.Exec(query, blockerID, blockedID); err != nil {
+		return fmt.Errorf("failed to unblock user: %w", err)
+	}
+	return nil
+}
+
+// ListBlockedUsers returns the users blockerID has blocked, most recent
+// first.
+func ListBlockedUsers(blockerID int) ([]*BlockedUser, error) {
+	query := `
+		SELECT u.id, u.username, u.avatar_url, b.created_at
+		FROM user_blocks b
+		JOIN users u ON u.id = b.blocked_id
+		WHERE b.blocker_id = $1
+		ORDER BY b.created_at DESC
+	`
+	rows, err := database.DB.Query(query, blockerID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list blocked users: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*BlockedUser{}
+	for rows.Next() {
+		u := &BlockedUser{}
+		if err := rows.Scan(&u.ID, &u.Username, &u.AvatarURL, &u.BlockedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan blocked user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating blocked users: %w", err)
+	}
+
+	return users, nil
+}
+
+// IsBlockedBetween reports whether either user has blocked the other.
+func IsBlockedBetween(a, b int) (bool, error) {
+	query := `
+		SELECT EXISTS (
+			SELECT 1 FROM user_blocks
+			WHERE (blocker_id = $1 AND blocked_id = $2)
+			   OR (blocker_id = $2 AND blocked_id = $1)
+		)
+	`
+	var blocked bool
+	if err := database.DB.QueryRow(query, a, b).Scan(&blocked); err != nil {
+		return false, fmt.Errorf("failed to check block: %w", err)
+	}
+	return blocked, nil
+}
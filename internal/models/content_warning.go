@@ -0,0 +1,342 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+// Built-in content labels. Subreddit content warnings can't reuse them.
+const (
+	ContentLabelNSFW    = "nsfw"
+	ContentLabelSpoiler = "spoiler"
+)
+
+var (
+	ErrContentWarningExists  = errors.New("a content warning with this slug already exists")
+	ErrUnknownContentWarning = errors.New("unknown content warning for this subreddit")
+	ErrContentLabelRemoval   = errors.New("only moderators can remove content labels")
+)
+
+var contentWarningSlug = regexp.MustCompile(`^[a-z0-9_-]{1,32}$`)
+
+// IsContentWarningSlug reports whether slug can name a subreddit content
+// warning.
+func IsContentWarningSlug(slug string) bool {
+	return contentWarningSlug.MatchString(slug) && slug != ContentLabelNSFW && slug != ContentLabelSpoiler
+}
+
+type ContentWarning struct {
+	ID          int       `json:"id"`
+	SubredditID int       `json:"subreddit_id"`
+	Slug        string    `json:"slug"`
+	Label       string    `json:"label"`
+	Description *string   `json:"description,omitempty"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// BlurHint tells clients whether to blur a post for the viewer. Reasons
+// lists every label on the post (nsfw, spoiler and warning slugs); Blurred
+// is set when any of them isn't auto-revealed by the viewer's preferences.
+type BlurHint struct {
+	Blurred bool     `json:"blurred"`
+	Reasons []string `json:"reasons"`
+}
+
+const contentWarningColumns = `id, subreddit_id, slug, label, description, created_at`
+
+func scanContentWarning(row rowScanner) (*ContentWarning, error) {
+	w := &ContentWarning{}
+	if err := row.Scan(&w.ID, &w.SubredditID, &w.Slug, &w.Label, &w.Description, &w.CreatedAt); err != nil {
+		return nil, err
+	}
+	return w, nil
+}
+
+// CreateContentWarning adds a content warning to a subreddit.
+func CreateContentWarning(w *ContentWarning) error {
+	err := database.DB.QueryRow(`
+		INSERT INTO subreddit_content_warnings (subreddit_id, slug, label, description)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, w.SubredditID, w.Slug, w.Label, w.Description).Scan(&w.ID, &w.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrContentWarningExists
+		}
+		return fmt.Errorf("failed to create content warning: %w", err)
+	}
+	return nil
+}
+
+// GetContentWarning returns a content warning by ID, or nil if not found.
+func GetContentWarning(id int) (*ContentWarning, error) {
+	w, err := scanContentWarning(database.DB.QueryRow(
+		`SELECT `+contentWarningColumns+` FROM subreddit_content_warnings WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get content warning: %w", err)
+	}
+	return w, nil
+}
+
+// UpdateContentWarning changes the label and description. The slug is
+// fixed because preferences and filters refer to it.
+func UpdateContentWarning(w *ContentWarning) error {
+	_, err := database.DB.Exec(`UPDATE subreddit_content_warnings SET label = $1, description = $2 WHERE id = $3`,
+		w.Label, w.Description, w.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update content warning: %w", err)
+	}
+	return nil
+}
+
+// DeleteContentWarning deletes a content warning and untags its posts.
+func DeleteContentWarning(id int) error {
+	if _, err := database.DB.Exec(`DELETE FROM subreddit_content_warnings WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete content warning: %w", err)
+	}
+	return nil
+}
+
+// ListContentWarnings returns a subreddit's content warnings by slug.
+func ListContentWarnings(subredditID int) ([]*ContentWarning, error) {
+	rows, err := database.DB.Query(`SELECT `+contentWarningColumns+`
+		FROM subreddit_content_warnings WHERE subreddit_id = $1 ORDER BY slug`, subredditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list content warnings: %w", err)
+	}
+	defer rows.Close()
+
+	warnings := []*ContentWarning{}
+	for rows.Next() {
+		w, err := scanContentWarning(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan content warning: %w", err)
+		}
+		warnings = append(warnings, w)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating content warnings: %w", err)
+	}
+
+	return warnings, nil
+}
+
+// ResolveContentWarnings looks up slugs among a subreddit's content
+// warnings, failing with ErrUnknownContentWarning if any is missing.
+func ResolveContentWarnings(subredditID int, slugs []string) ([]*ContentWarning, error) {
+	if len(slugs) == 0 {
+		return []*ContentWarning{}, nil
+	}
+
+	all, err := ListContentWarnings(subredditID)
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]*ContentWarning, len(all))
+	for _, w := range all {
+		bySlug[w.Slug] = w
+	}
+
+	resolved := []*ContentWarning{}
+	seen := make(map[string]bool)
+	for _, slug := range slugs {
+		w, ok := bySlug[slug]
+		if !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnknownContentWarning, slug)
+		}
+		if !seen[slug] {
+			seen[slug] = true
+			resolved = append(resolved, w)
+		}
+	}
+	return resolved, nil
+}
+
+// setPostContentWarnings replaces the content warnings of a post in tx.
+func setPostContentWarnings(tx *sql.Tx, postID int, warnings []*ContentWarning) error {
+	if _, err := tx.Exec(`DELETE FROM post_content_warnings WHERE post_id = $1`, postID); err != nil {
+		return fmt.Errorf("failed to clear content warnings: %w", err)
+	}
+	for _, w := range warnings {
+		_, err := tx.Exec(`INSERT INTO post_content_warnings (post_id, warning_id) VALUES ($1, $2)`, postID, w.ID)
+		if err != nil {
+			return fmt.Errorf("failed to tag content warning: %w", err)
+		}
+	}
+	return nil
+}
+
+// SetPostContentLabels updates the spoiler flag and content warnings of a
+// post. Unless canRemove is set, the labels the post already has must be
+// kept, or ErrContentLabelRemoval is returned.
+func SetPostContentLabels(post *Post, isSpoiler bool, warnings []*ContentWarning, canRemove bool) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if !canRemove {
+		var wasSpoiler, dropsWarning bool
+		err := tx.QueryRow(`
+			SELECT is_spoiler, EXISTS (
+				SELECT 1 FROM post_content_warnings
+				WHERE post_id = $1 AND NOT (warning_id = ANY($2))
+			)
+			FROM posts WHERE id = $1 FOR UPDATE
+		`, post.ID, pq.Array(contentWarningIDs(warnings))).Scan(&wasSpoiler, &dropsWarning)
+		if err != nil {
+			return fmt.Errorf("failed to get content labels: %w", err)
+		}
+		if (wasSpoiler && !isSpoiler) || dropsWarning {
+			return ErrContentLabelRemoval
+		}
+	}
+
+	if _, err := tx.Exec(`UPDATE posts SET is_spoiler = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, isSpoiler, post.ID); err != nil {
+		return fmt.Errorf("failed to update spoiler flag: %w", err)
+	}
+	if err := setPostContentWarnings(tx, post.ID, warnings); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit content labels: %w", err)
+	}
+
+	post.IsSpoiler = isSpoiler
+	post.ContentWarnings = warnings
+	return nil
+}
+
+func contentWarningIDs(warnings []*ContentWarning) []int64 {
+	ids := make([]int64, len(warnings))
+	for i, w := range warnings {
+		ids[i] = int64(w.ID)
+	}
+	return ids
+}
+
+// loadContentWarnings attaches content warnings to posts.
+func loadContentWarnings(posts []*Post) error {
+	if len(posts) == 0 {
+		return nil
+	}
+	byID := make(map[int][]*Post)
+	ids := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		p.ContentWarnings = []*ContentWarning{}
+		if _, ok := byID[p.ID]; !ok {
+			ids = append(ids, int64(p.ID))
+		}
+		byID[p.ID] = append(byID[p.ID], p)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT pcw.post_id, w.id, w.subreddit_id, w.slug, w.label, w.description, w.created_at
+		FROM post_content_warnings pcw
+		JOIN subreddit_content_warnings w ON w.id = pcw.warning_id
+		WHERE pcw.post_id = ANY($1)
+		ORDER BY w.slug
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load content warnings: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		w := &ContentWarning{}
+		if err := rows.Scan(&postID, &w.ID, &w.SubredditID, &w.Slug, &w.Label, &w.Description, &w.CreatedAt); err != nil {
+			return fmt.Errorf("failed to scan content warning: %w", err)
+		}
+		for _, p := range byID[postID] {
+			p.ContentWarnings = append(p.ContentWarnings, w)
+		}
+	}
+
+	return rows.Err()
+}
+
+// applyBlurHints sets the blur hint of each post for a viewer with prefs.
+func applyBlurHints(posts []*Post, prefs *Preferences) error {
+	var subredditIDs []int64
+	for _, p := range posts {
+		subredditIDs = append(subredditIDs, int64(p.SubredditID))
+	}
+	nsfwSubreddits := make(map[int]bool)
+	if len(subredditIDs) > 0 {
+		rows, err := database.DB.Query(`SELECT id FROM subreddits WHERE is_nsfw AND id = ANY($1)`, pq.Array(subredditIDs))
+		if err != nil {
+			return fmt.Errorf("failed to load NSFW subreddits: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id int
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("failed to scan subreddit: %w", err)
+			}
+			nsfwSubreddits[id] = true
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("error iterating subreddits: %w", err)
+		}
+	}
+
+	reveal := make(map[string]bool)
+	for _, slug := range prefs.AutoRevealWarnings {
+		reveal[slug] = true
+	}
+	reveal[ContentLabelNSFW] = !prefs.BlurNSFW
+
+	for _, p := range posts {
+		hint := &BlurHint{Reasons: []string{}}
+		if p.IsNSFW || nsfwSubreddits[p.SubredditID] {
+			hint.Reasons = append(hint.Reasons, ContentLabelNSFW)
+		}
+		if p.IsSpoiler {
+			hint.Reasons = append(hint.Reasons, ContentLabelSpoiler)
+		}
+		for _, w := range p.ContentWarnings {
+			hint.Reasons = append(hint.Reasons, w.Slug)
+		}
+		for _, reason := range hint.Reasons {
+			if !reveal[reason] {
+				hint.Blurred = true
+			}
+		}
+		p.Blur = hint
+	}
+	return nil
+}
+
+// applyContentFilters excludes spoilers and posts tagged with any of the
+// given warning slugs from a posts query.
+func applyContentFilters(q *queryBuilder, excludeSpoilers bool, excludeWarnings []string) {
+	if excludeSpoilers {
+		q.where("NOT is_spoiler")
+	}
+	if len(excludeWarnings) > 0 {
+		q.where(`id NOT IN (
+			SELECT pcw.post_id FROM post_content_warnings pcw
+			JOIN subreddit_content_warnings w ON w.id = pcw.warning_id
+			WHERE w.slug = ANY(` + q.arg(pq.Array(excludeWarnings)) + `))`)
+	}
+}
+
+// hasInlineSpoilers reports whether rendered markdown contains >!spoilers!<.
+func hasInlineSpoilers(html *string) bool {
+	return html != nil && strings.Contains(*html, `<span class="md-spoiler">`)
+}
@@ -0,0 +1,166 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+var (
+	ErrCrosspostSameSubreddit = errors.New("the post is already in this subreddit")
+	ErrCrosspostPrivateSource = errors.New("posts from private subreddits can't be crossposted")
+	ErrCrosspostPrivateTarget = errors.New("only moderators can post in this private subreddit")
+	ErrCrosspostNSFW          = errors.New("NSFW posts can only be crossposted to NSFW subreddits")
+	ErrCrosspostPoll          = errors.New("polls can't be crossposted")
+)
+
+// CreateCrosspost shares original into target. Crossposting a crosspost
+// shares its original instead, so every crosspost points at the root post.
+// The crosspost inherits NSFW from the original and its subreddit.
+func CreateCrosspost(original *Post, target *Subreddit, authorID int, title string, isNSFW bool) (*Post, error) {
+	if original.CrosspostParent != nil {
+		original = original.CrosspostParent
+	}
+	// Votes belong to the original poll; a copy would split them.
+	if original.PostType == PostTypePoll {
+		return nil, ErrCrosspostPoll
+	}
+
+	source, err := GetSubredditByID(original.SubredditID)
+	if err != nil {
+		return nil, err
+	}
+	if source == nil {
+		return nil, fmt.Errorf("subreddit %d of post %d not found", original.SubredditID, original.ID)
+	}
+
+	if source.ID == target.ID {
+		return nil, ErrCrosspostSameSubreddit
+	}
+	if source.IsPrivate {
+		return nil, ErrCrosspostPrivateSource
+	}
+	if target.IsPrivate {
+		isMod, err := IsSubredditModerator(target.ID, authorID)
+		if err != nil {
+			return nil, err
+		}
+		if !isMod {
+			return nil, ErrCrosspostPrivateTarget
+		}
+	}
+
+	isNSFW = isNSFW || original.IsNSFW || source.IsNSFW
+	if isNSFW && !target.IsNSFW {
+		return nil, ErrCrosspostNSFW
+	}
+
+	post, err := SubmitPost(&Post{
+		Title:             title,
+		PostType:          original.PostType,
+		AuthorID:          authorID,
+		SubredditID:       target.ID,
+		IsNSFW:            isNSFW,
+		IsSpoiler:         original.IsSpoiler,
+		CrosspostParentID: &original.ID,
+	}, target)
+	if err != nil {
+		return nil, err
+	}
+	post.CrosspostParent = original
+	return post, nil
+}
+
+// loadCrosspostParents attaches the original post to every crosspost in
+// posts with a single query.
+func loadCrosspostParents(posts []*Post) error {
+	var ids []int64
+	for _, p := range posts {
+		if p.CrosspostParentID != nil {
+			ids = append(ids, int64(*p.CrosspostParentID))
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := database.DB.Query(`SELECT `+postColumns+` FROM posts WHERE id = ANY($1)`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load crosspost parents: %w", err)
+	}
+	defer rows.Close()
+
+	parents := make(map[int]*Post, len(ids))
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return fmt.Errorf("failed to scan post: %w", err)
+		}
+		parents[p.ID] = p
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating posts: %w", err)
+	}
+
+	for _, p := range posts {
+		if p.CrosspostParentID != nil {
+			p.CrosspostParent = parents[*p.CrosspostParentID]
+		}
+	}
+	return nil
+}
+
+// ListOtherDiscussions returns posts discussing the same thing as post:
+// its original and the original's other crossposts, and other posts of the
+// same link URL. The viewer's filters apply as in ListPosts.
+func ListOtherDiscussions(post *Post, viewerID *int, includeNSFW bool, limit, offset int) ([]*Post, error) {
+	rootID := post.ID
+	if post.CrosspostParentID != nil {
+		rootID = *post.CrosspostParentID
+	}
+
+	q := &queryBuilder{}
+	root := q.arg(rootID)
+	same := "id = " + root + " OR crosspost_parent_id = " + root
+	if post.LinkURL != nil && *post.LinkURL != "" {
+		same += " OR link_url = " + q.arg(*post.LinkURL)
+	}
+	q.where("(" + same + ")")
+	q.where("id <> " + q.arg(post.ID))
+	q.where("subreddit_id NOT IN (SELECT id FROM subreddits WHERE is_private)")
+	applyNSFWFilter(q, includeNSFW)
+	applyViewerFilters(q, viewerID, false)
+
+	query := `SELECT ` + postColumns + ` FROM posts` + q.whereClause() +
+		` ORDER BY score DESC, created_at DESC LIMIT ` + q.arg(limit) + ` OFFSET ` + q.arg(offset)
+
+	rows, err := database.DB.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list other discussions: %w", err)
+	}
+	defer rows.Close()
+
+	posts := []*Post{}
+	for rows.Next() {
+		p, err := scanPost(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		posts = append(posts, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posts: %w", err)
+	}
+
+	if err := loadCrosspostParents(posts); err != nil {
+		return nil, err
+	}
+	if err := LoadPostDetails(posts, viewerID); err != nil {
+		return nil, err
+	}
+
+	return posts, nil
+}
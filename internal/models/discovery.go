@@ -0,0 +1,276 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+// MaxSubredditCategories caps how many categories a subreddit can be
+// listed under.
+const MaxSubredditCategories = 3
+
+// Trending compares the last TrendingWindow of activity with the daily
+// average over the TrendingBaselineDays before it. Subreddits need at
+// least MinTrendingActivity in the window to trend.
+const (
+	TrendingWindow       = 24 * time.Hour
+	TrendingBaselineDays = 7
+	MinTrendingActivity  = 10
+)
+
+// MinSharedContributors is how many contributors two subreddits must share
+// before one is recommended from the other. Contributors are users who
+// posted in the subreddit, since there are no subscriptions.
+const MinSharedContributors = 2
+
+// maxSimilarityContributors caps how many of a subreddit's most recent
+// contributors are compared when looking for similar subreddits.
+const maxSimilarityContributors = 1000
+
+var ErrUnknownCategory = errors.New("unknown category")
+
+type Category struct {
+	ID             int    `json:"id"`
+	Slug           string `json:"slug"`
+	Name           string `json:"name"`
+	SubredditCount int    `json:"subreddit_count"`
+}
+
+// SubredditFilter narrows subreddit listings.
+type SubredditFilter struct {
+	IncludeNSFW bool
+	Category    string // Category slug
+	Language    string
+	MinMembers  int
+}
+
+type TrendingSubreddit struct {
+	*Subreddit
+	RecentActivity int     `json:"recent_activity"` // Activity in the trending window
+	Growth         float64 `json:"growth"`          // Ratio to the daily baseline
+}
+
+type SimilarSubreddit struct {
+	*Subreddit
+	SharedContributors int    `json:"shared_contributors"`
+	Reason             string `json:"reason"` // "contributors" or "category"
+}
+
+// applySubredditFilter adds the filter's conditions to a query over
+// subreddits.
+func applySubredditFilter(q *queryBuilder, filter SubredditFilter) {
+	if !filter.IncludeNSFW {
+		q.where("NOT is_nsfw")
+	}
+	if filter.Category != "" {
+		q.where(`id IN (SELECT sc.subreddit_id FROM subreddit_categories sc
+			JOIN categories c ON c.id = sc.category_id WHERE c.slug = ` + q.arg(filter.Category) + `)`)
+	}
+	if filter.Language != "" {
+		q.where("language = " + q.arg(filter.Language))
+	}
+	if filter.MinMembers > 0 {
+		q.where("members_count >= " + q.arg(filter.MinMembers))
+	}
+}
+
+// ListCategories returns the category catalogue with how many subreddits
+// each lists, ordered by name.
+func ListCategories() ([]*Category, error) {
+	rows, err := database.DB.Query(`
+		SELECT c.id, c.slug, c.name, COUNT(sc.subreddit_id)
+		FROM categories c
+		LEFT JOIN subreddit_categories sc ON sc.category_id = c.id
+		GROUP BY c.id
+		ORDER BY c.name
+	`)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []*Category{}
+	for rows.Next() {
+		c := &Category{}
+		if err := rows.Scan(&c.ID, &c.Slug, &c.Name, &c.SubredditCount); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, c)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+// setSubredditCategories replaces a subreddit's categories with the given
+// slugs. It returns ErrUnknownCategory if any slug isn't in the catalogue.
+func setSubredditCategories(tx *sql.Tx, subredditID int, slugs []string) error {
+	if _, err := tx.Exec(`DELETE FROM subreddit_categories WHERE subreddit_id = $1`, subredditID); err != nil {
+		return fmt.Errorf("failed to clear subreddit categories: %w", err)
+	}
+	if len(slugs) == 0 {
+		return nil
+	}
+
+	res, err := tx.Exec(`
+		INSERT INTO subreddit_categories (subreddit_id, category_id)
+		SELECT $1, id FROM categories WHERE slug = ANY($2)
+	`, subredditID, pq.Array(slugs))
+	if err != nil {
+		return fmt.Errorf("failed to set subreddit categories: %w", err)
+	}
+	if n, err := res.RowsAffected(); err != nil {
+		return fmt.Errorf("failed to set subreddit categories: %w", err)
+	} else if int(n) != len(slugs) {
+		return ErrUnknownCategory
+	}
+	return nil
+}
+
+// ListTrendingSubreddits ranks public subreddits by recent activity from
+// the traffic rollups (unique visitors, plus posts, comments and net
+// subscriptions weighted 5 each), scaled by growth over their baseline, so
+// a small community taking off can outrank a big steady one.
+func ListTrendingSubreddits(limit int, filter SubredditFilter) ([]*TrendingSubreddit, error) {
+	now := time.Now().UTC()
+	q := &queryBuilder{}
+	windowStart := q.arg(now.Add(-TrendingWindow))
+	baselineStart := q.arg(now.Add(-TrendingWindow).AddDate(0, 0, -TrendingBaselineDays))
+	baselineDays := q.arg(TrendingBaselineDays)
+	q.where("NOT is_private")
+	q.where("t.recent_activity >= " + q.arg(MinTrendingActivity))
+	applySubredditFilter(q, filter)
+
+	query := `SELECT ` + subredditColumns + `, t.recent_activity, (t.recent_activity + 1) / (t.baseline + 1)
+		FROM subreddits
+		JOIN (
+			SELECT subreddit_id,
+			       COALESCE(SUM(activity) FILTER (WHERE hour >= ` + windowStart + `), 0) AS recent_activity,
+			       COALESCE(SUM(activity) FILTER (WHERE hour < ` + windowStart + `), 0)::float / ` + baselineDays + ` AS baseline
+			FROM (
+				SELECT subreddit_id, hour,
+				       unique_visitors + 5 * (posts + comments + GREATEST(subscriptions - unsubscriptions, 0)) AS activity
+				FROM subreddit_traffic_hourly
+				WHERE hour >= ` + baselineStart + `
+			) h
+			GROUP BY subreddit_id
+		) t ON t.subreddit_id = subreddits.id` +
+		q.whereClause() +
+		` ORDER BY t.recent_activity * (t.recent_activity + 1) / (t.baseline + 1) DESC, members_count DESC
+		LIMIT ` + q.arg(limit)
+
+	rows, err := database.DB.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list trending subreddits: %w", err)
+	}
+	defer rows.Close()
+
+	trending := []*TrendingSubreddit{}
+	for rows.Next() {
+		t := &TrendingSubreddit{Subreddit: &Subreddit{}}
+		if err := rows.Scan(append(subredditScanTargets(t.Subreddit), &t.RecentActivity, &t.Growth)...); err != nil {
+			return nil, fmt.Errorf("failed to scan trending subreddit: %w", err)
+		}
+		finishSubredditScan(t.Subreddit)
+		trending = append(trending, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating trending subreddits: %w", err)
+	}
+
+	return trending, nil
+}
+
+// ListSimilarSubreddits recommends public subreddits for people who like
+// subreddit. Subreddits sharing contributors come first, ranked by overlap
+// relative to their own contributor count; the rest of the list is filled with subreddits
+// in the same categories, largest first. Only the subreddit's most recent
+// contributors are compared, using the subreddit_contributors table.
+func ListSimilarSubreddits(subreddit *Subreddit, limit int, includeNSFW bool) ([]*SimilarSubreddit, error) {
+	rows, err := database.DB.Query(`
+		WITH mine AS (
+			SELECT author_id FROM subreddit_contributors
+			WHERE subreddit_id = $1
+			ORDER BY last_posted_at DESC
+			LIMIT $5
+		)
+		SELECT `+subredditColumns+`, t.shared
+		FROM subreddits
+		JOIN (
+			SELECT c.subreddit_id, COUNT(*) AS shared
+			FROM subreddit_contributors c
+			JOIN mine ON mine.author_id = c.author_id
+			WHERE c.subreddit_id <> $1
+			GROUP BY c.subreddit_id
+			HAVING COUNT(*) >= $2
+		) t ON t.subreddit_id = subreddits.id
+		WHERE NOT is_private AND ($3 OR NOT is_nsfw)
+		ORDER BY t.shared / SQRT(GREATEST(subreddits.contributors_count, 1)) DESC,
+		         members_count DESC
+		LIMIT $4
+	`, subreddit.ID, MinSharedContributors, includeNSFW, limit, maxSimilarityContributors)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list similar subreddits: %w", err)
+	}
+
+	similar := []*SimilarSubreddit{}
+	seen := []int64{int64(subreddit.ID)}
+	for rows.Next() {
+		s := &SimilarSubreddit{Subreddit: &Subreddit{}, Reason: "contributors"}
+		if err := rows.Scan(append(subredditScanTargets(s.Subreddit), &s.SharedContributors)...); err != nil {
+			rows.Close()
+			return nil, fmt.Errorf("failed to scan similar subreddit: %w", err)
+		}
+		finishSubredditScan(s.Subreddit)
+		similar = append(similar, s)
+		seen = append(seen, int64(s.ID))
+	}
+	rows.Close()
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating similar subreddits: %w", err)
+	}
+
+	if len(similar) >= limit || len(subreddit.Categories) == 0 {
+		return similar, nil
+	}
+
+	rows, err = database.DB.Query(`
+		SELECT `+subredditColumns+`
+		FROM subreddits
+		WHERE id IN (
+			SELECT sc.subreddit_id FROM subreddit_categories sc
+			JOIN categories c ON c.id = sc.category_id
+			WHERE c.slug = ANY($1)
+		)
+		  AND id <> ALL($2) AND NOT is_private AND ($3 OR NOT is_nsfw)
+		ORDER BY members_count DESC, id
+		LIMIT $4
+	`, pq.Array(subreddit.Categories), pq.Array(seen), includeNSFW, limit-len(similar))
+	if err != nil {
+		return nil, fmt.Errorf("failed to list similar subreddits: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		s, err := scanSubreddit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan similar subreddit: %w", err)
+		}
+		similar = append(similar, &SimilarSubreddit{Subreddit: s, Reason: "category"})
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating similar subreddits: %w", err)
+	}
+
+	return similar, nil
+}
@@ -0,0 +1,394 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+// Draft statuses
+const (
+	DraftStatusDraft     = "draft"
+	DraftStatusScheduled = "scheduled"
+	DraftStatusPublished = "published"
+	DraftStatusCancelled = "cancelled"
+	DraftStatusFailed    = "failed"
+)
+
+// Draft recurrences
+const (
+	RecurrenceDaily   = "daily"
+	RecurrenceWeekly  = "weekly"
+	RecurrenceMonthly = "monthly"
+)
+
+var (
+	ErrDraftNotEditable = errors.New("published and cancelled drafts can't be changed")
+	ErrDraftChanged     = errors.New("the draft was changed or published in the meantime")
+)
+
+type PostDraft struct {
+	ID              int64      `json:"id"`
+	AuthorID        int        `json:"author_id"`
+	SubredditID     *int       `json:"subreddit_id"`
+	Title           string     `json:"title"`
+	Content         *string    `json:"content"`
+	PostType        string     `json:"post_type"`
+	LinkURL         *string    `json:"link_url"`
+	ImageURL        *string    `json:"image_url"`
+	IsNSFW          bool       `json:"is_nsfw"`
+	IsSpoiler       bool       `json:"is_spoiler"`
+	ContentWarnings []string   `json:"content_warnings"` // Slugs, resolved when published
+	Status          string     `json:"status"`
+	PublishAt       *time.Time `json:"publish_at"`
+	Recurrence      *string    `json:"recurrence"`
+	RecurrenceStart *time.Time `json:"recurrence_start"` // First occurrence; later ones are counted from it
+	PublishedPostID *int       `json:"published_post_id"`
+	LastError       *string    `json:"last_error"`
+	CreatedAt       time.Time  `json:"created_at"`
+	UpdatedAt       time.Time  `json:"updated_at"`
+}
+
+func IsRecurrence(r string) bool {
+	return r == RecurrenceDaily || r == RecurrenceWeekly || r == RecurrenceMonthly
+}
+
+// nextOccurrence returns the first occurrence of a recurrence starting at
+// start that is after now. Occurrence n is counted from start rather than
+// from the previous one, and monthly occurrences fall on start's day of the
+// month or the month's last day if it is shorter, so Jan 31 is followed by
+// Feb 28 and Mar 31.
+func nextOccurrence(start time.Time, recurrence string, now time.Time) time.Time {
+	t := start
+	for n := 1; !t.After(now); n++ {
+		switch recurrence {
+		case RecurrenceDaily:
+			t = start.AddDate(0, 0, n)
+		case RecurrenceWeekly:
+			t = start.AddDate(0, 0, 7*n)
+		default:
+			t = addMonthsClamped(start, n)
+		}
+	}
+	return t
+}
+
+// addMonthsClamped adds n months to t, moving to the last day of the
+// resulting month instead of overflowing into the next one.
+func addMonthsClamped(t time.Time, n int) time.Time {
+	year, month, day := t.Date()
+	lastDay := time.Date(year, month+time.Month(n)+1, 0, 0, 0, 0, 0, t.Location()).Day()
+	return time.Date(year, month+time.Month(n), min(day, lastDay),
+		t.Hour(), t.Minute(), t.Second(), t.Nanosecond(), t.Location())
+}
+
+const draftColumns = `id, author_id, subreddit_id, title, content, post_type, link_url, image_url,
+		is_nsfw, is_spoiler, content_warnings, status, publish_at, recurrence, recurrence_start,
+		published_post_id, last_error, created_at, updated_at`
+
+func scanDraft(row rowScanner) (*PostDraft, error) {
+	d := &PostDraft{}
+	err := row.Scan(&d.ID, &d.AuthorID, &d.SubredditID, &d.Title, &d.Content, &d.PostType, &d.LinkURL, &d.ImageURL,
+		&d.IsNSFW, &d.IsSpoiler, (*pq.StringArray)(&d.ContentWarnings), &d.Status, &d.PublishAt, &d.Recurrence, &d.RecurrenceStart,
+		&d.PublishedPostID, &d.LastError, &d.CreatedAt, &d.UpdatedAt)
+	return d, err
+}
+
+func CreateDraft(d *PostDraft) (*PostDraft, error) {
+	var recurrenceStart *time.Time
+	if d.Recurrence != nil {
+		recurrenceStart = d.PublishAt
+	}
+
+	query := `
+		INSERT INTO post_drafts (
+			author_id, subreddit_id, title, content, post_type, link_url, image_url,
+			is_nsfw, status, publish_at, recurrence, is_spoiler, content_warnings, recurrence_start
+		)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+		RETURNING ` + draftColumns
+
+	draft, err := scanDraft(database.DB.QueryRow(query, d.AuthorID, d.SubredditID, d.Title, d.Content, d.PostType,
+		d.LinkURL, d.ImageURL, d.IsNSFW, d.Status, d.PublishAt, d.Recurrence, d.IsSpoiler, pq.Array(d.ContentWarnings),
+		recurrenceStart))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create draft: %w", err)
+	}
+	return draft, nil
+}
+
+// GetDraft returns nil when the draft does not exist.
+func GetDraft(id int64) (*PostDraft, error) {
+	draft, err := scanDraft(database.DB.QueryRow(`SELECT `+draftColumns+` FROM post_drafts WHERE id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get draft: %w", err)
+	}
+	return draft, nil
+}
+
+// ListDrafts returns an author's drafts, optionally only those with the
+// given status. Scheduled drafts are ordered by when they publish.
+func ListDrafts(authorID int, status string, limit, offset int) ([]*PostDraft, error) {
+	q := &queryBuilder{}
+	q.where("author_id = " + q.arg(authorID))
+	if status != "" {
+		q.where("status = " + q.arg(status))
+	}
+
+	query := `SELECT ` + draftColumns + ` FROM post_drafts` + q.whereClause() +
+		` ORDER BY (status = 'scheduled') DESC, publish_at, updated_at DESC` +
+		` LIMIT ` + q.arg(limit) + ` OFFSET ` + q.arg(offset)
+
+	rows, err := database.DB.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list drafts: %w", err)
+	}
+	defer rows.Close()
+
+	drafts := []*PostDraft{}
+	for rows.Next() {
+		d, err := scanDraft(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan draft: %w", err)
+		}
+		drafts = append(drafts, d)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating drafts: %w", err)
+	}
+
+	return drafts, nil
+}
+
+// UpdateDraft saves the editable fields and status of a draft that has not
+// been published or cancelled. A recurring draft keeps its recurrence_start
+// unless its schedule changes.
+func UpdateDraft(d *PostDraft) error {
+	query := `
+		UPDATE post_drafts SET
+			subreddit_id = $2, title = $3, content = $4, post_type = $5, link_url = $6,
+			image_url = $7, is_nsfw = $8, status = $9, publish_at = $10, recurrence = $11,
+			is_spoiler = $12, content_warnings = $13, last_error = NULL, updated_at = CURRENT_TIMESTAMP,
+			recurrence_start = CASE
+				WHEN $11 IS NULL THEN NULL
+				WHEN publish_at = $10 AND recurrence = $11 THEN COALESCE(recurrence_start, $10)
+				ELSE $10
+			END
+		WHERE id = $1 AND status IN ('draft', 'scheduled', 'failed')
+		RETURNING updated_at, recurrence_start
+	`
+	err := database.DB.QueryRow(query, d.ID, d.SubredditID, d.Title, d.Content, d.PostType, d.LinkURL,
+		d.ImageURL, d.IsNSFW, d.Status, d.PublishAt, d.Recurrence, d.IsSpoiler, pq.Array(d.ContentWarnings)).
+		Scan(&d.UpdatedAt, &d.RecurrenceStart)
+	if err == sql.ErrNoRows {
+		return ErrDraftNotEditable
+	}
+	if err != nil {
+		return fmt.Errorf("failed to update draft: %w", err)
+	}
+	d.LastError = nil
+	return nil
+}
+
+// CancelDraft stops a scheduled draft from publishing again. It returns
+// false if the draft was not scheduled.
+func CancelDraft(id int64) (bool, error) {
+	result, err := database.DB.Exec(`
+		UPDATE post_drafts SET status = 'cancelled', updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND status = 'scheduled'
+	`, id)
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel draft: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to cancel draft: %w", err)
+	}
+	return n > 0, nil
+}
+
+func DeleteDraft(id int64) error {
+	if _, err := database.DB.Exec(`DELETE FROM post_drafts WHERE id = $1`, id); err != nil {
+		return fmt.Errorf("failed to delete draft: %w", err)
+	}
+	return nil
+}
+
+// PublishDraft turns a draft into a post now. The draft is marked
+// published, or for recurring drafts rescheduled to its next occurrence.
+// The draft is claimed and the post created in one transaction, so a
+// scheduler run publishing the same draft can't create a second post. It
+// returns ErrDraftChanged if the draft is no longer as the caller read it.
+func PublishDraft(d *PostDraft) (*Post, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	claimed, err := scanDraft(tx.QueryRow(`
+		UPDATE post_drafts SET updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1 AND updated_at = $2 AND status IN ('draft', 'scheduled', 'failed')
+		RETURNING `+draftColumns, d.ID, d.UpdatedAt))
+	if err == sql.ErrNoRows {
+		return nil, ErrDraftChanged
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to claim draft: %w", err)
+	}
+
+	post, published, err := publishDraft(tx, claimed)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit draft: %w", err)
+	}
+	published()
+	*d = *claimed
+
+	return post, nil
+}
+
+// publishDraft creates the draft's post and records the outcome through tx,
+// which must hold the draft's row lock. The returned function announces the
+// post and must be called once tx has committed.
+func publishDraft(tx *sql.Tx, d *PostDraft) (*Post, func(), error) {
+	if d.SubredditID == nil {
+		return nil, nil, fmt.Errorf("a subreddit is required to publish")
+	}
+	subreddit, err := GetSubredditByID(*d.SubredditID)
+	if err != nil {
+		return nil, nil, err
+	}
+	if subreddit == nil {
+		return nil, nil, fmt.Errorf("subreddit no longer exists")
+	}
+	warnings, err := ResolveContentWarnings(subreddit.ID, d.ContentWarnings)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	post := &Post{
+		Title:       d.Title,
+		Content:     d.Content,
+		PostType:    d.PostType,
+		LinkURL:     d.LinkURL,
+		ImageURL:    d.ImageURL,
+		AuthorID:    d.AuthorID,
+		SubredditID: subreddit.ID,
+		IsNSFW:      d.IsNSFW,
+		IsSpoiler:   d.IsSpoiler,
+
+		ContentWarnings: warnings,
+	}
+	published, err := submitPost(tx, post, subreddit)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	d.PublishedPostID = &post.ID
+	d.Status = DraftStatusPublished
+	if d.Recurrence != nil && d.PublishAt != nil {
+		start := d.PublishAt
+		if d.RecurrenceStart != nil {
+			start = d.RecurrenceStart
+		}
+		next := nextOccurrence(*start, *d.Recurrence, time.Now())
+		d.PublishAt = &next
+		d.Status = DraftStatusScheduled
+	}
+
+	err = tx.QueryRow(`
+		UPDATE post_drafts
+		SET status = $2, publish_at = $3, published_post_id = $4, last_error = NULL, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`, d.ID, d.Status, d.PublishAt, post.ID).Scan(&d.UpdatedAt)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to mark draft published: %w", err)
+	}
+	d.LastError = nil
+
+	return post, published, nil
+}
+
+// PublishDueDrafts publishes scheduled drafts whose time has come and
+// returns how many were processed. Each draft is locked while it is
+// published so concurrent schedulers skip it. A draft that can't be
+// published is marked failed with the reason.
+func PublishDueDrafts(limit int) (int, error) {
+	processed := 0
+	for processed < limit {
+		done, err := publishNextDueDraft()
+		if err != nil {
+			return processed, err
+		}
+		if !done {
+			break
+		}
+		processed++
+	}
+	return processed, nil
+}
+
+func publishNextDueDraft() (bool, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return false, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	draft, err := scanDraft(tx.QueryRow(`
+		SELECT ` + draftColumns + `
+		FROM post_drafts
+		WHERE status = 'scheduled' AND publish_at <= NOW()
+		ORDER BY publish_at
+		LIMIT 1
+		FOR UPDATE SKIP LOCKED
+	`))
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, fmt.Errorf("failed to get due draft: %w", err)
+	}
+
+	// A failed attempt is rolled back to here, keeping the row lock while
+	// the draft is marked failed.
+	if _, err = tx.Exec(`SAVEPOINT publish_draft`); err != nil {
+		return false, fmt.Errorf("failed to create savepoint: %w", err)
+	}
+	_, published, publishErr := publishDraft(tx, draft)
+	if publishErr != nil {
+		log.Printf("scheduled draft %d failed: %v", draft.ID, publishErr)
+		if _, err = tx.Exec(`ROLLBACK TO SAVEPOINT publish_draft`); err != nil {
+			return false, fmt.Errorf("failed to roll back draft: %w", err)
+		}
+		_, err = tx.Exec(`
+			UPDATE post_drafts SET status = 'failed', last_error = $2, updated_at = CURRENT_TIMESTAMP
+			WHERE id = $1
+		`, draft.ID, publishErr.Error())
+		if err != nil {
+			return false, fmt.Errorf("failed to mark draft failed: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return false, fmt.Errorf("failed to commit draft: %w", err)
+	}
+	if published != nil {
+		published()
+	}
+	return true, nil
+}
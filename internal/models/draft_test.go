@@ -0,0 +1,37 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestNextOccurrence(t *testing.T) {
+	date := func(year int, month time.Month, day int) time.Time {
+		return time.Date(year, month, day, 9, 30, 0, 0, time.UTC)
+	}
+	tests := []struct {
+		name       string
+		start      time.Time
+		recurrence string
+		now        time.Time
+		want       time.Time
+	}{
+		{"daily", date(2025, 3, 1), RecurrenceDaily, date(2025, 3, 1), date(2025, 3, 2)},
+		{"daily catches up", date(2025, 3, 1), RecurrenceDaily, date(2025, 3, 10).Add(time.Hour), date(2025, 3, 11)},
+		{"weekly", date(2025, 3, 3), RecurrenceWeekly, date(2025, 3, 12), date(2025, 3, 17)},
+		{"monthly", date(2025, 1, 15), RecurrenceMonthly, date(2025, 1, 15), date(2025, 2, 15)},
+		{"monthly clamps to february", date(2025, 1, 31), RecurrenceMonthly, date(2025, 1, 31), date(2025, 2, 28)},
+		{"monthly clamps in leap year", date(2024, 1, 31), RecurrenceMonthly, date(2024, 1, 31), date(2024, 2, 29)},
+		{"monthly returns to the 31st", date(2025, 1, 31), RecurrenceMonthly, date(2025, 2, 28), date(2025, 3, 31)},
+		{"monthly after a 30-day month", date(2025, 1, 31), RecurrenceMonthly, date(2025, 4, 30), date(2025, 5, 31)},
+		{"monthly across a year", date(2025, 11, 30), RecurrenceMonthly, date(2026, 1, 30), date(2026, 2, 28)},
+		{"start in the future", date(2025, 6, 1), RecurrenceMonthly, date(2025, 1, 1), date(2025, 6, 1)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := nextOccurrence(tt.start, tt.recurrence, tt.now); !got.Equal(tt.want) {
+				t.Errorf("nextOccurrence(%v, %s, %v) = %v, want %v", tt.start, tt.recurrence, tt.now, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package models
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+var ErrFollowNotAllowed = errors.New("you can't follow this user")
+
+type FollowCounts struct {
+	Followers int `json:"followers"`
+	Following int `json:"following"`
+}
+
+type FollowEntry struct {
+	UserSummary
+	FollowedAt time.Time `json:"followed_at"`
+}
+
+// hiddenProfilesSQL selects users whose profile_visibility preference is
+// hidden.
+const hiddenProfilesSQL = `SELECT user_id FROM user_preferences WHERE settings->>'profile_visibility' = '` + ProfileHidden + `'`
+
+// showsNSFWSQL selects users whose show_nsfw preference is on.
+const showsNSFWSQL = `SELECT user_id FROM user_preferences WHERE settings->>'show_nsfw' = 'true'`
+
+// FollowUser is idempotent. It fails with ErrFollowNotAllowed when either
+// user blocked the other or the followed user's profile is hidden.
+func FollowUser(followerID, followedID int) error {
+	blocked, err := IsBlockedBetween(followerID, followedID)
+	if err != nil {
+		return err
+	}
+	prefs, err := GetUserPreferences(followedID)
+	if err != nil {
+		return err
+	}
+	if blocked || prefs.ProfileVisibility == ProfileHidden {
+		return ErrFollowNotAllowed
+	}
+
+	query := `
+		INSERT INTO user_follows (follower_id, followed_id)
+		VALUES ($1, $2)
+		ON CONFLICT (follower_id, followed_id) DO NOTHING
+	`
+	if _, err := database.DB.Exec(query, followerID, followedID); err != nil {
+		return fmt.Errorf("failed to follow user: %w", err)
+	}
+	return nil
+}
+
+func UnfollowUser(followerID, followedID int) error {
+	query := `DELETE FROM user_follows WHERE follower_id = $1 AND followed_id = $2`
+	if _, err := database.DB.Exec(query, followerID, followedID); err != nil {
+		return fmt.Errorf("failed to unfollow user: %w", err)
+	}
+	return nil
+}
+
+func IsFollowing(followerID, followedID int) (bool, error) {
+	var following bool
+	query := `SELECT EXISTS (SELECT 1 FROM user_follows WHERE follower_id = $1 AND followed_id = $2)`
+	if err := database.DB.QueryRow(query, followerID, followedID).Scan(&following); err != nil {
+		return false, fmt.Errorf("failed to check follow: %w", err)
+	}
+	return following, nil
+}
+
+func GetFollowCounts(userID int) (*FollowCounts, error) {
+	counts := &FollowCounts{}
+	query := `
+		SELECT (SELECT COUNT(*) FROM user_follows WHERE followed_id = $1),
+		       (SELECT COUNT(*) FROM user_follows WHERE follower_id = $1)
+	`
+	if err := database.DB.QueryRow(query, userID).Scan(&counts.Followers, &counts.Following); err != nil {
+		return nil, fmt.Errorf("failed to count follows: %w", err)
+	}
+	return counts, nil
+}
+
+// ListFollowers returns the users following userID, newest first.
+func ListFollowers(userID, limit, offset int) ([]*FollowEntry, error) {
+	return listFollows(`
+		SELECT u.id, u.username, u.avatar_url, f.created_at
+		FROM user_follows f
+		JOIN users u ON u.id = f.follower_id
+		WHERE f.followed_id = $1
+		ORDER BY f.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+}
+
+// ListFollowing returns the users userID follows, newest first.
+func ListFollowing(userID, limit, offset int) ([]*FollowEntry, error) {
+	return listFollows(`
+		SELECT u.id, u.username, u.avatar_url, f.created_at
+		FROM user_follows f
+		JOIN users u ON u.id = f.followed_id
+		WHERE f.follower_id = $1
+		ORDER BY f.created_at DESC
+		LIMIT $2 OFFSET $3
+	`, userID, limit, offset)
+}
+
+func listFollows(query string, args ...any) ([]*FollowEntry, error) {
+	rows, err := database.DB.Query(query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list follows: %w", err)
+	}
+	defer rows.Close()
+
+	users := []*FollowEntry{}
+	for rows.Next() {
+		u := &FollowEntry{}
+		if err := rows.Scan(&u.ID, &u.Username, &u.AvatarURL, &u.FollowedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan user: %w", err)
+		}
+		users = append(users, u)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating follows: %w", err)
+	}
+
+	return users, nil
+}
+
+// NotifyFollowersOfPost tells the author's followers about a new post.
+// Nothing is sent while the author's profile is hidden, to followers who
+// blocked the author or were blocked by them, or about NSFW posts to
+// followers who don't show NSFW content. Muted types are skipped by Notify.
+func NotifyFollowersOfPost(post *Post, authorUsername string) error {
+	query := `
+		SELECT f.follower_id
+		FROM user_follows f
+		WHERE f.followed_id = $1
+		  AND $1 NOT IN (` + hiddenProfilesSQL + `)
+		  AND NOT EXISTS (
+			SELECT 1 FROM user_blocks b
+			WHERE (b.blocker_id = f.follower_id AND b.blocked_id = $1)
+			   OR (b.blocker_id = $1 AND b.blocked_id = f.follower_id)
+		  )
+		  AND (
+			f.follower_id IN (` + showsNSFWSQL + `)
+			OR NOT EXISTS (
+				SELECT 1 FROM posts p
+				JOIN subreddits s ON s.id = p.subreddit_id
+				WHERE p.id = $2 AND (p.is_nsfw OR s.is_nsfw)
+			)
+		  )
+	`
+	rows, err := database.DB.Query(query, post.AuthorID, post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to list followers to notify: %w", err)
+	}
+	defer rows.Close()
+
+	var followers []int
+	for rows.Next() {
+		var id int
+		if err := rows.Scan(&id); err != nil {
+			return fmt.Errorf("failed to scan follower: %w", err)
+		}
+		followers = append(followers, id)
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating followers: %w", err)
+	}
+	if len(followers) == 0 {
+		return nil
+	}
+
+	data, err := json.Marshal(map[string]any{"title": post.Title})
+	if err != nil {
+		return fmt.Errorf("failed to encode notification: %w", err)
+	}
+	_, err = notifyAll(&Notification{
+		Type:        NotificationFollowedPost,
+		ActorID:     &post.AuthorID,
+		PostID:      &post.ID,
+		SubredditID: &post.SubredditID,
+		Message:     "u/" + authorUsername + " posted: " + post.Title,
+		Data:        data,
+	}, followers)
+	return err
+}
@@ -0,0 +1,227 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+// Karma sources recorded in karma_ledger.source_type
+const (
+	KarmaSourcePost    = "post"
+	KarmaSourceComment = "comment"
+)
+
+var ErrInsufficientKarma = errors.New("not enough karma to post in this subreddit")
+
+type KarmaLedgerEntry struct {
+	ID          int64     `json:"id"`
+	SubredditID *int      `json:"subreddit_id"`
+	SourceType  string    `json:"source_type"`
+	SourceID    int       `json:"source_id"`
+	Delta       int       `json:"delta"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+type SubredditKarma struct {
+	SubredditID   int    `json:"subreddit_id"`
+	SubredditName string `json:"subreddit_name"`
+	PostKarma     int    `json:"post_karma"`
+	CommentKarma  int    `json:"comment_karma"`
+}
+
+// applyKarmaDelta records a karma change in the ledger and updates the
+// cached totals in the same transaction.
+func applyKarmaDelta(tx *sql.Tx, userID, subredditID int, sourceType string, sourceID, delta int, reason string) error {
+	if delta == 0 {
+		return nil
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO karma_ledger (user_id, subreddit_id, source_type, source_id, delta, reason)
+		VALUES ($1, $2, $3, $4, $5, $6)`,
+		userID, subredditID, sourceType, sourceID, delta, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record karma change: %w", err)
+	}
+
+	column := "post_karma"
+	if sourceType == KarmaSourceComment {
+		column = "comment_karma"
+	}
+
+	_, err = tx.Exec(`UPDATE users SET `+column+` = `+column+` + $1 WHERE id = $2`, delta, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update user karma: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_subreddit_karma (user_id, subreddit_id, `+column+`)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, subreddit_id)
+		DO UPDATE SET `+column+` = user_subreddit_karma.`+column+` + EXCLUDED.`+column,
+		userID, subredditID, delta)
+	if err != nil {
+		return fmt.Errorf("failed to update subreddit karma: %w", err)
+	}
+
+	return nil
+}
+
+// RecomputeUserKarma rebuilds a user's cached karma totals and per-subreddit
+// breakdown from the ledger.
+func RecomputeUserKarma(userID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(`
+		UPDATE users SET
+		    post_karma = (SELECT COALESCE(SUM(delta), 0) FROM karma_ledger WHERE user_id = $1 AND source_type = 'post'),
+		    comment_karma = (SELECT COALESCE(SUM(delta), 0) FROM karma_ledger WHERE user_id = $1 AND source_type = 'comment')
+		WHERE id = $1`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to recompute user karma: %w", err)
+	}
+
+	if _, err = tx.Exec(`DELETE FROM user_subreddit_karma WHERE user_id = $1`, userID); err != nil {
+		return fmt.Errorf("failed to clear subreddit karma: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO user_subreddit_karma (user_id, subreddit_id, post_karma, comment_karma)
+		SELECT user_id, subreddit_id,
+		       COALESCE(SUM(delta) FILTER (WHERE source_type = 'post'), 0),
+		       COALESCE(SUM(delta) FILTER (WHERE source_type = 'comment'), 0)
+		FROM karma_ledger
+		WHERE user_id = $1 AND subreddit_id IS NOT NULL
+		GROUP BY user_id, subreddit_id`, userID)
+	if err != nil {
+		return fmt.Errorf("failed to recompute subreddit karma: %w", err)
+	}
+
+	return tx.Commit()
+}
+
+// GetSubredditKarmaBreakdown returns a user's karma per subreddit, highest
+// first.
+func GetSubredditKarmaBreakdown(userID int) ([]*SubredditKarma, error) {
+	query := `
+		SELECT k.subreddit_id, s.name, k.post_karma, k.comment_karma
+		FROM user_subreddit_karma k
+		JOIN subreddits s ON s.id = k.subreddit_id
+		WHERE k.user_id = $1
+		ORDER BY k.post_karma + k.comment_karma DESC
+	`
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get karma breakdown: %w", err)
+	}
+	defer rows.Close()
+
+	breakdown := []*SubredditKarma{}
+	for rows.Next() {
+		k := &SubredditKarma{}
+		if err := rows.Scan(&k.SubredditID, &k.SubredditName, &k.PostKarma, &k.CommentKarma); err != nil {
+			return nil, fmt.Errorf("failed to scan karma: %w", err)
+		}
+		breakdown = append(breakdown, k)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating karma: %w", err)
+	}
+
+	return breakdown, nil
+}
+
+// GetUserSubredditKarma returns the total karma a user has earned in one
+// subreddit.
+func GetUserSubredditKarma(userID, subredditID int) (int, error) {
+	var karma int
+	query := `
+		SELECT COALESCE(SUM(post_karma + comment_karma), 0)
+		FROM user_subreddit_karma
+		WHERE user_id = $1 AND subreddit_id = $2
+	`
+	if err := database.DB.QueryRow(query, userID, subredditID).Scan(&karma); err != nil {
+		return 0, fmt.Errorf("failed to get subreddit karma: %w", err)
+	}
+	return karma, nil
+}
+
+// ListKarmaLedger returns a user's karma changes, newest first.
+func ListKarmaLedger(userID, limit, offset int) ([]*KarmaLedgerEntry, error) {
+	query := `
+		SELECT id, subreddit_id, source_type, source_id, delta, reason, created_at
+		FROM karma_ledger
+		WHERE user_id = $1
+		ORDER BY created_at DESC, id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := database.DB.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list karma ledger: %w", err)
+	}
+	defer rows.Close()
+
+	entries := []*KarmaLedgerEntry{}
+	for rows.Next() {
+		e := &KarmaLedgerEntry{}
+		if err := rows.Scan(&e.ID, &e.SubredditID, &e.SourceType, &e.SourceID, &e.Delta, &e.Reason, &e.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan karma entry: %w", err)
+		}
+		entries = append(entries, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating karma ledger: %w", err)
+	}
+
+	return entries, nil
+}
+
+// CheckPostingKarma returns ErrInsufficientKarma when the user is below one
+// of the subreddit's minimum-karma posting thresholds.
+func CheckPostingKarma(userID int, subreddit *Subreddit) error {
+	if subreddit.MinKarmaToPost <= 0 && subreddit.MinSubredditKarmaToPost <= 0 {
+		return nil
+	}
+
+	// Moderators are never locked out of their own community.
+	isMod, err := IsSubredditModerator(subreddit.ID, userID)
+	if err != nil {
+		return err
+	}
+	if isMod {
+		return nil
+	}
+
+	if subreddit.MinKarmaToPost > 0 {
+		user, err := GetUserByID(userID)
+		if err != nil {
+			return err
+		}
+		if user.PostKarma+user.CommentKarma < subreddit.MinKarmaToPost {
+			return ErrInsufficientKarma
+		}
+	}
+
+	if subreddit.MinSubredditKarmaToPost > 0 {
+		karma, err := GetUserSubredditKarma(userID, subreddit.ID)
+		if err != nil {
+			return err
+		}
+		if karma < subreddit.MinSubredditKarmaToPost {
+			return ErrInsufficientKarma
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,187 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+// Gallery and video post types
+const (
+	PostTypeGallery = "gallery"
+	PostTypeVideo   = "video"
+
+	MinGalleryItems = 2
+	MaxGalleryItems = 20
+)
+
+type GalleryItem struct {
+	Position    int     `json:"position"`
+	MediaURL    string  `json:"media_url"`
+	Caption     *string `json:"caption"`
+	OutboundURL *string `json:"outbound_url"`
+}
+
+type Video struct {
+	VideoURL        string `json:"video_url"`
+	DurationMS      int    `json:"duration_ms"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	ThumbnailURL    string `json:"thumbnail_url"`
+	ThumbnailSource string `json:"thumbnail_source"` // cover_art or placeholder
+	Thumbnail       []byte `json:"-"`                // PNG, only set while creating the post
+}
+
+func videoThumbnailURL(postID int) string {
+	return fmt.Sprintf("/api/posts/%d/video/thumbnail", postID)
+}
+
+// createPostMedia stores the gallery items or video of a post being created
+// in tx.
+func createPostMedia(tx *sql.Tx, post *Post) error {
+	for i, item := range post.Gallery {
+		item.Position = i
+		_, err := tx.Exec(`
+			INSERT INTO gallery_items (post_id, position, media_url, caption, outbound_url)
+			VALUES ($1, $2, $3, $4, $5)
+		`, post.ID, i, item.MediaURL, item.Caption, item.OutboundURL)
+		if err != nil {
+			return fmt.Errorf("failed to create gallery item: %w", err)
+		}
+	}
+
+	if v := post.Video; v != nil {
+		_, err := tx.Exec(`
+			INSERT INTO post_videos (post_id, video_url, duration_ms, width, height, thumbnail, thumbnail_source)
+			VALUES ($1, $2, $3, $4, $5, $6, $7)
+		`, post.ID, v.VideoURL, v.DurationMS, v.Width, v.Height, v.Thumbnail, v.ThumbnailSource)
+		if err != nil {
+			return fmt.Errorf("failed to create post video: %w", err)
+		}
+		v.ThumbnailURL = videoThumbnailURL(post.ID)
+	}
+	return nil
+}
+
+// loadPostMedia attaches gallery items and videos to the gallery and video
+// posts in posts.
+func loadPostMedia(posts []*Post) error {
+	byID := make(map[int]*Post)
+	var galleryIDs, videoIDs []int64
+	for _, p := range posts {
+		switch p.PostType {
+		case PostTypeGallery:
+			galleryIDs = append(galleryIDs, int64(p.ID))
+		case PostTypeVideo:
+			videoIDs = append(videoIDs, int64(p.ID))
+		default:
+			continue
+		}
+		byID[p.ID] = p
+	}
+
+	if len(galleryIDs) > 0 {
+		rows, err := database.DB.Query(`
+			SELECT post_id, position, media_url, caption, outbound_url
+			FROM gallery_items WHERE post_id = ANY($1)
+			ORDER BY post_id, position
+		`, pq.Array(galleryIDs))
+		if err != nil {
+			return fmt.Errorf("failed to load gallery items: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var postID int
+			item := &GalleryItem{}
+			if err := rows.Scan(&postID, &item.Position, &item.MediaURL, &item.Caption, &item.OutboundURL); err != nil {
+				return fmt.Errorf("failed to scan gallery item: %w", err)
+			}
+			byID[postID].Gallery = append(byID[postID].Gallery, item)
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating gallery items: %w", err)
+		}
+	}
+
+	if len(videoIDs) > 0 {
+		rows, err := database.DB.Query(`
+			SELECT post_id, video_url, duration_ms, width, height, thumbnail_source
+			FROM post_videos WHERE post_id = ANY($1)
+		`, pq.Array(videoIDs))
+		if err != nil {
+			return fmt.Errorf("failed to load post videos: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var postID int
+			v := &Video{}
+			if err := rows.Scan(&postID, &v.VideoURL, &v.DurationMS, &v.Width, &v.Height, &v.ThumbnailSource); err != nil {
+				return fmt.Errorf("failed to scan post video: %w", err)
+			}
+			v.ThumbnailURL = videoThumbnailURL(postID)
+			byID[postID].Video = v
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating post videos: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// GetVideoThumbnail returns the PNG thumbnail of a video post, or nil if
+// the post has no video.
+func GetVideoThumbnail(postID int) ([]byte, error) {
+	var thumbnail []byte
+	err := database.DB.QueryRow(`SELECT thumbnail FROM post_videos WHERE post_id = $1`, postID).Scan(&thumbnail)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get video thumbnail: %w", err)
+	}
+	return thumbnail, nil
+}
+
+// LoadPostDetails attaches the type-specific parts of posts (polls, gallery
+// items and videos), content warnings, award counts, the viewer's votes and
+// blur hints, including those of embedded crosspost originals. viewerID decides which
+// poll results are visible and whose preferences apply.
+func LoadPostDetails(posts []*Post, viewerID *int) error {
+	all := make([]*Post, 0, len(posts))
+	for _, p := range posts {
+		all = append(all, p)
+		if p.CrosspostParent != nil {
+			all = append(all, p.CrosspostParent)
+		}
+	}
+
+	if err := loadPolls(all, viewerID); err != nil {
+		return err
+	}
+	if err := loadPostMedia(all); err != nil {
+		return err
+	}
+	if err := loadContentWarnings(all); err != nil {
+		return err
+	}
+	if err := loadAwardCounts(all); err != nil {
+		return err
+	}
+	if err := loadViewerVotes(all, viewerID); err != nil {
+		return err
+	}
+
+	prefs := DefaultPreferences()
+	if viewerID != nil {
+		var err error
+		if prefs, err = GetUserPreferences(*viewerID); err != nil {
+			return err
+		}
+	}
+	return applyBlurHints(all, prefs)
+}
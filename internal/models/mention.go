@@ -0,0 +1,125 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+
+	"github.com/kshzz24/gosocial/internal/utils"
+	"github.com/lib/pq"
+)
+
+// MaxMentionNotifications caps how many users one post notifies by
+// mentioning them, edits included, so posts can't be used to page a crowd.
+// Later mentions are still stored and linked.
+const MaxMentionNotifications = 5
+
+// postReferences returns the u/ and r/ names in post's title and body.
+func postReferences(post *Post) utils.References {
+	html := utils.RenderMarkdown(post.Title)
+	if post.ContentHTML != nil {
+		html += "\n" + *post.ContentHTML
+	}
+	return utils.ExtractReferences(html)
+}
+
+// syncMentions records the existing users and subreddits post references,
+// marking the ones an edit removed as no longer current. It returns the
+// users to notify: current mentions that haven't been notified, in order
+// of appearance, up to what is left of MaxMentionNotifications. The author
+// and users in a block with the author are skipped.
+func syncMentions(tx *sql.Tx, post *Post) ([]int, error) {
+	refs := postReferences(post)
+
+	if _, err := tx.Exec(`UPDATE mentions SET is_current = FALSE WHERE post_id = $1`, post.ID); err != nil {
+		return nil, fmt.Errorf("failed to reset mentions: %w", err)
+	}
+
+	_, err := tx.Exec(`
+		INSERT INTO mentions (post_id, user_id)
+		SELECT $1, u.id
+		FROM unnest($2::text[]) WITH ORDINALITY AS m(name, pos)
+		JOIN users u ON u.username = m.name
+		WHERE u.id <> $3
+		ORDER BY m.pos
+		ON CONFLICT (post_id, user_id) WHERE user_id IS NOT NULL DO UPDATE SET is_current = TRUE
+	`, post.ID, pq.Array(refs.Users), post.AuthorID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record user mentions: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO mentions (post_id, subreddit_id)
+		SELECT $1, s.id FROM subreddits s WHERE s.name = ANY($2)
+		ON CONFLICT (post_id, subreddit_id) WHERE subreddit_id IS NOT NULL DO UPDATE SET is_current = TRUE
+	`, post.ID, pq.Array(refs.Subreddits))
+	if err != nil {
+		return nil, fmt.Errorf("failed to record subreddit references: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		UPDATE mentions SET notified_at = CURRENT_TIMESTAMP
+		WHERE id IN (
+			SELECT m.id FROM mentions m
+			WHERE m.post_id = $1 AND m.user_id IS NOT NULL AND m.is_current AND m.notified_at IS NULL
+			  AND NOT EXISTS (
+				SELECT 1 FROM user_blocks b
+				WHERE (b.blocker_id = m.user_id AND b.blocked_id = $2)
+				   OR (b.blocker_id = $2 AND b.blocked_id = m.user_id)
+			  )
+			ORDER BY m.id
+			LIMIT GREATEST($3 - (SELECT COUNT(*) FROM mentions WHERE post_id = $1 AND notified_at IS NOT NULL), 0)
+		)
+		RETURNING user_id
+	`, post.ID, post.AuthorID, MaxMentionNotifications)
+	if err != nil {
+		return nil, fmt.Errorf("failed to select mentions to notify: %w", err)
+	}
+	defer rows.Close()
+
+	var notify []int
+	for rows.Next() {
+		var userID int
+		if err := rows.Scan(&userID); err != nil {
+			return nil, fmt.Errorf("failed to scan mention: %w", err)
+		}
+		notify = append(notify, userID)
+	}
+
+	return notify, rows.Err()
+}
+
+// notifyMentions sends mention notifications for post to userIDs, as
+// returned by syncMentions. Failures are logged; the post is already saved.
+func notifyMentions(post *Post, userIDs []int) {
+	if len(userIDs) == 0 {
+		return
+	}
+	author, err := GetUserByID(post.AuthorID)
+	if err != nil || author == nil {
+		log.Printf("failed to notify mentions of post %d: %v", post.ID, err)
+		return
+	}
+
+	data, err := json.Marshal(map[string]any{"title": post.Title})
+	if err != nil {
+		log.Println(err)
+		return
+	}
+
+	for _, userID := range userIDs {
+		err := Notify(&Notification{
+			UserID:      userID,
+			Type:        NotificationMention,
+			ActorID:     &post.AuthorID,
+			PostID:      &post.ID,
+			SubredditID: &post.SubredditID,
+			Message:     "u/" + author.Username + " mentioned you: " + post.Title,
+			Data:        data,
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
@@ -0,0 +1,335 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+)
+
+// Accounts younger than NewAccountAge may send at most
+// NewAccountMessageLimit messages per hour.
+const (
+	NewAccountAge          = 7 * 24 * time.Hour
+	NewAccountMessageLimit = 5
+)
+
+var (
+	ErrMessagingBlocked   = errors.New("one of the users has blocked the other")
+	ErrMessageRateLimited = errors.New("new accounts can only send a few messages per hour")
+)
+
+type Message struct {
+	ID             int64     `json:"id"`
+	ConversationID int64     `json:"conversation_id"`
+	SenderID       *int      `json:"sender_id"` // nil if the sender deleted their account
+	Body           string    `json:"body"`
+	IsRead         bool      `json:"is_read"` // Read by the recipient
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type Conversation struct {
+	ID              int64       `json:"id"`
+	OtherUser       UserSummary `json:"other_user"`
+	LastMessage     *Message    `json:"last_message"`
+	UnreadCount     int         `json:"unread_count"`
+	OtherLastReadAt *time.Time  `json:"other_last_read_at"` // Read receipt
+	LastMessageAt   time.Time   `json:"last_message_at"`
+	CreatedAt       time.Time   `json:"created_at"`
+}
+
+// messageReadSQL is true when the recipient of message m has read it.
+const messageReadSQL = `COALESCE(m.created_at <= CASE WHEN m.sender_id = c.user_low_id
+		THEN c.user_high_last_read_at ELSE c.user_low_last_read_at END, FALSE)`
+
+// messageVisibleSQL excludes messages the user bound to $1 deleted.
+const messageVisibleSQL = `NOT EXISTS (
+		SELECT 1 FROM message_deletions d WHERE d.message_id = m.id AND d.user_id = $1)`
+
+// conversationSelect lists conversations from the point of view of the user
+// bound to $1. Conversations whose messages that user deleted are skipped.
+const conversationSelect = `
+	SELECT c.id, c.last_message_at, c.created_at,
+	       u.id, u.username, u.avatar_url,
+	       CASE WHEN c.user_low_id = $1 THEN c.user_high_last_read_at ELSE c.user_low_last_read_at END,
+	       (SELECT COUNT(*) FROM messages m
+	        WHERE m.conversation_id = c.id
+	          AND m.sender_id IS DISTINCT FROM $1
+	          AND m.created_at > COALESCE(CASE WHEN c.user_low_id = $1
+	              THEN c.user_low_last_read_at ELSE c.user_high_last_read_at END, '-infinity')
+	          AND ` + messageVisibleSQL + `),
+	       lm.id, lm.sender_id, lm.body, lm.is_read, lm.created_at
+	FROM conversations c
+	JOIN users u ON u.id = CASE WHEN c.user_low_id = $1 THEN c.user_high_id ELSE c.user_low_id END
+	JOIN LATERAL (
+		SELECT m.id, m.sender_id, m.body, ` + messageReadSQL + ` AS is_read, m.created_at
+		FROM messages m
+		WHERE m.conversation_id = c.id AND ` + messageVisibleSQL + `
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT 1
+	) lm ON TRUE
+	WHERE $1 IN (c.user_low_id, c.user_high_id)`
+
+func scanConversation(row rowScanner) (*Conversation, error) {
+	conv := &Conversation{LastMessage: &Message{}}
+	err := row.Scan(
+		&conv.ID,
+		&conv.LastMessageAt,
+		&conv.CreatedAt,
+		&conv.OtherUser.ID,
+		&conv.OtherUser.Username,
+		&conv.OtherUser.AvatarURL,
+		&conv.OtherLastReadAt,
+		&conv.UnreadCount,
+		&conv.LastMessage.ID,
+		&conv.LastMessage.SenderID,
+		&conv.LastMessage.Body,
+		&conv.LastMessage.IsRead,
+		&conv.LastMessage.CreatedAt,
+	)
+	if err != nil {
+		return nil, err
+	}
+	conv.LastMessage.ConversationID = conv.ID
+	return conv, nil
+}
+
+// ListConversations returns a user's conversations, most recently active
+// first.
+func ListConversations(userID, limit, offset int) ([]*Conversation, error) {
+	query := conversationSelect + `
+		ORDER BY c.last_message_at DESC, c.id DESC
+		LIMIT $2 OFFSET $3
+	`
+	rows, err := database.DB.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list conversations: %w", err)
+	}
+	defer rows.Close()
+
+	conversations := []*Conversation{}
+	for rows.Next() {
+		conv, err := scanConversation(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan conversation: %w", err)
+		}
+		conversations = append(conversations, conv)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating conversations: %w", err)
+	}
+
+	return conversations, nil
+}
+
+// GetConversation returns nil when the conversation does not exist, userID
+// is not a participant, or every message in it was deleted by userID.
+func GetConversation(id int64, userID int) (*Conversation, error) {
+	conv, err := scanConversation(database.DB.QueryRow(conversationSelect+` AND c.id = $2`, userID, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+	return conv, nil
+}
+
+// ListMessages returns the messages of a conversation visible to userID,
+// newest first.
+func ListMessages(conversationID int64, userID, limit, offset int) ([]*Message, error) {
+	query := `
+		SELECT m.id, m.conversation_id, m.sender_id, m.body, ` + messageReadSQL + `, m.created_at
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE m.conversation_id = $2
+		  AND $1 IN (c.user_low_id, c.user_high_id)
+		  AND ` + messageVisibleSQL + `
+		ORDER BY m.created_at DESC, m.id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := database.DB.Query(query, userID, conversationID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*Message{}
+	for rows.Next() {
+		m := &Message{}
+		if err := rows.Scan(&m.ID, &m.ConversationID, &m.SenderID, &m.Body, &m.IsRead, &m.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// SendDirectMessage appends a message to the conversation between sender
+// and recipient, starting one if needed.
+func SendDirectMessage(senderID, recipientID int, body string) (*Message, error) {
+	blocked, err := IsBlockedBetween(senderID, recipientID)
+	if err != nil {
+		return nil, err
+	}
+	if blocked {
+		return nil, ErrMessagingBlocked
+	}
+	if err := checkMessageRateLimit(senderID); err != nil {
+		return nil, err
+	}
+
+	low, high := senderID, recipientID
+	if low > high {
+		low, high = high, low
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	msg := &Message{SenderID: &senderID, Body: body}
+	err = tx.QueryRow(`
+		INSERT INTO conversations (user_low_id, user_high_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_low_id, user_high_id) DO UPDATE SET last_message_at = CURRENT_TIMESTAMP
+		RETURNING id
+	`, low, high).Scan(&msg.ConversationID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get conversation: %w", err)
+	}
+
+	err = tx.QueryRow(`
+		INSERT INTO messages (conversation_id, sender_id, body)
+		VALUES ($1, $2, $3)
+		RETURNING id, created_at
+	`, msg.ConversationID, senderID, body).Scan(&msg.ID, &msg.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to send message: %w", err)
+	}
+
+	// Sending implies the sender has read everything before it.
+	if _, _, err = markConversationRead(tx, msg.ConversationID, senderID, &msg.CreatedAt); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit message: %w", err)
+	}
+
+	realtime.Publish(realtime.UserTopic(recipientID), "message", msg)
+	realtime.Publish(realtime.UserTopic(senderID), "message", msg)
+
+	data, _ := json.Marshal(map[string]int64{"conversation_id": msg.ConversationID, "message_id": msg.ID})
+	err = Notify(&Notification{
+		UserID:  recipientID,
+		Type:    NotificationMessage,
+		ActorID: &senderID,
+		Message: "You have a new message",
+		Data:    data,
+	})
+	if err != nil {
+		log.Println(err)
+	}
+
+	return msg, nil
+}
+
+// MarkConversationRead records that userID has read everything in the
+// conversation and tells the other participant.
+func MarkConversationRead(conversationID int64, userID int) error {
+	otherID, readAt, err := markConversationRead(database.DB, conversationID, userID, nil)
+	if err != nil {
+		return err
+	}
+
+	realtime.Publish(realtime.UserTopic(otherID), "message_read", map[string]any{
+		"conversation_id": conversationID,
+		"read_at":         readAt,
+	})
+	return nil
+}
+
+// markConversationRead moves userID's read receipt forward to readAt, or to
+// now when readAt is nil. It returns the other participant and the time
+// used.
+func markConversationRead(db queryRower, conversationID int64, userID int, readAt *time.Time) (int, time.Time, error) {
+	var otherID int
+	var at time.Time
+	err := db.QueryRow(`
+		UPDATE conversations SET
+			user_low_last_read_at = CASE WHEN user_low_id = $2
+				THEN GREATEST(user_low_last_read_at, COALESCE($3::timestamp, LOCALTIMESTAMP))
+				ELSE user_low_last_read_at END,
+			user_high_last_read_at = CASE WHEN user_high_id = $2
+				THEN GREATEST(user_high_last_read_at, COALESCE($3::timestamp, LOCALTIMESTAMP))
+				ELSE user_high_last_read_at END
+		WHERE id = $1
+		RETURNING CASE WHEN user_low_id = $2 THEN user_high_id ELSE user_low_id END,
+		          COALESCE($3::timestamp, LOCALTIMESTAMP)
+	`, conversationID, userID, readAt).Scan(&otherID, &at)
+	if err != nil {
+		return 0, time.Time{}, fmt.Errorf("failed to mark conversation read: %w", err)
+	}
+	return otherID, at, nil
+}
+
+// DeleteMessageForUser hides a message from userID only. It returns false
+// when the message does not exist, is not in one of the user's
+// conversations, or was already deleted.
+func DeleteMessageForUser(messageID int64, userID int) (bool, error) {
+	query := `
+		INSERT INTO message_deletions (message_id, user_id)
+		SELECT m.id, $2
+		FROM messages m
+		JOIN conversations c ON c.id = m.conversation_id
+		WHERE m.id = $1 AND $2 IN (c.user_low_id, c.user_high_id)
+		ON CONFLICT (user_id, message_id) DO NOTHING
+	`
+	result, err := database.DB.Exec(query, messageID, userID)
+	if err != nil {
+		return false, fmt.Errorf("failed to delete message: %w", err)
+	}
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, fmt.Errorf("failed to delete message: %w", err)
+	}
+	return n > 0, nil
+}
+
+// checkMessageRateLimit limits how many messages new accounts can send,
+// counting both direct messages and modmail.
+func checkMessageRateLimit(userID int) error {
+	query := `
+		SELECT u.created_at > NOW() - $2 * INTERVAL '1 second',
+		       (SELECT COUNT(*) FROM messages
+		        WHERE sender_id = $1 AND created_at > NOW() - INTERVAL '1 hour') +
+		       (SELECT COUNT(*) FROM modmail_messages
+		        WHERE author_id = $1 AND NOT is_internal AND created_at > NOW() - INTERVAL '1 hour')
+		FROM users u
+		WHERE u.id = $1
+	`
+	var isNew bool
+	var sent int
+	err := database.DB.QueryRow(query, userID, int(NewAccountAge.Seconds())).Scan(&isNew, &sent)
+	if err != nil {
+		return fmt.Errorf("failed to check message rate limit: %w", err)
+	}
+	if isNew && sent >= NewAccountMessageLimit {
+		return ErrMessageRateLimited
+	}
+	return nil
+}
@@ -0,0 +1,153 @@
+package models
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+)
+
+// MaxStickiedPosts is how many posts a subreddit can pin at once.
+const MaxStickiedPosts = 2
+
+// Distinguish markers
+const (
+	DistinguishModerator = "moderator"
+	DistinguishAdmin     = "admin"
+)
+
+// SuggestedSorts are the comment sorts a moderator can suggest for a post.
+var SuggestedSorts = []string{"confidence", "top", "new", "controversial", "old", "qa"}
+
+var ErrStickyLimit = fmt.Errorf("a subreddit can have at most %d sticky posts", MaxStickiedPosts)
+
+var errUnknownPostField = errors.New("unknown moderation field")
+
+// IsSuggestedSort reports whether sort is a valid suggested comment sort.
+func IsSuggestedSort(sort string) bool {
+	for _, s := range SuggestedSorts {
+		if s == sort {
+			return true
+		}
+	}
+	return false
+}
+
+// IsAdmin reports whether userID is a site administrator.
+func IsAdmin(userID int) (bool, error) {
+	var isAdmin bool
+	err := database.DB.QueryRow(`SELECT COALESCE(is_admin, FALSE) FROM users WHERE id = $1`, userID).Scan(&isAdmin)
+	if err != nil {
+		return false, fmt.Errorf("failed to check admin: %w", err)
+	}
+	return isAdmin, nil
+}
+
+// setPostModerationField updates one of the moderator-controlled columns of
+// a post and announces the change on the post's topic.
+func setPostModerationField(post *Post, column string, value any) error {
+	switch column {
+	case "is_locked", "distinguished", "contest_mode", "suggested_sort":
+	default:
+		return errUnknownPostField
+	}
+
+	_, err := database.DB.Exec(`UPDATE posts SET `+column+` = $1, updated_at = CURRENT_TIMESTAMP WHERE id = $2`, value, post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to update post %s: %w", column, err)
+	}
+
+	realtime.Publish(realtime.PostTopic(post.ID), "post_moderated", map[string]any{column: value})
+	return nil
+}
+
+// SetPostLocked locks or unlocks a post.
+func SetPostLocked(post *Post, locked bool) error {
+	if err := setPostModerationField(post, "is_locked", locked); err != nil {
+		return err
+	}
+	post.IsLocked = locked
+	return nil
+}
+
+// SetPostDistinguished marks a post as moderator or admin speech, or clears
+// the marker when as is nil.
+func SetPostDistinguished(post *Post, as *string) error {
+	if err := setPostModerationField(post, "distinguished", as); err != nil {
+		return err
+	}
+	post.Distinguished = as
+	return nil
+}
+
+// SetPostContestMode turns contest mode on or off.
+func SetPostContestMode(post *Post, enabled bool) error {
+	if err := setPostModerationField(post, "contest_mode", enabled); err != nil {
+		return err
+	}
+	post.ContestMode = enabled
+	return nil
+}
+
+// SetPostSuggestedSort sets the default comment sort of a post, or clears it
+// when sort is nil.
+func SetPostSuggestedSort(post *Post, sort *string) error {
+	if err := setPostModerationField(post, "suggested_sort", sort); err != nil {
+		return err
+	}
+	post.SuggestedSort = sort
+	return nil
+}
+
+// StickyPost pins a post to the top of its subreddit. The subreddit row is
+// locked while counting so concurrent requests can't exceed the limit.
+func StickyPost(post *Post) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if _, err := tx.Exec(`SELECT id FROM subreddits WHERE id = $1 FOR UPDATE`, post.SubredditID); err != nil {
+		return fmt.Errorf("failed to lock subreddit: %w", err)
+	}
+
+	var stickied int
+	err = tx.QueryRow(`SELECT COUNT(*) FROM posts WHERE subreddit_id = $1 AND is_stickied AND id <> $2`,
+		post.SubredditID, post.ID).Scan(&stickied)
+	if err != nil {
+		return fmt.Errorf("failed to count sticky posts: %w", err)
+	}
+	if stickied >= MaxStickiedPosts {
+		return ErrStickyLimit
+	}
+
+	_, err = tx.Exec(`
+		UPDATE posts SET is_stickied = TRUE, stickied_at = COALESCE(stickied_at, CURRENT_TIMESTAMP)
+		WHERE id = $1
+	`, post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to sticky post: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit sticky: %w", err)
+	}
+
+	post.IsStickied = true
+	realtime.Publish(realtime.PostTopic(post.ID), "post_moderated", map[string]any{"is_stickied": true})
+	return nil
+}
+
+// UnstickyPost unpins a post.
+func UnstickyPost(post *Post) error {
+	_, err := database.DB.Exec(`UPDATE posts SET is_stickied = FALSE, stickied_at = NULL WHERE id = $1`, post.ID)
+	if err != nil {
+		return fmt.Errorf("failed to unsticky post: %w", err)
+	}
+
+	post.IsStickied = false
+	realtime.Publish(realtime.PostTopic(post.ID), "post_moderated", map[string]any{"is_stickied": false})
+	return nil
+}
@@ -0,0 +1,270 @@
+package models
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+)
+
+type ModmailThread struct {
+	ID             int64     `json:"id"`
+	SubredditID    int       `json:"subreddit_id"`
+	SubredditName  string    `json:"subreddit_name"`
+	AuthorID       *int      `json:"author_id"`
+	AuthorUsername *string   `json:"author_username"`
+	Subject        string    `json:"subject"`
+	IsArchived     bool      `json:"is_archived"`
+	LastMessageAt  time.Time `json:"last_message_at"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ModmailMessage struct {
+	ID             int64     `json:"id"`
+	ThreadID       int64     `json:"thread_id"`
+	AuthorID       *int      `json:"author_id"`
+	AuthorUsername *string   `json:"author_username"`
+	Body           string    `json:"body"`
+	IsInternal     bool      `json:"is_internal"`
+	CreatedAt      time.Time `json:"created_at"`
+}
+
+type ModmailFilter struct {
+	SubredditID *int
+	Archived    bool
+}
+
+const modmailThreadSelect = `
+	SELECT t.id, t.subreddit_id, s.name, t.author_id, u.username,
+	       t.subject, t.is_archived, t.last_message_at, t.created_at
+	FROM modmail_threads t
+	JOIN subreddits s ON s.id = t.subreddit_id
+	LEFT JOIN users u ON u.id = t.author_id`
+
+func scanModmailThread(row rowScanner) (*ModmailThread, error) {
+	t := &ModmailThread{}
+	err := row.Scan(&t.ID, &t.SubredditID, &t.SubredditName, &t.AuthorID, &t.AuthorUsername,
+		&t.Subject, &t.IsArchived, &t.LastMessageAt, &t.CreatedAt)
+	return t, err
+}
+
+// CreateModmailThread opens a conversation between authorID and the
+// moderators of a subreddit.
+func CreateModmailThread(subredditID, authorID int, subject, body string) (*ModmailThread, error) {
+	if err := checkMessageRateLimit(authorID); err != nil {
+		return nil, err
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var threadID int64
+	err = tx.QueryRow(`
+		INSERT INTO modmail_threads (subreddit_id, author_id, subject)
+		VALUES ($1, $2, $3)
+		RETURNING id
+	`, subredditID, authorID, subject).Scan(&threadID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create modmail thread: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO modmail_messages (thread_id, author_id, body)
+		VALUES ($1, $2, $3)
+	`, threadID, authorID, body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create modmail message: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit modmail thread: %w", err)
+	}
+
+	thread, err := GetModmailThread(threadID)
+	if err != nil {
+		return nil, err
+	}
+	notifyModmail(thread, authorID, false)
+	return thread, nil
+}
+
+// GetModmailThread returns nil when the thread does not exist.
+func GetModmailThread(id int64) (*ModmailThread, error) {
+	thread, err := scanModmailThread(database.DB.QueryRow(modmailThreadSelect+` WHERE t.id = $1`, id))
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get modmail thread: %w", err)
+	}
+	return thread, nil
+}
+
+// ListModmailThreads returns the threads userID can see: the shared inbox
+// of every subreddit they moderate plus the threads they started.
+func ListModmailThreads(userID int, filter ModmailFilter, limit, offset int) ([]*ModmailThread, error) {
+	q := &queryBuilder{}
+	user := q.arg(userID)
+	q.where("(t.subreddit_id IN (" + moderatedSubredditsSQL(user) + ") OR t.author_id = " + user + ")")
+	q.where("t.is_archived = " + q.arg(filter.Archived))
+	if filter.SubredditID != nil {
+		q.where("t.subreddit_id = " + q.arg(*filter.SubredditID))
+	}
+
+	query := modmailThreadSelect + q.whereClause() +
+		` ORDER BY t.last_message_at DESC, t.id DESC` +
+		` LIMIT ` + q.arg(limit) + ` OFFSET ` + q.arg(offset)
+
+	rows, err := database.DB.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modmail: %w", err)
+	}
+	defer rows.Close()
+
+	threads := []*ModmailThread{}
+	for rows.Next() {
+		t, err := scanModmailThread(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan modmail thread: %w", err)
+		}
+		threads = append(threads, t)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating modmail threads: %w", err)
+	}
+
+	return threads, nil
+}
+
+// ListModmailMessages returns a thread's messages, oldest first. Internal
+// notes are only included for moderators.
+func ListModmailMessages(threadID int64, includeInternal bool) ([]*ModmailMessage, error) {
+	query := `
+		SELECT m.id, m.thread_id, m.author_id, u.username, m.body, m.is_internal, m.created_at
+		FROM modmail_messages m
+		LEFT JOIN users u ON u.id = m.author_id
+		WHERE m.thread_id = $1 AND ($2 OR NOT m.is_internal)
+		ORDER BY m.created_at, m.id
+	`
+	rows, err := database.DB.Query(query, threadID, includeInternal)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list modmail messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := []*ModmailMessage{}
+	for rows.Next() {
+		m := &ModmailMessage{}
+		err := rows.Scan(&m.ID, &m.ThreadID, &m.AuthorID, &m.AuthorUsername, &m.Body, &m.IsInternal, &m.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan modmail message: %w", err)
+		}
+		messages = append(messages, m)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating modmail messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// AddModmailMessage replies to a thread. asModerator must reflect whether
+// authorID moderates the thread's subreddit; only moderators may write
+// internal notes. A reply from the user reopens an archived thread.
+func AddModmailMessage(thread *ModmailThread, authorID int, body string, internal, asModerator bool) (*ModmailMessage, error) {
+	if internal && !asModerator {
+		return nil, fmt.Errorf("only moderators can add internal notes")
+	}
+	if !asModerator {
+		if err := checkMessageRateLimit(authorID); err != nil {
+			return nil, err
+		}
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	m := &ModmailMessage{ThreadID: thread.ID, AuthorID: &authorID, Body: body, IsInternal: internal}
+	err = tx.QueryRow(`
+		INSERT INTO modmail_messages (thread_id, author_id, body, is_internal)
+		VALUES ($1, $2, $3, $4)
+		RETURNING id, created_at
+	`, thread.ID, authorID, body, internal).Scan(&m.ID, &m.CreatedAt)
+	if err != nil {
+		return nil, fmt.Errorf("failed to add modmail message: %w", err)
+	}
+
+	if !internal {
+		_, err = tx.Exec(`
+			UPDATE modmail_threads
+			SET last_message_at = $2, is_archived = is_archived AND $3
+			WHERE id = $1
+		`, thread.ID, m.CreatedAt, asModerator)
+		if err != nil {
+			return nil, fmt.Errorf("failed to update modmail thread: %w", err)
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit modmail message: %w", err)
+	}
+
+	if !internal {
+		notifyModmail(thread, authorID, asModerator)
+	}
+	return m, nil
+}
+
+func SetModmailArchived(threadID int64, archived bool) error {
+	query := `UPDATE modmail_threads SET is_archived = $2 WHERE id = $1`
+	if _, err := database.DB.Exec(query, threadID, archived); err != nil {
+		return fmt.Errorf("failed to archive modmail thread: %w", err)
+	}
+	return nil
+}
+
+// notifyModmail tells the other side of a thread about a new message:
+// the user when a moderator replied, otherwise every moderator.
+func notifyModmail(thread *ModmailThread, actorID int, fromModerator bool) {
+	var recipients []int
+	if fromModerator {
+		if thread.AuthorID != nil {
+			recipients = []int{*thread.AuthorID}
+		}
+	} else {
+		mods, err := ListSubredditModeratorIDs(thread.SubredditID)
+		if err != nil {
+			log.Println(err)
+			return
+		}
+		recipients = mods
+	}
+
+	data, _ := json.Marshal(map[string]int64{"modmail_thread_id": thread.ID})
+	for _, userID := range recipients {
+		realtime.Publish(realtime.UserTopic(userID), "modmail", thread)
+		err := Notify(&Notification{
+			UserID:      userID,
+			Type:        NotificationMessage,
+			ActorID:     &actorID,
+			SubredditID: &thread.SubredditID,
+			Message:     "New modmail in r/" + thread.SubredditName + ": " + thread.Subject,
+			Data:        data,
+		})
+		if err != nil {
+			log.Println(err)
+		}
+	}
+}
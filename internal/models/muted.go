@@ -0,0 +1,66 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+type MutedSubreddit struct {
+	ID          int       `json:"id"`
+	Name        string    `json:"name"`
+	DisplayName string    `json:"display_name"`
+	MutedAt     time.Time `json:"muted_at"`
+}
+
+// MuteSubreddit is idempotent.
+func MuteSubreddit(userID, subredditID int) error {
+	query := `
+		INSERT INTO muted_subreddits (user_id, subreddit_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, subreddit_id) DO NOTHING
+	`
+	if _, err := database.DB.Exec(query, userID, subredditID); err != nil {
+		return fmt.Errorf("failed to mute subreddit: %w", err)
+	}
+	return nil
+}
+
+func UnmuteSubreddit(userID, subredditID int) error {
+	query := `DELETE FROM muted_subreddits WHERE user_id = $1 AND subreddit_id = $2`
+	if _, err := database.DB.Exec(query, userID, subredditID); err != nil {
+		return fmt.Errorf("failed to unmute subreddit: %w", err)
+	}
+	return nil
+}
+
+func ListMutedSubreddits(userID int) ([]*MutedSubreddit, error) {
+	query := `
+		SELECT s.id, s.name, s.display_name, m.muted_at
+		FROM muted_subreddits m
+		JOIN subreddits s ON s.id = m.subreddit_id
+		WHERE m.user_id = $1
+		ORDER BY s.name
+	`
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list muted subreddits: %w", err)
+	}
+	defer rows.Close()
+
+	subreddits := []*MutedSubreddit{}
+	for rows.Next() {
+		s := &MutedSubreddit{}
+		if err := rows.Scan(&s.ID, &s.Name, &s.DisplayName, &s.MutedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan muted subreddit: %w", err)
+		}
+		subreddits = append(subreddits, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating muted subreddits: %w", err)
+	}
+
+	return subreddits, nil
+}
@@ -0,0 +1,218 @@
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+	"github.com/lib/pq"
+)
+
+// Notification types
+const (
+	NotificationCommentReply = "comment_reply"
+	NotificationPostReply    = "post_reply"
+	NotificationMention      = "mention"
+	NotificationModRemoval   = "mod_removal"
+	NotificationBan          = "ban"
+	NotificationMessage      = "message"
+	NotificationFollowedPost = "followed_post"
+	NotificationAward        = "award"
+)
+
+// NotificationTypes lists every type users can mute.
+var NotificationTypes = []string{
+	NotificationCommentReply,
+	NotificationPostReply,
+	NotificationMention,
+	NotificationModRemoval,
+	NotificationBan,
+	NotificationMessage,
+	NotificationFollowedPost,
+	NotificationAward,
+}
+
+func IsNotificationType(t string) bool {
+	for _, known := range NotificationTypes {
+		if t == known {
+			return true
+		}
+	}
+	return false
+}
+
+type Notification struct {
+	ID          int64           `json:"id"`
+	UserID      int             `json:"user_id"`
+	Type        string          `json:"type"`
+	ActorID     *int            `json:"actor_id"`
+	PostID      *int            `json:"post_id"`
+	SubredditID *int            `json:"subreddit_id"`
+	Message     string          `json:"message"`
+	Data        json.RawMessage `json:"data"` // JSONB
+	IsRead      bool            `json:"is_read"`
+	CreatedAt   time.Time       `json:"created_at"`
+}
+
+// Notify delivers a notification to n.UserID. Nothing is stored when the
+// recipient triggered the event themselves or has muted this type.
+func Notify(n *Notification) error {
+	delivered, err := notifyAll(n, []int{n.UserID})
+	if err != nil {
+		return err
+	}
+	if len(delivered) == 1 {
+		*n = *delivered[0]
+	}
+	return nil
+}
+
+// notifyAll delivers a copy of n to each recipient and returns the copies
+// stored. Every notification goes through here, so the actor and recipients
+// who muted the type are skipped in one place.
+func notifyAll(n *Notification, recipients []int) ([]*Notification, error) {
+	data := n.Data
+	if len(data) == 0 {
+		data = json.RawMessage(`{}`)
+	}
+
+	query := `
+		INSERT INTO notifications (user_id, type, actor_id, post_id, subreddit_id, message, data)
+		SELECT r.user_id, $2, $3, $4, $5, $6, $7
+		FROM unnest($1::int[]) AS r(user_id)
+		WHERE r.user_id IS DISTINCT FROM $3::int
+		  AND NOT EXISTS (
+			SELECT 1 FROM notification_preferences np
+			WHERE np.user_id = r.user_id AND np.type = $2 AND np.muted
+		  )
+		RETURNING id, user_id, is_read, created_at
+	`
+	rows, err := database.DB.Query(query, pq.Array(recipients), n.Type, n.ActorID, n.PostID, n.SubredditID, n.Message, data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create notification: %w", err)
+	}
+	defer rows.Close()
+
+	var delivered []*Notification
+	for rows.Next() {
+		d := *n
+		d.Data = data
+		if err := rows.Scan(&d.ID, &d.UserID, &d.IsRead, &d.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		delivered = append(delivered, &d)
+	}
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	for _, d := range delivered {
+		realtime.Publish(realtime.UserTopic(d.UserID), "notification", d)
+	}
+	return delivered, nil
+}
+
+// ListNotifications returns a user's notifications, newest first.
+func ListNotifications(userID int, unreadOnly bool, limit, offset int) ([]*Notification, error) {
+	query := `
+		SELECT id, user_id, type, actor_id, post_id, subreddit_id, message, data, is_read, created_at
+		FROM notifications
+		WHERE user_id = $1 AND (NOT $2 OR NOT is_read)
+		ORDER BY created_at DESC, id DESC
+		LIMIT $3 OFFSET $4
+	`
+	rows, err := database.DB.Query(query, userID, unreadOnly, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list notifications: %w", err)
+	}
+	defer rows.Close()
+
+	notifications := []*Notification{}
+	for rows.Next() {
+		n := &Notification{}
+		err := rows.Scan(&n.ID, &n.UserID, &n.Type, &n.ActorID, &n.PostID, &n.SubredditID,
+			&n.Message, &n.Data, &n.IsRead, &n.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan notification: %w", err)
+		}
+		notifications = append(notifications, n)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notifications: %w", err)
+	}
+
+	return notifications, nil
+}
+
+func CountUnreadNotifications(userID int) (int, error) {
+	var count int
+	query := `SELECT COUNT(*) FROM notifications WHERE user_id = $1 AND NOT is_read`
+	if err := database.DB.QueryRow(query, userID).Scan(&count); err != nil {
+		return 0, fmt.Errorf("failed to count notifications: %w", err)
+	}
+	return count, nil
+}
+
+// MarkNotificationsRead marks the given notifications read. IDs that belong
+// to other users are ignored.
+func MarkNotificationsRead(userID int, ids []int64) (int64, error) {
+	query := `UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND id = ANY($2) AND NOT is_read`
+	res, err := database.DB.Exec(query, userID, pq.Array(ids))
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+func MarkAllNotificationsRead(userID int) (int64, error) {
+	query := `UPDATE notifications SET is_read = TRUE WHERE user_id = $1 AND NOT is_read`
+	res, err := database.DB.Exec(query, userID)
+	if err != nil {
+		return 0, fmt.Errorf("failed to mark notifications read: %w", err)
+	}
+	return res.RowsAffected()
+}
+
+// GetNotificationPreferences returns the mute setting for every type.
+func GetNotificationPreferences(userID int) (map[string]bool, error) {
+	prefs := make(map[string]bool, len(NotificationTypes))
+	for _, t := range NotificationTypes {
+		prefs[t] = false
+	}
+
+	rows, err := database.DB.Query(`SELECT type, muted FROM notification_preferences WHERE user_id = $1`, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get notification preferences: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var t string
+		var muted bool
+		if err := rows.Scan(&t, &muted); err != nil {
+			return nil, fmt.Errorf("failed to scan notification preference: %w", err)
+		}
+		prefs[t] = muted
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating notification preferences: %w", err)
+	}
+
+	return prefs, nil
+}
+
+func SetNotificationPreference(userID int, notificationType string, muted bool) error {
+	query := `
+		INSERT INTO notification_preferences (user_id, type, muted)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, type) DO UPDATE SET muted = EXCLUDED.muted
+	`
+	if _, err := database.DB.Exec(query, userID, notificationType, muted); err != nil {
+		return fmt.Errorf("failed to set notification preference: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,244 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+	"github.com/lib/pq"
+)
+
+// Poll limits and result visibility settings
+const (
+	PostTypePoll = "poll"
+
+	MinPollOptions = 2
+	MaxPollOptions = 6
+
+	PollResultsAfterVote  = "after_vote"
+	PollResultsAfterClose = "after_close"
+)
+
+var (
+	ErrPollClosed        = errors.New("this poll is closed")
+	ErrPollAlreadyVoted  = errors.New("you have already voted in this poll")
+	ErrPollInvalidOption = errors.New("option does not belong to this poll")
+)
+
+type Poll struct {
+	ClosesAt          time.Time     `json:"closes_at"`
+	ResultsVisibility string        `json:"results_visibility"`
+	IsClosed          bool          `json:"is_closed"`
+	ResultsVisible    bool          `json:"results_visible"`
+	TotalVotes        *int          `json:"total_votes"`      // nil while results are hidden
+	ViewerOptionID    *int          `json:"viewer_option_id"` // Option the viewer voted for
+	Options           []*PollOption `json:"options"`
+}
+
+type PollOption struct {
+	ID        int    `json:"id"`
+	Position  int    `json:"position"`
+	Text      string `json:"text"`
+	VoteCount *int   `json:"vote_count"` // nil while results are hidden
+}
+
+func IsPollResultsVisibility(v string) bool {
+	return v == PollResultsAfterVote || v == PollResultsAfterClose
+}
+
+// createPoll stores post.Poll for a post being created in tx.
+func createPoll(tx *sql.Tx, post *Post) error {
+	poll := post.Poll
+	_, err := tx.Exec(`INSERT INTO polls (post_id, closes_at, results_visibility) VALUES ($1, $2, $3)`,
+		post.ID, poll.ClosesAt, poll.ResultsVisibility)
+	if err != nil {
+		return fmt.Errorf("failed to create poll: %w", err)
+	}
+
+	for i, opt := range poll.Options {
+		opt.Position = i
+		err := tx.QueryRow(`INSERT INTO poll_options (post_id, position, text) VALUES ($1, $2, $3) RETURNING id`,
+			post.ID, i, opt.Text).Scan(&opt.ID)
+		if err != nil {
+			return fmt.Errorf("failed to create poll option: %w", err)
+		}
+		zero := 0
+		opt.VoteCount = &zero
+	}
+
+	total := 0
+	poll.TotalVotes = &total
+	poll.ResultsVisible = true // The author can always see results
+	return nil
+}
+
+// loadPolls attaches polls to the poll posts in posts. Vote counts are only
+// filled in when the viewer may see them: once the poll has closed, once
+// the viewer voted (for after_vote polls), or for the poll's author.
+func loadPolls(posts []*Post, viewerID *int) error {
+	byID := make(map[int]*Post)
+	var ids []int64
+	for _, p := range posts {
+		if p.PostType == PostTypePoll {
+			byID[p.ID] = p
+			ids = append(ids, int64(p.ID))
+		}
+	}
+	if len(ids) == 0 {
+		return nil
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT post_id, closes_at, results_visibility, closes_at <= NOW()
+		FROM polls WHERE post_id = ANY($1)
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load polls: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID int
+		poll := &Poll{Options: []*PollOption{}}
+		if err := rows.Scan(&postID, &poll.ClosesAt, &poll.ResultsVisibility, &poll.IsClosed); err != nil {
+			return fmt.Errorf("failed to scan poll: %w", err)
+		}
+		byID[postID].Poll = poll
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating polls: %w", err)
+	}
+
+	if viewerID != nil {
+		rows, err := database.DB.Query(`SELECT post_id, option_id FROM poll_votes WHERE post_id = ANY($1) AND user_id = $2`,
+			pq.Array(ids), *viewerID)
+		if err != nil {
+			return fmt.Errorf("failed to load poll votes: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var postID, optionID int
+			if err := rows.Scan(&postID, &optionID); err != nil {
+				return fmt.Errorf("failed to scan poll vote: %w", err)
+			}
+			if p := byID[postID]; p.Poll != nil {
+				p.Poll.ViewerOptionID = &optionID
+			}
+		}
+		if err = rows.Err(); err != nil {
+			return fmt.Errorf("error iterating poll votes: %w", err)
+		}
+	}
+
+	for _, p := range byID {
+		if p.Poll == nil {
+			continue
+		}
+		poll := p.Poll
+		isAuthor := viewerID != nil && *viewerID == p.AuthorID
+		poll.ResultsVisible = poll.IsClosed || isAuthor ||
+			(poll.ResultsVisibility == PollResultsAfterVote && poll.ViewerOptionID != nil)
+		if poll.ResultsVisible {
+			total := 0
+			poll.TotalVotes = &total
+		}
+	}
+
+	optRows, err := database.DB.Query(`
+		SELECT post_id, id, position, text, vote_count
+		FROM poll_options WHERE post_id = ANY($1)
+		ORDER BY post_id, position
+	`, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load poll options: %w", err)
+	}
+	defer optRows.Close()
+
+	for optRows.Next() {
+		var postID, count int
+		opt := &PollOption{}
+		if err := optRows.Scan(&postID, &opt.ID, &opt.Position, &opt.Text, &count); err != nil {
+			return fmt.Errorf("failed to scan poll option: %w", err)
+		}
+		poll := byID[postID].Poll
+		if poll == nil {
+			continue
+		}
+		if poll.ResultsVisible {
+			opt.VoteCount = &count
+			*poll.TotalVotes += count
+		}
+		poll.Options = append(poll.Options, opt)
+	}
+
+	return optRows.Err()
+}
+
+// VotePoll records userID's vote. The poll_votes primary key guarantees a
+// single vote per user even under concurrent requests.
+func VotePoll(postID, userID, optionID int) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var closed, archived bool
+	err = tx.QueryRow(`
+		SELECT polls.closes_at <= NOW(), posts.is_archived
+		FROM polls JOIN posts ON posts.id = polls.post_id
+		WHERE polls.post_id = $1
+	`, postID).Scan(&closed, &archived)
+	if err != nil {
+		return fmt.Errorf("failed to get poll: %w", err)
+	}
+	if archived {
+		return ErrPostArchived
+	}
+	if closed {
+		return ErrPollClosed
+	}
+
+	var inserted bool
+	err = tx.QueryRow(`
+		WITH vote AS (
+			INSERT INTO poll_votes (post_id, user_id, option_id)
+			SELECT $1, $2, id FROM poll_options WHERE id = $3 AND post_id = $1
+			ON CONFLICT (post_id, user_id) DO NOTHING
+			RETURNING option_id
+		)
+		SELECT EXISTS (SELECT 1 FROM vote)
+	`, postID, userID, optionID).Scan(&inserted)
+	if err != nil {
+		return fmt.Errorf("failed to record poll vote: %w", err)
+	}
+	if !inserted {
+		var voted bool
+		err := tx.QueryRow(`SELECT EXISTS (SELECT 1 FROM poll_votes WHERE post_id = $1 AND user_id = $2)`,
+			postID, userID).Scan(&voted)
+		if err != nil {
+			return fmt.Errorf("failed to check poll vote: %w", err)
+		}
+		if voted {
+			return ErrPollAlreadyVoted
+		}
+		return ErrPollInvalidOption
+	}
+
+	_, err = tx.Exec(`UPDATE poll_options SET vote_count = vote_count + 1 WHERE id = $1`, optionID)
+	if err != nil {
+		return fmt.Errorf("failed to count poll vote: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit poll vote: %w", err)
+	}
+
+	// Counts depend on who is looking, so subscribers refetch the poll.
+	realtime.Publish(realtime.PostTopic(postID), "poll_vote", map[string]int{"post_id": postID})
+	return nil
+}
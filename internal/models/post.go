@@ -2,48 +2,183 @@ package models
 
 import (
 	"database/sql"
+	"encoding/json"
 	"fmt"
 	"log"
 	"time"
 
+	"github.com/kshzz24/gosocial/internal/analytics"
 	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+	"github.com/kshzz24/gosocial/internal/utils"
 )
 
 type Post struct {
-	ID           int       `json:"id"`
-	Title        string    `json:"title"`
-	Content      *string   `json:"content"`
-	PostType     string    `json:"post_type"`
-	LinkURL      *string   `json:"link_url"`
-	ImageURL     *string   `json:"image_url"`
-	AuthorID     int       `json:"author_id"`
-	SubredditID  int       `json:"subreddit_id"`
-	Upvotes      int       `json:"upvotes"`
-	Downvotes    int       `json:"downvotes"`
-	Score        int       `json:"score"`
-	CommentCount int       `json:"comment_count"`
-	IsLocked     bool      `json:"is_locked"`
-	IsNSFW       bool      `json:"is_nsfw"`
-	CreatedAt    time.Time `json:"created_at"`
-	UpdatedAt    time.Time `json:"updated_at"`
+	ID           int        `json:"id"`
+	Title        string     `json:"title"`
+	Content      *string    `json:"content"`
+	ContentHTML  *string    `json:"content_html"` // Rendered from Content on write
+	PostType     string     `json:"post_type"`
+	LinkURL      *string    `json:"link_url"`
+	ImageURL     *string    `json:"image_url"`
+	AuthorID     int        `json:"author_id"`
+	SubredditID  int        `json:"subreddit_id"`
+	Upvotes      int        `json:"upvotes"`
+	Downvotes    int        `json:"downvotes"`
+	Score        int        `json:"score"`
+	CommentCount int        `json:"comment_count"`
+	IsLocked     bool       `json:"is_locked"`
+	IsNSFW       bool       `json:"is_nsfw"`
+	IsSpoiler    bool       `json:"is_spoiler"`
+	CreatedAt    time.Time  `json:"created_at"`
+	UpdatedAt    time.Time  `json:"updated_at"`
+	EditedAt     *time.Time `json:"edited_at"` // Set when the title or body was edited
+
+	// Moderator-controlled, see moderation.go
+	IsStickied    bool    `json:"is_stickied"`
+	Distinguished *string `json:"distinguished"` // moderator or admin
+	ContestMode   bool    `json:"contest_mode"`
+	SuggestedSort *string `json:"suggested_sort"` // Default comment sort
+
+	IsArchived bool       `json:"is_archived"` // Read-only for votes and comments
+	ArchivedAt *time.Time `json:"archived_at"`
+
+	CrosspostParentID *int           `json:"crosspost_parent_id"`
+	CrosspostCount    int            `json:"crosspost_count"`
+	CrosspostParent   *Post          `json:"crosspost_parent,omitempty"` // Original, loaded for display
+	Poll              *Poll          `json:"poll,omitempty"`             // Type-specific parts, see LoadPostDetails
+	Gallery           []*GalleryItem `json:"gallery,omitempty"`
+	Video             *Video         `json:"video,omitempty"`
+
+	HasInlineSpoilers bool              `json:"has_inline_spoilers"` // Body contains >!spoilers!<
+	ContentWarnings   []*ContentWarning `json:"content_warnings"`
+	Awards            []*AwardCount     `json:"awards"`
+	Vote              int               `json:"vote"`           // Viewer's vote: 1, -1 or 0
+	Blur              *BlurHint         `json:"blur,omitempty"` // For the viewer, see LoadPostDetails
+}
+
+// MaxPostContentLength is the longest post body accepted, in bytes.
+const MaxPostContentLength = 40000
+
+// postColumns lists the posts columns in the order scanPost reads them.
+const postColumns = `id, title, content, content_html, post_type, link_url, image_url,
+		author_id, subreddit_id, upvotes, downvotes, score, comment_count,
+		is_locked, is_nsfw, is_spoiler, created_at, updated_at, edited_at,
+		is_stickied, distinguished, contest_mode, suggested_sort,
+		is_archived, archived_at,
+		crosspost_parent_id, crosspost_count`
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+// queryRower is satisfied by both *sql.DB and *sql.Tx.
+type queryRower interface {
+	QueryRow(query string, args ...any) *sql.Row
+}
+
+func postScanTargets(p *Post) []any {
+	return []any{
+		&p.ID,
+		&p.Title,
+		&p.Content,
+		&p.ContentHTML,
+		&p.PostType,
+		&p.LinkURL,
+		&p.ImageURL,
+		&p.AuthorID,
+		&p.SubredditID,
+		&p.Upvotes,
+		&p.Downvotes,
+		&p.Score,
+		&p.CommentCount,
+		&p.IsLocked,
+		&p.IsNSFW,
+		&p.IsSpoiler,
+		&p.CreatedAt,
+		&p.UpdatedAt,
+		&p.EditedAt,
+		&p.IsStickied,
+		&p.Distinguished,
+		&p.ContestMode,
+		&p.SuggestedSort,
+		&p.IsArchived,
+		&p.ArchivedAt,
+		&p.CrosspostParentID,
+		&p.CrosspostCount,
+	}
+}
+
+func scanPost(row rowScanner) (*Post, error) {
+	p := &Post{}
+	if err := row.Scan(postScanTargets(p)...); err != nil {
+		return nil, err
+	}
+	finishPostScan(p)
+	return p, nil
+}
+
+// finishPostScan fills in derived fields after a post row has been scanned.
+func finishPostScan(p *Post) {
+	// Rows written before rendering existed are rendered on read.
+	if p.ContentHTML == nil {
+		renderPostContent(p)
+	}
+	p.HasInlineSpoilers = hasInlineSpoilers(p.ContentHTML)
+}
+
+// renderPostContent caches the sanitized HTML rendering of the post body.
+func renderPostContent(post *Post) {
+	post.ContentHTML = nil
+	if post.Content != nil {
+		html := utils.RenderMarkdown(*post.Content)
+		post.ContentHTML = &html
+	}
+	post.HasInlineSpoilers = hasInlineSpoilers(post.ContentHTML)
 }
 
 // CreatePost creates a new post
 func CreatePost(post *Post) (*Post, error) {
-	// TODO: Implement
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	mentioned, err := insertPost(tx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit post: %w", err)
+	}
+	announcePost(post, mentioned)
+
+	return post, nil
+
+}
+
+// insertPost writes a post with its poll, media, content warnings and
+// mentions through tx. It returns the users to notify of mentions once tx
+// commits.
+func insertPost(tx *sql.Tx, post *Post) ([]int, error) {
+	renderPostContent(post)
+
 	query := `
 		INSERT INTO posts (
-			title, content, post_type, link_url, image_url,
-			author_id, subreddit_id, is_locked, is_nsfw
+			title, content, content_html, post_type, link_url, image_url,
+			author_id, subreddit_id, is_locked, is_nsfw, crosspost_parent_id, is_spoiler
 		)
-		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
 		RETURNING id, created_at, updated_at
 	`
 
-	err := database.DB.QueryRow(
+	err := tx.QueryRow(
 		query,
 		post.Title,
 		post.Content,
+		post.ContentHTML,
 		post.PostType,
 		post.LinkURL,
 		post.ImageURL,
@@ -51,47 +186,115 @@ func CreatePost(post *Post) (*Post, error) {
 		post.SubredditID,
 		post.IsLocked,
 		post.IsNSFW,
+		post.CrosspostParentID,
+		post.IsSpoiler,
 	).Scan(&post.ID, &post.CreatedAt, &post.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to create post: %w", err)
 	}
 
+	if post.Poll != nil {
+		if err := createPoll(tx, post); err != nil {
+			return nil, err
+		}
+	}
+	if err := createPostMedia(tx, post); err != nil {
+		return nil, err
+	}
+	if err := setPostContentWarnings(tx, post.ID, post.ContentWarnings); err != nil {
+		return nil, err
+	}
+	return syncMentions(tx, post)
+}
+
+// announcePost notifies mentioned users and subreddit viewers of a post
+// that has just been committed.
+func announcePost(post *Post, mentioned []int) {
+	notifyMentions(post, mentioned)
+
 	// Set default values that database assigned
 	post.Upvotes = 0
 	post.Downvotes = 0
 	post.Score = 0
 	post.CommentCount = 0
+	if post.ContentWarnings == nil {
+		post.ContentWarnings = []*ContentWarning{}
+	}
+
+	realtime.Publish(realtime.SubredditTopic(post.SubredditID), "post_created", postEventData(post))
+}
+
+// postEventData is the realtime payload for a post event. Bodies are left
+// out to keep events within the NOTIFY size limit; subscribers refetch the
+// post for them.
+func postEventData(post *Post) map[string]any {
+	return map[string]any{
+		"post_id":      post.ID,
+		"subreddit_id": post.SubredditID,
+		"author_id":    post.AuthorID,
+		"title":        post.Title,
+		"post_type":    post.PostType,
+		"updated_at":   post.UpdatedAt,
+		"edited_at":    post.EditedAt,
+	}
+}
+
+// SubmitPost publishes a post on behalf of its author: it enforces the
+// subreddit's posting requirements, creates the post and notifies the
+// author's followers. Everything that makes a post go live (the create
+// endpoint, crossposts, the scheduler) goes through here.
+func SubmitPost(post *Post, subreddit *Subreddit) (*Post, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	published, err := submitPost(tx, post, subreddit)
+	if err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit post: %w", err)
+	}
+	published()
 
 	return post, nil
+}
 
+// submitPost creates a post through tx, so callers can commit it together
+// with their own changes. The returned function announces the post and must
+// be called once tx has committed.
+func submitPost(tx *sql.Tx, post *Post, subreddit *Subreddit) (func(), error) {
+	if err := CheckPostingKarma(post.AuthorID, subreddit); err != nil {
+		return nil, err
+	}
+
+	mentioned, err := insertPost(tx, post)
+	if err != nil {
+		return nil, err
+	}
+
+	return func() {
+		announcePost(post, mentioned)
+		analytics.Default.Count(post.SubredditID, analytics.Posts)
+
+		if author, err := GetUserByID(post.AuthorID); err != nil {
+			log.Println(err)
+		} else if err := NotifyFollowersOfPost(post, author.Username); err != nil {
+			log.Println(err)
+		}
+	}, nil
 }
 
 // GetPostByID retrieves a post by ID
 func GetPostByID(id int) (*Post, error) {
 
-	query := `SELECT id, title, content, post_type, link_url, image_url,
-			author_id, subreddit_id, upvotes, downvotes, score, comment_count, is_locked, is_nsfw, created_at, updated_at FROM posts WHERE id = $1 `
-
-	post := &Post{}
-	err := database.DB.QueryRow(query, id).Scan(
-		&post.ID,
-		&post.Title,
-		&post.Content,
-		&post.PostType,
-		&post.LinkURL,
-		&post.ImageURL,
-		&post.AuthorID,
-		&post.SubredditID,
-		&post.Upvotes,
-		&post.Downvotes,
-		&post.Score,
-		&post.CommentCount,
-		&post.IsLocked,
-		&post.IsNSFW,
-		&post.CreatedAt,
-		&post.UpdatedAt,
-	)
+	query := `SELECT ` + postColumns + ` FROM posts WHERE id = $1 `
+
+	post, err := scanPost(database.DB.QueryRow(query, id))
 
 	if err == sql.ErrNoRows {
 		return nil, nil
@@ -101,30 +304,95 @@ func GetPostByID(id int) (*Post, error) {
 		return nil, fmt.Errorf("failed to get post: %w", err)
 	}
 
+	if err := loadCrosspostParents([]*Post{post}); err != nil {
+		return nil, err
+	}
+
 	return post, nil
 	// TODO: Implement
 }
 
+// PostFilter narrows ListPosts. ViewerID is the authenticated user, if any,
+// and applies their personal filters (see applyViewerFilters).
+type PostFilter struct {
+	SubredditID     *int
+	AuthorID        *int
+	ViewerID        *int
+	FollowedBy      *int // Only posts by users this user follows
+	MentionedUserID *int // Only posts currently mentioning this user
+	IncludeNSFW     bool // Include NSFW posts and posts in NSFW subreddits
+	// Content labels to leave out
+	ExcludeSpoilers bool
+	ExcludeWarnings []string
+	Sort            string // "top" (default) or "new"
+}
+
+// postOrderBy maps a listing sort to its ORDER BY clause.
+func postOrderBy(sort string) string {
+	if sort == "new" {
+		return "created_at DESC"
+	}
+	return "score DESC, created_at DESC"
+}
+
+// applyViewerFilters removes posts a viewer has opted out of from a posts
+// query: hidden posts and posts by users they blocked everywhere, and posts
+// from muted subreddits in aggregate feeds that span subreddits. Every post
+// listing should go through here rather than filtering in handlers.
+func applyViewerFilters(q *queryBuilder, viewerID *int, aggregate bool) {
+	if viewerID == nil {
+		return
+	}
+	viewer := q.arg(*viewerID)
+	q.where("id NOT IN (SELECT post_id FROM hidden_posts WHERE user_id = " + viewer + ")")
+	q.where("author_id NOT IN (SELECT blocked_id FROM user_blocks WHERE blocker_id = " + viewer + ")")
+	if aggregate {
+		q.where("subreddit_id NOT IN (SELECT subreddit_id FROM muted_subreddits WHERE user_id = " + viewer + ")")
+	}
+}
+
+// applyNSFWFilter excludes NSFW posts and posts in NSFW subreddits unless
+// include is set.
+func applyNSFWFilter(q *queryBuilder, include bool) {
+	if !include {
+		q.where("NOT is_nsfw")
+		q.where("subreddit_id NOT IN (SELECT id FROM subreddits WHERE is_nsfw)")
+	}
+}
+
 // ListPosts retrieves posts with pagination and optional filters
-func ListPosts(limit, offset int, subredditID *int) ([]*Post, error) {
-	query := `
-		SELECT id, title, content, post_type, link_url, image_url,
-		       author_id, subreddit_id, upvotes, downvotes, score,
-		       comment_count, is_locked, is_nsfw, created_at, updated_at
-		FROM posts
-	`
+func ListPosts(limit, offset int, filter PostFilter) ([]*Post, error) {
+	q := &queryBuilder{}
+
+	if filter.SubredditID != nil {
+		q.where("subreddit_id = " + q.arg(*filter.SubredditID))
+	}
+	if filter.AuthorID != nil {
+		q.where("author_id = " + q.arg(*filter.AuthorID))
+	}
+	if filter.FollowedBy != nil {
+		q.where("author_id IN (SELECT followed_id FROM user_follows WHERE follower_id = " + q.arg(*filter.FollowedBy) + ")")
+		q.where("author_id NOT IN (" + hiddenProfilesSQL + ")")
+	}
+	if filter.MentionedUserID != nil {
+		q.where("id IN (SELECT post_id FROM mentions WHERE user_id = " + q.arg(*filter.MentionedUserID) + " AND is_current)")
+	}
+	applyNSFWFilter(q, filter.IncludeNSFW)
+	applyContentFilters(q, filter.ExcludeSpoilers, filter.ExcludeWarnings)
 
-	var rows *sql.Rows
-	var err error
+	aggregate := filter.SubredditID == nil && filter.AuthorID == nil
+	applyViewerFilters(q, filter.ViewerID, aggregate)
 
-	if subredditID != nil {
-		query += `WHERE subreddit_id = $1 ORDER BY score DESC LIMIT $2 OFFSET $3`
-		rows, err = database.DB.Query(query, *subredditID, limit, offset)
-	} else {
-		query += `ORDER BY score DESC LIMIT $1 OFFSET $2`
-		rows, err = database.DB.Query(query, limit, offset)
+	orderBy := postOrderBy(filter.Sort)
+	if filter.SubredditID != nil {
+		// Sticky posts lead the subreddit's own listing, oldest sticky first.
+		orderBy = "is_stickied DESC, stickied_at ASC, " + orderBy
 	}
 
+	query := `SELECT ` + postColumns + ` FROM posts` + q.whereClause() +
+		` ORDER BY ` + orderBy + ` LIMIT ` + q.arg(limit) + ` OFFSET ` + q.arg(offset)
+
+	rows, err := database.DB.Query(query, q.args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list posts: %w", err)
 	}
@@ -133,26 +401,7 @@ func ListPosts(limit, offset int, subredditID *int) ([]*Post, error) {
 	posts := []*Post{}
 
 	for rows.Next() {
-		p := &Post{}
-		err := rows.Scan(
-			&p.ID,
-			&p.Title,
-			&p.Content,
-			&p.PostType,
-			&p.LinkURL,
-			&p.ImageURL,
-			&p.AuthorID,
-			&p.SubredditID,
-			&p.Upvotes,
-			&p.Downvotes,
-			&p.Score,
-			&p.CommentCount,
-			&p.IsLocked,
-			&p.IsNSFW,
-			&p.CreatedAt,
-			&p.UpdatedAt,
-		)
-
+		p, err := scanPost(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan post: %w", err)
 		}
@@ -164,50 +413,124 @@ func ListPosts(limit, offset int, subredditID *int) ([]*Post, error) {
 		return nil, fmt.Errorf("error iterating posts: %w", err)
 	}
 
+	if err := loadCrosspostParents(posts); err != nil {
+		return nil, err
+	}
+	if err := LoadPostDetails(posts, filter.ViewerID); err != nil {
+		return nil, err
+	}
+
 	return posts, nil
 }
 
-// UpdatePost updates a post
-func UpdatePost(post *Post) error {
-	// TODO: Implement
+// UpdatePost updates a post. When the title or body changes, the previous
+// version is kept in post_revisions and edited_at is set.
+func UpdatePost(post *Post, editorID int) error {
+	renderPostContent(post)
 
-	query := `UPDATE posts SET title=$1, content=$2, post_type=$3, link_url=$4, image_url=$5,  is_locked=$6, is_nsfw=$7,updated_at = CURRENT_TIMESTAMP where id=$8`
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
 
-	rows, err := database.DB.Exec(query, post.Title, post.Content, post.PostType, post.LinkURL, post.ImageURL, post.IsLocked, post.IsNSFW, post.ID)
-	// defer rows.Close()
+	current, err := scanPost(tx.QueryRow(`SELECT `+postColumns+` FROM posts WHERE id = $1 FOR UPDATE`, post.ID))
 	if err != nil {
 		return fmt.Errorf("failed to get post: %w", err)
 	}
 
-	log.Println(rows.RowsAffected())
+	edited := current.Title != post.Title || stringValue(current.Content) != stringValue(post.Content)
+	if edited {
+		if err := recordRevision(tx, current, editorID, RevisionEdit); err != nil {
+			return err
+		}
+	}
+
+	// Only the author-owned columns are written; locks and labels may have
+	// been changed by a moderator since the caller loaded the post.
+	updated, err := scanPost(tx.QueryRow(`UPDATE posts SET title=$1, content=$2, content_html=$3,
+		updated_at = CURRENT_TIMESTAMP,
+		edited_at = CASE WHEN $4 THEN CURRENT_TIMESTAMP ELSE edited_at END
+		WHERE id=$5 RETURNING `+postColumns,
+		post.Title, post.Content, post.ContentHTML, edited, post.ID))
+	if err != nil {
+		return fmt.Errorf("failed to update post: %w", err)
+	}
+	*post = *updated
+
+	var mentioned []int
+	if edited {
+		if mentioned, err = syncMentions(tx, post); err != nil {
+			return err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit post update: %w", err)
+	}
+	notifyMentions(post, mentioned)
+
+	realtime.Publish(realtime.PostTopic(post.ID), "post_updated", postEventData(post))
 	return nil
 }
 
-// DeletePost deletes a post
-func DeletePost(id int) error {
-	// TODO: Implement
-	query := `DELETE FROM posts where id=$1`
-	rows, err := database.DB.Exec(query, id)
-	// defer rows.Close()
+// DeletePost deletes a post. Its final version is kept as a revision so
+// moderators can still review it; reason is RevisionDeleted or
+// RevisionRemoved. The author is notified of removals.
+func DeletePost(post *Post, deletedBy int, reason string) error {
+	tx, err := database.DB.Begin()
 	if err != nil {
-		return fmt.Errorf("failed to get post: %w", err)
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	if err := recordRevision(tx, post, deletedBy, reason); err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(`DELETE FROM posts where id=$1`, post.ID); err != nil {
+		return fmt.Errorf("failed to delete post: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit post deletion: %w", err)
 	}
-	log.Println(rows.RowsAffected())
-	return nil
 
+	if reason == RevisionRemoved {
+		if err := notifyPostRemoved(post, deletedBy); err != nil {
+			log.Println(err)
+		}
+	}
+	return nil
 }
 
-// UpdatePostScore updates upvotes/downvotes/score
-func UpdatePostScore(id, upvotes, downvotes int) error {
-	// TODO: Implement
-	query := `UPDATE posts SET upvotes=$1, downvotes=$2, score=$3 where id=$4`
-	score := upvotes - downvotes
-	rows, err := database.DB.Exec(query, upvotes, downvotes, score, id)
-	// defer rows.Close()
+// notifyPostRemoved tells the author a moderator removed their post. The
+// post is gone, so it is identified in the data rather than by post_id,
+// which would cascade the notification away with it.
+func notifyPostRemoved(post *Post, moderatorID int) error {
+	subreddit, err := GetSubredditByID(post.SubredditID)
 	if err != nil {
-		return fmt.Errorf("failed to get post: %w", err)
+		return err
+	}
+	name := "a subreddit"
+	if subreddit != nil {
+		name = "r/" + subreddit.Name
 	}
 
-	log.Println(rows.RowsAffected())
-	return nil
+	data, _ := json.Marshal(map[string]any{"post_id": post.ID, "title": post.Title})
+	return Notify(&Notification{
+		UserID:      post.AuthorID,
+		Type:        NotificationModRemoval,
+		ActorID:     &moderatorID,
+		SubredditID: &post.SubredditID,
+		Message:     "Your post was removed by the moderators of " + name + ": " + post.Title,
+		Data:        data,
+	})
+}
+
+func stringValue(s *string) string {
+	if s == nil {
+		return ""
+	}
+	return *s
 }
@@ -0,0 +1,201 @@
+package models
+
+import (
+	"bytes"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+// PreferencesSchemaVersion is the version of the Preferences document
+// written by this code.
+//
+// Adding a key only needs a new field and a default in DefaultPreferences:
+// stored documents are decoded over the defaults, so missing keys pick them
+// up. Renaming or reinterpreting a key also needs a bump here and an entry
+// in preferenceMigrations.
+const PreferencesSchemaVersion = 1
+
+// preferenceMigrations[i] upgrades a stored document from version i+1 to
+// i+2.
+var preferenceMigrations = []func(settings map[string]json.RawMessage){}
+
+const (
+	FeedSortTop = "top"
+	FeedSortNew = "new"
+
+	ProfilePublic = "public"
+	ProfileHidden = "hidden" // Posts and activity only visible to the owner
+)
+
+type Preferences struct {
+	ShowNSFW          bool     `json:"show_nsfw"`          // Include NSFW posts and subreddits in listings
+	BlurNSFW          bool     `json:"blur_nsfw"`          // Blur NSFW thumbnails when they are shown
+	DefaultFeedSort   string   `json:"default_feed_sort"`  // FeedSortTop or FeedSortNew
+	EmailOptOut       []string `json:"email_opt_out"`      // Notification types not to email about
+	Language          string   `json:"language"`           // BCP 47 tag, e.g. "en" or "pt-BR"
+	Timezone          string   `json:"timezone"`           // IANA zone, e.g. "Europe/Berlin"
+	ProfileVisibility string   `json:"profile_visibility"` // ProfilePublic or ProfileHidden
+	// Content labels shown without blurring: spoiler and content warning
+	// slugs. NSFW blurring follows BlurNSFW.
+	AutoRevealWarnings []string `json:"auto_reveal_warnings"`
+}
+
+func DefaultPreferences() *Preferences {
+	return &Preferences{
+		ShowNSFW:           false,
+		BlurNSFW:           true,
+		DefaultFeedSort:    FeedSortTop,
+		EmailOptOut:        []string{},
+		Language:           "en",
+		Timezone:           "UTC",
+		ProfileVisibility:  ProfilePublic,
+		AutoRevealWarnings: []string{},
+	}
+}
+
+var languageTag = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+
+// IsLanguageTag reports whether tag looks like a BCP 47 language tag such
+// as en or pt-BR, up to 35 characters.
+func IsLanguageTag(tag string) bool {
+	return len(tag) <= 35 && languageTag.MatchString(tag)
+}
+
+// Validate checks every field holds a supported value.
+func (p *Preferences) Validate() error {
+	if p.DefaultFeedSort != FeedSortTop && p.DefaultFeedSort != FeedSortNew {
+		return fmt.Errorf("default_feed_sort must be one of: %s, %s", FeedSortTop, FeedSortNew)
+	}
+	if p.ProfileVisibility != ProfilePublic && p.ProfileVisibility != ProfileHidden {
+		return fmt.Errorf("profile_visibility must be one of: %s, %s", ProfilePublic, ProfileHidden)
+	}
+	if !IsLanguageTag(p.Language) {
+		return fmt.Errorf("language must be a language tag such as en or pt-BR")
+	}
+	if _, err := time.LoadLocation(p.Timezone); err != nil || p.Timezone == "" || p.Timezone == "Local" {
+		return fmt.Errorf("unknown timezone: %s", p.Timezone)
+	}
+	for _, t := range p.EmailOptOut {
+		if !IsNotificationType(t) {
+			return fmt.Errorf("unknown notification type in email_opt_out: %s", t)
+		}
+	}
+	for _, slug := range p.AutoRevealWarnings {
+		if slug != ContentLabelSpoiler && !IsContentWarningSlug(slug) {
+			return fmt.Errorf("invalid content warning in auto_reveal_warnings: %s", slug)
+		}
+	}
+	return nil
+}
+
+// GetUserPreferences returns the user's preferences, filling unset keys with
+// defaults and upgrading documents written by older schema versions.
+func GetUserPreferences(userID int) (*Preferences, error) {
+	var version int
+	var raw []byte
+	err := database.DB.QueryRow(`SELECT schema_version, settings FROM user_preferences WHERE user_id = $1`, userID).
+		Scan(&version, &raw)
+	if err == sql.ErrNoRows {
+		return DefaultPreferences(), nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get preferences: %w", err)
+	}
+
+	settings := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &settings); err != nil {
+		return nil, fmt.Errorf("failed to decode preferences: %w", err)
+	}
+	for v := version; v < PreferencesSchemaVersion && v-1 < len(preferenceMigrations); v++ {
+		preferenceMigrations[v-1](settings)
+	}
+
+	upgraded, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode preferences: %w", err)
+	}
+	prefs := DefaultPreferences()
+	if err := json.Unmarshal(upgraded, prefs); err != nil {
+		return nil, fmt.Errorf("failed to decode preferences: %w", err)
+	}
+	if prefs.EmailOptOut == nil {
+		prefs.EmailOptOut = []string{}
+	}
+	if prefs.AutoRevealWarnings == nil {
+		prefs.AutoRevealWarnings = []string{}
+	}
+	return prefs, nil
+}
+
+// SaveUserPreferences stores the keys of prefs that differ from
+// DefaultPreferences at the current schema version, so the others keep
+// following the defaults. Callers should Validate first.
+func SaveUserPreferences(userID int, prefs *Preferences) error {
+	changed, err := changedPreferences(prefs)
+	if err != nil {
+		return err
+	}
+	settings, err := json.Marshal(changed)
+	if err != nil {
+		return fmt.Errorf("failed to encode preferences: %w", err)
+	}
+
+	query := `
+		INSERT INTO user_preferences (user_id, schema_version, settings, updated_at)
+		VALUES ($1, $2, $3, CURRENT_TIMESTAMP)
+		ON CONFLICT (user_id) DO UPDATE
+		SET schema_version = EXCLUDED.schema_version,
+		    settings = EXCLUDED.settings,
+		    updated_at = EXCLUDED.updated_at
+	`
+	if _, err := database.DB.Exec(query, userID, PreferencesSchemaVersion, settings); err != nil {
+		return fmt.Errorf("failed to save preferences: %w", err)
+	}
+	return nil
+}
+
+// changedPreferences returns the keys of prefs whose values differ from
+// DefaultPreferences. Nil lists count as empty.
+func changedPreferences(prefs *Preferences) (map[string]json.RawMessage, error) {
+	normalized := *prefs
+	if normalized.EmailOptOut == nil {
+		normalized.EmailOptOut = []string{}
+	}
+	if normalized.AutoRevealWarnings == nil {
+		normalized.AutoRevealWarnings = []string{}
+	}
+
+	current, err := preferenceKeys(&normalized)
+	if err != nil {
+		return nil, err
+	}
+	defaults, err := preferenceKeys(DefaultPreferences())
+	if err != nil {
+		return nil, err
+	}
+
+	for key, value := range current {
+		if bytes.Equal(value, defaults[key]) {
+			delete(current, key)
+		}
+	}
+	return current, nil
+}
+
+// preferenceKeys returns the JSON encoding of each key of prefs.
+func preferenceKeys(prefs *Preferences) (map[string]json.RawMessage, error) {
+	raw, err := json.Marshal(prefs)
+	if err != nil {
+		return nil, fmt.Errorf("failed to encode preferences: %w", err)
+	}
+	keys := map[string]json.RawMessage{}
+	if err := json.Unmarshal(raw, &keys); err != nil {
+		return nil, fmt.Errorf("failed to encode preferences: %w", err)
+	}
+	return keys, nil
+}
@@ -0,0 +1,71 @@
+package models
+
+import (
+	"encoding/json"
+	"reflect"
+	"testing"
+)
+
+func TestChangedPreferences(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(p *Preferences)
+		want   map[string]string
+	}{
+		{"defaults", func(p *Preferences) {}, map[string]string{}},
+		{"nil slices match empty defaults", func(p *Preferences) {
+			p.EmailOptOut = nil
+			p.AutoRevealWarnings = nil
+		}, map[string]string{}},
+		{"changed keys only", func(p *Preferences) {
+			p.ShowNSFW = true
+			p.Timezone = "Europe/Berlin"
+			p.EmailOptOut = []string{NotificationMessage}
+		}, map[string]string{
+			"show_nsfw":     "true",
+			"timezone":      `"Europe/Berlin"`,
+			"email_opt_out": `["message"]`,
+		}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			prefs := DefaultPreferences()
+			tt.change(prefs)
+			changed, err := changedPreferences(prefs)
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := map[string]string{}
+			for key, value := range changed {
+				got[key] = string(value)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("changedPreferences() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+// TestChangedPreferencesRoundTrip checks that decoding the stored keys over
+// the defaults gives back the saved preferences.
+func TestChangedPreferencesRoundTrip(t *testing.T) {
+	prefs := DefaultPreferences()
+	prefs.DefaultFeedSort = FeedSortNew
+	prefs.AutoRevealWarnings = []string{ContentLabelSpoiler}
+
+	changed, err := changedPreferences(prefs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	raw, err := json.Marshal(changed)
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := DefaultPreferences()
+	if err := json.Unmarshal(raw, got); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(got, prefs) {
+		t.Errorf("round trip = %+v, want %+v", got, prefs)
+	}
+}
@@ -0,0 +1,30 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+)
+
+// queryBuilder accumulates WHERE conditions and their positional arguments
+// for queries whose filters are optional.
+type queryBuilder struct {
+	conditions []string
+	args       []any
+}
+
+// arg records v as the next positional argument and returns its placeholder.
+func (q *queryBuilder) arg(v any) string {
+	q.args = append(q.args, v)
+	return fmt.Sprintf("$%d", len(q.args))
+}
+
+func (q *queryBuilder) where(cond string) {
+	q.conditions = append(q.conditions, cond)
+}
+
+func (q *queryBuilder) whereClause() string {
+	if len(q.conditions) == 0 {
+		return ""
+	}
+	return " WHERE " + strings.Join(q.conditions, " AND ")
+}
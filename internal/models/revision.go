@@ -0,0 +1,127 @@
+package models
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/utils"
+)
+
+// Revision reasons
+const (
+	RevisionEdit    = "edit"
+	RevisionDeleted = "deleted" // Deleted by the author
+	RevisionRemoved = "removed" // Deleted by a moderator
+)
+
+// PostRevision is one version of a post, stored when it was replaced.
+type PostRevision struct {
+	ID          int       `json:"id"`
+	PostID      int       `json:"post_id"`
+	SubredditID int       `json:"subreddit_id"`
+	AuthorID    *int      `json:"author_id"`
+	EditorID    *int      `json:"editor_id"`
+	Title       string    `json:"title"`
+	Content     *string   `json:"content"`
+	Reason      string    `json:"reason"`
+	CreatedAt   time.Time `json:"created_at"`
+}
+
+// PostVersion is an entry in a post's history: version Number as it read
+// until ReplacedAt, with the diff to the version that replaced it.
+type PostVersion struct {
+	Number     int        `json:"number"`
+	Title      string     `json:"title"`
+	Content    *string    `json:"content"`
+	ReplacedAt *time.Time `json:"replaced_at"` // nil for the current version
+	ReplacedBy *int       `json:"replaced_by"` // Editor who made the next version
+	Reason     *string    `json:"reason"`      // Why it was replaced
+	Diff       string     `json:"diff"`        // Unified diff to the next version
+}
+
+// recordRevision stores the current title and body of post as a revision.
+func recordRevision(tx *sql.Tx, post *Post, editorID int, reason string) error {
+	_, err := tx.Exec(`
+		INSERT INTO post_revisions (post_id, subreddit_id, author_id, editor_id, title, content, reason)
+		VALUES ($1, $2, $3, $4, $5, $6, $7)
+	`, post.ID, post.SubredditID, post.AuthorID, editorID, post.Title, post.Content, reason)
+	if err != nil {
+		return fmt.Errorf("failed to record post revision: %w", err)
+	}
+	return nil
+}
+
+// ListPostRevisions returns the stored revisions of a post, oldest first.
+// It also works for deleted posts.
+func ListPostRevisions(postID int) ([]*PostRevision, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, post_id, subreddit_id, author_id, editor_id, title, content, reason, created_at
+		FROM post_revisions WHERE post_id = $1
+		ORDER BY created_at, id
+	`, postID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list post revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*PostRevision{}
+	for rows.Next() {
+		r := &PostRevision{}
+		err := rows.Scan(&r.ID, &r.PostID, &r.SubredditID, &r.AuthorID, &r.EditorID, &r.Title, &r.Content, &r.Reason, &r.CreatedAt)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post revision: %w", err)
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating post revisions: %w", err)
+	}
+
+	return revisions, nil
+}
+
+// PostHistory turns revisions into numbered versions, each with a diff to
+// the next one. current is the live post, or nil if it was deleted.
+func PostHistory(revisions []*PostRevision, current *Post) []*PostVersion {
+	versions := make([]*PostVersion, 0, len(revisions)+1)
+	for i, r := range revisions {
+		versions = append(versions, &PostVersion{
+			Number:     i + 1,
+			Title:      r.Title,
+			Content:    r.Content,
+			ReplacedAt: &r.CreatedAt,
+			ReplacedBy: r.EditorID,
+			Reason:     &r.Reason,
+		})
+	}
+	if current != nil {
+		versions = append(versions, &PostVersion{
+			Number:  len(versions) + 1,
+			Title:   current.Title,
+			Content: current.Content,
+		})
+	}
+
+	for i := 0; i+1 < len(versions); i++ {
+		from, to := versions[i], versions[i+1]
+		versions[i].Diff = utils.UnifiedDiff(
+			fmt.Sprintf("version %d", from.Number),
+			fmt.Sprintf("version %d", to.Number),
+			versionText(from), versionText(to),
+		)
+	}
+	return versions
+}
+
+// versionText is the text diffed between versions: the title, a blank line
+// and the body.
+func versionText(v *PostVersion) string {
+	text := v.Title + "\n"
+	if v.Content != nil && *v.Content != "" {
+		text += "\n" + *v.Content + "\n"
+	}
+	return text
+}
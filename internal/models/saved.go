@@ -0,0 +1,179 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+type SavedPost struct {
+	*Post
+	Category *string   `json:"category"`
+	SavedAt  time.Time `json:"saved_at"`
+}
+
+type HiddenPost struct {
+	*Post
+	HiddenAt time.Time `json:"hidden_at"`
+}
+
+// SavePost bookmarks a post for a user. Saving an already saved post moves
+// it to the given category.
+func SavePost(userID, postID int, category *string) error {
+	query := `
+		INSERT INTO saved_posts (user_id, post_id, category)
+		VALUES ($1, $2, $3)
+		ON CONFLICT (user_id, post_id) DO UPDATE SET category = EXCLUDED.category
+	`
+	_, err := database.DB.Exec(query, userID, postID, category)
+	if err != nil {
+		return fmt.Errorf("failed to save post: %w", err)
+	}
+	return nil
+}
+
+func UnsavePost(userID, postID int) error {
+	query := `DELETE FROM saved_posts WHERE user_id = $1 AND post_id = $2`
+	_, err := database.DB.Exec(query, userID, postID)
+	if err != nil {
+		return fmt.Errorf("failed to unsave post: %w", err)
+	}
+	return nil
+}
+
+// ListSavedPosts returns a user's saved posts, most recently saved first,
+// optionally limited to one category.
+func ListSavedPosts(userID int, category *string, limit, offset int) ([]*SavedPost, error) {
+	q := &queryBuilder{}
+	q.where("saved_posts.user_id = " + q.arg(userID))
+	if category != nil {
+		q.where("saved_posts.category = " + q.arg(*category))
+	}
+
+	query := `SELECT ` + postColumns + `, saved_posts.category, saved_posts.saved_at
+		FROM posts JOIN saved_posts ON saved_posts.post_id = posts.id` +
+		q.whereClause() +
+		` ORDER BY saved_posts.saved_at DESC LIMIT ` + q.arg(limit) + ` OFFSET ` + q.arg(offset)
+
+	rows, err := database.DB.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved posts: %w", err)
+	}
+	defer rows.Close()
+
+	saved := []*SavedPost{}
+	for rows.Next() {
+		s := &SavedPost{Post: &Post{}}
+		if err := rows.Scan(append(postScanTargets(s.Post), &s.Category, &s.SavedAt)...); err != nil {
+			return nil, fmt.Errorf("failed to scan saved post: %w", err)
+		}
+		finishPostScan(s.Post)
+		saved = append(saved, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating saved posts: %w", err)
+	}
+
+	posts := make([]*Post, len(saved))
+	for i, s := range saved {
+		posts[i] = s.Post
+	}
+	if err := LoadPostDetails(posts, &userID); err != nil {
+		return nil, err
+	}
+
+	return saved, nil
+}
+
+// ListSavedCategories returns the distinct categories a user has saved
+// posts under.
+func ListSavedCategories(userID int) ([]string, error) {
+	query := `
+		SELECT DISTINCT category FROM saved_posts
+		WHERE user_id = $1 AND category IS NOT NULL
+		ORDER BY category
+	`
+	rows, err := database.DB.Query(query, userID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list saved categories: %w", err)
+	}
+	defer rows.Close()
+
+	categories := []string{}
+	for rows.Next() {
+		var category string
+		if err := rows.Scan(&category); err != nil {
+			return nil, fmt.Errorf("failed to scan category: %w", err)
+		}
+		categories = append(categories, category)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating categories: %w", err)
+	}
+
+	return categories, nil
+}
+
+func HidePost(userID, postID int) error {
+	query := `
+		INSERT INTO hidden_posts (user_id, post_id)
+		VALUES ($1, $2)
+		ON CONFLICT (user_id, post_id) DO NOTHING
+	`
+	_, err := database.DB.Exec(query, userID, postID)
+	if err != nil {
+		return fmt.Errorf("failed to hide post: %w", err)
+	}
+	return nil
+}
+
+func UnhidePost(userID, postID int) error {
+	query := `DELETE FROM hidden_posts WHERE user_id = $1 AND post_id = $2`
+	_, err := database.DB.Exec(query, userID, postID)
+	if err != nil {
+		return fmt.Errorf("failed to unhide post: %w", err)
+	}
+	return nil
+}
+
+// ListHiddenPosts returns a user's hidden posts, most recently hidden first.
+func ListHiddenPosts(userID, limit, offset int) ([]*HiddenPost, error) {
+	query := `SELECT ` + postColumns + `, hidden_posts.hidden_at
+		FROM posts JOIN hidden_posts ON hidden_posts.post_id = posts.id
+		WHERE hidden_posts.user_id = $1
+		ORDER BY hidden_posts.hidden_at DESC
+		LIMIT $2 OFFSET $3`
+
+	rows, err := database.DB.Query(query, userID, limit, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list hidden posts: %w", err)
+	}
+	defer rows.Close()
+
+	hidden := []*HiddenPost{}
+	for rows.Next() {
+		h := &HiddenPost{Post: &Post{}}
+		if err := rows.Scan(append(postScanTargets(h.Post), &h.HiddenAt)...); err != nil {
+			return nil, fmt.Errorf("failed to scan hidden post: %w", err)
+		}
+		finishPostScan(h.Post)
+		hidden = append(hidden, h)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating hidden posts: %w", err)
+	}
+
+	posts := make([]*Post, len(hidden))
+	for i, h := range hidden {
+		posts[i] = h.Post
+	}
+	if err := LoadPostDetails(posts, &userID); err != nil {
+		return nil, err
+	}
+
+	return hidden, nil
+}
@@ -0,0 +1,215 @@
+package models
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/utils"
+)
+
+// Sentinels passed to ts_headline so matches can be wrapped in <mark> after
+// the surrounding text has been HTML-escaped.
+const (
+	highlightStart = "\x02"
+	highlightStop  = "\x03"
+)
+
+var headlineOptions = fmt.Sprintf("StartSel=%s, StopSel=%s, MaxWords=35, MinWords=15, MaxFragments=2, FragmentDelimiter=\" … \"",
+	highlightStart, highlightStop)
+
+type SearchParams struct {
+	Query       string
+	SubredditID *int
+	Author      string
+	ViewerID    *int // Applies the viewer's hidden posts and blocks
+	From        *time.Time
+	To          *time.Time
+	IncludeNSFW bool
+	Sort        string // "relevance", "new" or "top"
+	Limit       int
+	Offset      int
+}
+
+type PostSearchResult struct {
+	*Post
+	Rank           float64 `json:"rank"`
+	TitleHighlight string  `json:"title_highlight"`
+	Snippet        string  `json:"snippet"`
+}
+
+type SubredditSearchResult struct {
+	*Subreddit
+	Rank    float64 `json:"rank"`
+	Snippet string  `json:"snippet"`
+}
+
+// SearchPosts runs a full-text search over post titles, bodies and links.
+func SearchPosts(params SearchParams) ([]*PostSearchResult, error) {
+	q := &queryBuilder{}
+	tsquery := q.arg(params.Query)
+	opts := q.arg(headlineOptions)
+
+	q.where("search_vector @@ query")
+	if params.SubredditID != nil {
+		q.where("subreddit_id = " + q.arg(*params.SubredditID))
+	}
+	if params.Author != "" {
+		q.where("author_id = (SELECT id FROM users WHERE username = " + q.arg(params.Author) + ")")
+	}
+	if params.From != nil {
+		q.where("created_at >= " + q.arg(*params.From))
+	}
+	if params.To != nil {
+		q.where("created_at <= " + q.arg(*params.To))
+	}
+	applyNSFWFilter(q, params.IncludeNSFW)
+	applyViewerFilters(q, params.ViewerID, false)
+
+	orderBy := "rank DESC, created_at DESC"
+	switch params.Sort {
+	case "new":
+		orderBy = "created_at DESC"
+	case "top":
+		orderBy = "score DESC, created_at DESC"
+	}
+
+	query := `SELECT ` + postColumns + `,
+		       ts_rank_cd(search_vector, query) AS rank,
+		       ts_headline('english', title, query, ` + opts + `),
+		       ts_headline('english', coalesce(content, ''), query, ` + opts + `)
+		FROM posts, websearch_to_tsquery('english', ` + tsquery + `) AS query` +
+		q.whereClause() +
+		` ORDER BY ` + orderBy +
+		` LIMIT ` + q.arg(params.Limit) + ` OFFSET ` + q.arg(params.Offset)
+
+	rows, err := database.DB.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search posts: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*PostSearchResult{}
+	for rows.Next() {
+		r := &PostSearchResult{Post: &Post{}}
+		var titleHL, snippet string
+		err := rows.Scan(append(postScanTargets(r.Post), &r.Rank, &titleHL, &snippet)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan post: %w", err)
+		}
+		finishPostScan(r.Post)
+		r.TitleHighlight = utils.HighlightHTML(titleHL, highlightStart, highlightStop)
+		r.Snippet = utils.HighlightHTML(snippet, highlightStart, highlightStop)
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating posts: %w", err)
+	}
+
+	posts := make([]*Post, len(results))
+	for i, r := range results {
+		posts[i] = r.Post
+	}
+	if err := LoadPostDetails(posts, params.ViewerID); err != nil {
+		return nil, err
+	}
+
+	return results, nil
+}
+
+// SearchSubreddits runs a full-text search over subreddit names and
+// descriptions.
+func SearchSubreddits(params SearchParams) ([]*SubredditSearchResult, error) {
+	q := &queryBuilder{}
+	tsquery := q.arg(params.Query)
+	opts := q.arg(headlineOptions)
+
+	q.where("search_vector @@ query")
+	if !params.IncludeNSFW {
+		q.where("NOT is_nsfw")
+	}
+
+	orderBy := "rank DESC, members_count DESC"
+	switch params.Sort {
+	case "new":
+		orderBy = "created_at DESC"
+	case "top":
+		orderBy = "members_count DESC"
+	}
+
+	query := `SELECT ` + subredditColumns + `,
+		       ts_rank_cd(search_vector, query) AS rank,
+		       ts_headline('english', coalesce(description, ''), query, ` + opts + `)
+		FROM subreddits, websearch_to_tsquery('english', ` + tsquery + `) AS query` +
+		q.whereClause() +
+		` ORDER BY ` + orderBy +
+		` LIMIT ` + q.arg(params.Limit) + ` OFFSET ` + q.arg(params.Offset)
+
+	rows, err := database.DB.Query(query, q.args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to search subreddits: %w", err)
+	}
+	defer rows.Close()
+
+	results := []*SubredditSearchResult{}
+	for rows.Next() {
+		r := &SubredditSearchResult{Subreddit: &Subreddit{}}
+		var snippet string
+		err := rows.Scan(append(subredditScanTargets(r.Subreddit), &r.Rank, &snippet)...)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subreddit: %w", err)
+		}
+		finishSubredditScan(r.Subreddit)
+		r.Snippet = utils.HighlightHTML(snippet, highlightStart, highlightStop)
+		results = append(results, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subreddits: %w", err)
+	}
+
+	return results, nil
+}
+
+// AutocompleteSubreddits suggests subreddits whose names start with or are
+// similar to prefix, so small typos still find the community.
+func AutocompleteSubreddits(prefix string, includeNSFW bool, limit int) ([]*Subreddit, error) {
+	prefix = strings.ToLower(strings.TrimSpace(prefix))
+	like := escapeLike(prefix) + "%"
+
+	query := `
+		SELECT ` + subredditColumns + `
+		FROM subreddits
+		WHERE (name LIKE $1 OR name % $2)
+		  AND ($3 OR NOT is_nsfw)
+		ORDER BY (name LIKE $1) DESC, similarity(name, $2) DESC, members_count DESC
+		LIMIT $4
+	`
+
+	rows, err := database.DB.Query(query, like, prefix, includeNSFW, limit)
+	if err != nil {
+		return nil, fmt.Errorf("failed to autocomplete subreddits: %w", err)
+	}
+	defer rows.Close()
+
+	subreddits := []*Subreddit{}
+	for rows.Next() {
+		s, err := scanSubreddit(rows)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan subreddit: %w", err)
+		}
+		subreddits = append(subreddits, s)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating subreddits: %w", err)
+	}
+
+	return subreddits, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
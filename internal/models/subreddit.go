@@ -7,40 +7,117 @@ import (
 	"time"
 
 	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/utils"
+	"github.com/lib/pq"
 )
 
 type Subreddit struct {
-	ID             int             `json:"id"`
-	Name           string          `json:"name"`
-	DisplayName    string          `json:"display_name"`
-	Description    *string         `json:"description"`
-	Rules          json.RawMessage `json:"rules"` // JSONB
-	BannerImageURL *string         `json:"banner_image_url"`
-	IconImageURL   *string         `json:"icon_image_url"`
-	IsNSFW         bool            `json:"is_nsfw"`
-	IsPrivate      bool            `json:"is_private"`
-	CreatedBy      int             `json:"created_by"`
-	MembersCount   int             `json:"members_count"`
-	ActiveUsers    int             `json:"active_users"`
-	Flairs         json.RawMessage `json:"flairs"` // JSONB
-	RulesUpdatedAt *time.Time      `json:"rules_updated_at"`
-	CreatedAt      time.Time       `json:"created_at"`
-	UpdatedAt      time.Time       `json:"updated_at"`
+	ID                      int             `json:"id"`
+	Name                    string          `json:"name"`
+	DisplayName             string          `json:"display_name"`
+	Description             *string         `json:"description"`
+	DescriptionHTML         *string         `json:"description_html"` // Rendered from Description on write
+	Rules                   json.RawMessage `json:"rules"`            // JSONB
+	BannerImageURL          *string         `json:"banner_image_url"`
+	IconImageURL            *string         `json:"icon_image_url"`
+	IsNSFW                  bool            `json:"is_nsfw"`
+	IsPrivate               bool            `json:"is_private"`
+	CreatedBy               int             `json:"created_by"`
+	MembersCount            int             `json:"members_count"`
+	ActiveUsers             int             `json:"active_users"`
+	Flairs                  json.RawMessage `json:"flairs"` // JSONB
+	RulesUpdatedAt          *time.Time      `json:"rules_updated_at"`
+	MinKarmaToPost          int             `json:"min_karma_to_post"`           // 0 = no limit
+	MinSubredditKarmaToPost int             `json:"min_subreddit_karma_to_post"` // 0 = no limit
+	ArchiveAfterDays        int             `json:"archive_after_days"`          // 0 = never archive
+	Language                string          `json:"language"`                    // BCP 47 tag, e.g. "en" or "pt-BR"
+	Categories              []string        `json:"categories"`                  // Category slugs
+	CreatedAt               time.Time       `json:"created_at"`
+	UpdatedAt               time.Time       `json:"updated_at"`
+}
+
+// subredditColumns lists the subreddits columns in the order scanSubreddit
+// reads them. Queries must select FROM subreddits without an alias, which
+// the categories subquery refers to.
+const subredditColumns = `id, name, display_name, description, description_html, rules,
+		       banner_image_url, icon_image_url, is_nsfw, is_private,
+		       created_by, members_count, active_users, flairs,
+		       rules_updated_at, min_karma_to_post, min_subreddit_karma_to_post,
+		       archive_after_days, language,
+		       ARRAY(SELECT c.slug FROM subreddit_categories sc JOIN categories c ON c.id = sc.category_id
+		             WHERE sc.subreddit_id = subreddits.id ORDER BY c.slug),
+		       created_at, updated_at`
+
+func subredditScanTargets(subreddit *Subreddit) []any {
+	return []any{
+		&subreddit.ID,
+		&subreddit.Name,
+		&subreddit.DisplayName,
+		&subreddit.Description,
+		&subreddit.DescriptionHTML,
+		&subreddit.Rules,
+		&subreddit.BannerImageURL,
+		&subreddit.IconImageURL,
+		&subreddit.IsNSFW,
+		&subreddit.IsPrivate,
+		&subreddit.CreatedBy,
+		&subreddit.MembersCount,
+		&subreddit.ActiveUsers,
+		&subreddit.Flairs,
+		&subreddit.RulesUpdatedAt,
+		&subreddit.MinKarmaToPost,
+		&subreddit.MinSubredditKarmaToPost,
+		&subreddit.ArchiveAfterDays,
+		&subreddit.Language,
+		(*pq.StringArray)(&subreddit.Categories),
+		&subreddit.CreatedAt,
+		&subreddit.UpdatedAt,
+	}
+}
+
+func scanSubreddit(row rowScanner) (*Subreddit, error) {
+	subreddit := &Subreddit{}
+	if err := row.Scan(subredditScanTargets(subreddit)...); err != nil {
+		return nil, err
+	}
+	finishSubredditScan(subreddit)
+	return subreddit, nil
+}
+
+// finishSubredditScan fills in derived fields after a subreddit row has been
+// scanned.
+func finishSubredditScan(subreddit *Subreddit) {
+	// Rows written before rendering existed are rendered on read.
+	if subreddit.DescriptionHTML == nil {
+		renderSubredditDescription(subreddit)
+	}
+}
+
+// renderSubredditDescription caches the sanitized HTML rendering of the
+// about section.
+func renderSubredditDescription(subreddit *Subreddit) {
+	subreddit.DescriptionHTML = nil
+	if subreddit.Description != nil {
+		html := utils.RenderMarkdown(*subreddit.Description)
+		subreddit.DescriptionHTML = &html
+	}
 }
 
 // CreateSubreddit creates a new subreddit
 func CreateSubreddit(subreddit *Subreddit) (*Subreddit, error) {
 
+	renderSubredditDescription(subreddit)
+
 	query := `
 	INSERT INTO subreddits (
-	  name, display_name, description, rules,
+	  name, display_name, description, description_html, rules,
 	  banner_image_url, icon_image_url,
 	  is_nsfw, is_private, created_by,
 	  members_count, active_users,
-	  flairs
+	  flairs, language
 	)
-	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)
-	RETURNING id, created_at, updated_at;
+	VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14)
+	RETURNING id, archive_after_days, created_at, updated_at;
 	`
 
 	rules := subreddit.Rules
@@ -52,11 +129,18 @@ func CreateSubreddit(subreddit *Subreddit) (*Subreddit, error) {
 	if len(flairs) == 0 {
 		flairs = json.RawMessage(`[]`)
 	}
-	err := database.DB.QueryRow(
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	err = tx.QueryRow(
 		query,
 		subreddit.Name,
 		subreddit.DisplayName,
 		subreddit.Description,
+		subreddit.DescriptionHTML,
 		rules,
 		subreddit.BannerImageURL,
 		subreddit.IconImageURL,
@@ -66,11 +150,19 @@ func CreateSubreddit(subreddit *Subreddit) (*Subreddit, error) {
 		subreddit.MembersCount,
 		subreddit.ActiveUsers,
 		flairs,
-	).Scan(&subreddit.ID, &subreddit.CreatedAt, &subreddit.UpdatedAt)
+		subreddit.Language,
+	).Scan(&subreddit.ID, &subreddit.ArchiveAfterDays, &subreddit.CreatedAt, &subreddit.UpdatedAt)
 
 	if err != nil {
 		return nil, fmt.Errorf("failed to insert subreddit: %w", err)
 	}
+	if err := setSubredditCategories(tx, subreddit.ID, subreddit.Categories); err != nil {
+		return nil, err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit subreddit: %w", err)
+	}
 	subreddit.Rules = rules
 	subreddit.Flairs = flairs
 	return subreddit, nil
@@ -81,10 +173,7 @@ func CreateSubreddit(subreddit *Subreddit) (*Subreddit, error) {
 func GetSubredditByDisplayName(name string) (*Subreddit, error) {
 	// TODO: Implement
 	query := `
-		SELECT id, name, display_name, description, rules,
-		       banner_image_url, icon_image_url, is_nsfw, is_private,
-		       created_by, members_count, active_users, flairs,
-		       rules_updated_at, created_at, updated_at
+		SELECT ` + subredditColumns + `
 		FROM subreddits
 		WHERE display_name = $1
 	`
@@ -92,25 +181,7 @@ func GetSubredditByDisplayName(name string) (*Subreddit, error) {
 	// 2. Scan all fields (including JSONB)
 	// 3. Return nil if not found (sql.ErrNoRows)
 	// 4. Return error for other database issues
-	subreddit := &Subreddit{}
-	err := database.DB.QueryRow(query, name).Scan(
-		&subreddit.ID,
-		&subreddit.Name,
-		&subreddit.DisplayName,
-		&subreddit.Description,
-		&subreddit.Rules,
-		&subreddit.BannerImageURL,
-		&subreddit.IconImageURL,
-		&subreddit.IsNSFW,
-		&subreddit.IsPrivate,
-		&subreddit.CreatedBy,
-		&subreddit.MembersCount,
-		&subreddit.ActiveUsers,
-		&subreddit.Flairs,
-		&subreddit.RulesUpdatedAt,
-		&subreddit.CreatedAt,
-		&subreddit.UpdatedAt,
-	)
+	subreddit, err := scanSubreddit(database.DB.QueryRow(query, name))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -122,10 +193,7 @@ func GetSubredditByDisplayName(name string) (*Subreddit, error) {
 func GetSubredditByName(name string) (*Subreddit, error) {
 	// TODO: Implement
 	query := `
-		SELECT id, name, display_name, description, rules,
-		       banner_image_url, icon_image_url, is_nsfw, is_private,
-		       created_by, members_count, active_users, flairs,
-		       rules_updated_at, created_at, updated_at
+		SELECT ` + subredditColumns + `
 		FROM subreddits
 		WHERE name = $1
 	`
@@ -133,25 +201,7 @@ func GetSubredditByName(name string) (*Subreddit, error) {
 	// 2. Scan all fields (including JSONB)
 	// 3. Return nil if not found (sql.ErrNoRows)
 	// 4. Return error for other database issues
-	subreddit := &Subreddit{}
-	err := database.DB.QueryRow(query, name).Scan(
-		&subreddit.ID,
-		&subreddit.Name,
-		&subreddit.DisplayName,
-		&subreddit.Description,
-		&subreddit.Rules,
-		&subreddit.BannerImageURL,
-		&subreddit.IconImageURL,
-		&subreddit.IsNSFW,
-		&subreddit.IsPrivate,
-		&subreddit.CreatedBy,
-		&subreddit.MembersCount,
-		&subreddit.ActiveUsers,
-		&subreddit.Flairs,
-		&subreddit.RulesUpdatedAt,
-		&subreddit.CreatedAt,
-		&subreddit.UpdatedAt,
-	)
+	subreddit, err := scanSubreddit(database.DB.QueryRow(query, name))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -165,10 +215,7 @@ func GetSubredditByName(name string) (*Subreddit, error) {
 func GetSubredditByID(id int) (*Subreddit, error) {
 	// TODO: Implement
 	query := `
-		SELECT id, name, display_name, description, rules,
-		       banner_image_url, icon_image_url, is_nsfw, is_private,
-		       created_by, members_count, active_users, flairs,
-		       rules_updated_at, created_at, updated_at
+		SELECT ` + subredditColumns + `
 		FROM subreddits
 		WHERE id = $1
 	`
@@ -176,25 +223,7 @@ func GetSubredditByID(id int) (*Subreddit, error) {
 	// 2. Scan all fields (including JSONB)
 	// 3. Return nil if not found (sql.ErrNoRows)
 	// 4. Return error for other database issues
-	subreddit := &Subreddit{}
-	err := database.DB.QueryRow(query, id).Scan(
-		&subreddit.ID,
-		&subreddit.Name,
-		&subreddit.DisplayName,
-		&subreddit.Description,
-		&subreddit.Rules,
-		&subreddit.BannerImageURL,
-		&subreddit.IconImageURL,
-		&subreddit.IsNSFW,
-		&subreddit.IsPrivate,
-		&subreddit.CreatedBy,
-		&subreddit.MembersCount,
-		&subreddit.ActiveUsers,
-		&subreddit.Flairs,
-		&subreddit.RulesUpdatedAt,
-		&subreddit.CreatedAt,
-		&subreddit.UpdatedAt,
-	)
+	subreddit, err := scanSubreddit(database.DB.QueryRow(query, id))
 	if err == sql.ErrNoRows {
 		return nil, nil
 	}
@@ -204,19 +233,15 @@ func GetSubredditByID(id int) (*Subreddit, error) {
 	return subreddit, nil
 }
 
-// ListSubreddits retrieves all subreddits with pagination
-func ListSubreddits(limit, offset int) ([]*Subreddit, error) {
-	query := `
-		SELECT id, name, display_name, description, rules,
-		       banner_image_url, icon_image_url, is_nsfw, is_private,
-		       created_by, members_count, active_users, flairs,
-		       rules_updated_at, created_at, updated_at
-		FROM subreddits
-		ORDER BY members_count DESC
-		LIMIT $1 OFFSET $2
-	`
+// ListSubreddits retrieves subreddits matching filter, largest first.
+func ListSubreddits(limit, offset int, filter SubredditFilter) ([]*Subreddit, error) {
+	q := &queryBuilder{}
+	applySubredditFilter(q, filter)
+
+	query := `SELECT ` + subredditColumns + ` FROM subreddits` + q.whereClause() +
+		` ORDER BY members_count DESC, id LIMIT ` + q.arg(limit) + ` OFFSET ` + q.arg(offset)
 
-	rows, err := database.DB.Query(query, limit, offset)
+	rows, err := database.DB.Query(query, q.args...)
 	if err != nil {
 		return nil, fmt.Errorf("failed to list subreddits: %w", err)
 	}
@@ -225,27 +250,7 @@ func ListSubreddits(limit, offset int) ([]*Subreddit, error) {
 	subreddits := []*Subreddit{}
 
 	for rows.Next() {
-		s := &Subreddit{} // Create new instance (not nil!)
-
-		err := rows.Scan(
-			&s.ID,
-			&s.Name,
-			&s.DisplayName,
-			&s.Description,
-			&s.Rules,
-			&s.BannerImageURL,
-			&s.IconImageURL,
-			&s.IsNSFW,
-			&s.IsPrivate,
-			&s.CreatedBy,
-			&s.MembersCount,
-			&s.ActiveUsers,
-			&s.Flairs,
-			&s.RulesUpdatedAt,
-			&s.CreatedAt,
-			&s.UpdatedAt,
-		)
-
+		s, err := scanSubreddit(rows)
 		if err != nil {
 			return nil, fmt.Errorf("failed to scan subreddit: %w", err)
 		}
@@ -263,19 +268,26 @@ func ListSubreddits(limit, offset int) ([]*Subreddit, error) {
 
 // UpdateSubreddit updates subreddit information
 func UpdateSubreddit(subreddit *Subreddit) error {
+	renderSubredditDescription(subreddit)
+
 	query := `
 		UPDATE subreddits 
 		SET display_name = $1,
 		    description = $2,
-		    rules = $3,
-		    banner_image_url = $4,
-		    icon_image_url = $5,
-		    is_nsfw = $6,
-		    is_private = $7,
-		    flairs = $8,
-		    rules_updated_at = $9,
+		    description_html = $3,
+		    rules = $4,
+		    banner_image_url = $5,
+		    icon_image_url = $6,
+		    is_nsfw = $7,
+		    is_private = $8,
+		    flairs = $9,
+		    rules_updated_at = $10,
+		    min_karma_to_post = $11,
+		    min_subreddit_karma_to_post = $12,
+		    archive_after_days = $14,
+		    language = $15,
 		    updated_at = CURRENT_TIMESTAMP
-		WHERE id = $10
+		WHERE id = $13
 	`
 
 	// Set defaults for JSONB if empty
@@ -289,10 +301,17 @@ func UpdateSubreddit(subreddit *Subreddit) error {
 		flairs = json.RawMessage(`[]`)
 	}
 
-	_, err := database.DB.Exec(
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	_, err = tx.Exec(
 		query,
 		subreddit.DisplayName,
 		subreddit.Description,
+		subreddit.DescriptionHTML,
 		rules,
 		subreddit.BannerImageURL,
 		subreddit.IconImageURL,
@@ -300,13 +319,26 @@ func UpdateSubreddit(subreddit *Subreddit) error {
 		subreddit.IsPrivate,
 		flairs,
 		subreddit.RulesUpdatedAt,
+		subreddit.MinKarmaToPost,
+		subreddit.MinSubredditKarmaToPost,
 		subreddit.ID,
+		subreddit.ArchiveAfterDays,
+		subreddit.Language,
 	)
 
 	if err != nil {
 		return fmt.Errorf("failed to update subreddit: %w", err)
 	}
+	// Categories are left alone when not given
+	if subreddit.Categories != nil {
+		if err := setSubredditCategories(tx, subreddit.ID, subreddit.Categories); err != nil {
+			return err
+		}
+	}
 
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit subreddit: %w", err)
+	}
 	return nil
 }
 func DeleteSubreddit(id int) error {
@@ -325,3 +357,37 @@ func DeleteSubreddit(id int) error {
 
 	return nil
 }
+
+// ListSubredditModeratorIDs returns the users who moderate a subreddit.
+// Until moderator teams exist this is just the subreddit's creator.
+func ListSubredditModeratorIDs(subredditID int) ([]int, error) {
+	var createdBy int
+	err := database.DB.QueryRow(`SELECT created_by FROM subreddits WHERE id = $1`, subredditID).Scan(&createdBy)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get moderators: %w", err)
+	}
+	return []int{createdBy}, nil
+}
+
+// IsSubredditModerator reports whether userID moderates subredditID.
+func IsSubredditModerator(subredditID, userID int) (bool, error) {
+	mods, err := ListSubredditModeratorIDs(subredditID)
+	if err != nil {
+		return false, err
+	}
+	for _, id := range mods {
+		if id == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// moderatedSubredditsSQL selects the IDs of subreddits moderated by the
+// user bound to the given placeholder.
+func moderatedSubredditsSQL(userParam string) string {
+	return `SELECT id FROM subreddits WHERE created_by = ` + userParam
+}
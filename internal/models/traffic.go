@@ -0,0 +1,80 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+)
+
+// Traffic intervals
+const (
+	TrafficHour  = "hour"
+	TrafficDay   = "day"
+	TrafficMonth = "month"
+)
+
+// MaxTrafficBuckets caps how many buckets one traffic report returns.
+const MaxTrafficBuckets = 1000
+
+// TrafficBucket is a subreddit's traffic for one hour, day or month
+// starting at Start (UTC).
+type TrafficBucket struct {
+	Start           time.Time `json:"start"`
+	PageViews       int       `json:"page_views"`
+	UniqueVisitors  int       `json:"unique_visitors"`
+	Subscriptions   int       `json:"subscriptions"`
+	Unsubscriptions int       `json:"unsubscriptions"`
+	Posts           int       `json:"posts"`
+	Comments        int       `json:"comments"`
+}
+
+func IsTrafficInterval(interval string) bool {
+	return interval == TrafficHour || interval == TrafficDay || interval == TrafficMonth
+}
+
+// GetSubredditTraffic returns one bucket per interval from the one holding
+// from to the one holding to, oldest first, including empty buckets.
+// Unique visitors are counted over the whole bucket, not summed by hour.
+func GetSubredditTraffic(subredditID int, interval string, from, to time.Time) ([]*TrafficBucket, error) {
+	rows, err := database.DB.Query(`
+		WITH buckets AS (
+			SELECT start, start + ('1 ' || $2)::interval AS stop
+			FROM generate_series(date_trunc($2, $3::timestamp), date_trunc($2, $4::timestamp), ('1 ' || $2)::interval) AS start
+		)
+		SELECT b.start,
+		       COALESCE(SUM(h.page_views), 0),
+		       (SELECT COUNT(DISTINCT v.visitor) FROM subreddit_traffic_visitors v
+		        WHERE v.subreddit_id = $1 AND v.hour >= b.start AND v.hour < b.stop),
+		       COALESCE(SUM(h.subscriptions), 0),
+		       COALESCE(SUM(h.unsubscriptions), 0),
+		       COALESCE(SUM(h.posts), 0),
+		       COALESCE(SUM(h.comments), 0)
+		FROM buckets b
+		LEFT JOIN subreddit_traffic_hourly h
+		       ON h.subreddit_id = $1 AND h.hour >= b.start AND h.hour < b.stop
+		GROUP BY b.start, b.stop
+		ORDER BY b.start
+		LIMIT $5
+	`, subredditID, interval, from.UTC(), to.UTC(), MaxTrafficBuckets)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get subreddit traffic: %w", err)
+	}
+	defer rows.Close()
+
+	buckets := []*TrafficBucket{}
+	for rows.Next() {
+		b := &TrafficBucket{}
+		err := rows.Scan(&b.Start, &b.PageViews, &b.UniqueVisitors, &b.Subscriptions, &b.Unsubscriptions, &b.Posts, &b.Comments)
+		if err != nil {
+			return nil, fmt.Errorf("failed to scan traffic: %w", err)
+		}
+		buckets = append(buckets, b)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating traffic: %w", err)
+	}
+
+	return buckets, nil
+}
@@ -17,6 +17,8 @@ type User struct {
 	PasswordHash      string     `json:"-"` // "-" means never include in JSON
 	AvatarURL         *string    `json:"avatar_url"`
 	Bio               *string    `json:"bio"`
+	PostKarma         int        `json:"post_karma"`
+	CommentKarma      int        `json:"comment_karma"`
 	CreatedAt         time.Time  `json:"created_at"`
 	UpdatedAt         time.Time  `json:"updated_at"`
 	ResetToken        *string    `json:"-"` // Add this
@@ -32,7 +34,7 @@ func CreateUser(username, email, password string) (*User, error) {
 
 	userInsertQuery := `INSERT INTO users (username, email, password_hash)
 VALUES ($1, $2, $3)
-RETURNING id, username, email, avatar_url, bio, created_at, updated_at, reset_token, reset_token_expires`
+RETURNING id, username, email, avatar_url, bio, post_karma, comment_karma, created_at, updated_at, reset_token, reset_token_expires`
 	user := &User{}
 	err = database.DB.QueryRow(userInsertQuery, username, email, hashedPassword).Scan(
 		&user.ID,
@@ -40,6 +42,8 @@ RETURNING id, username, email, avatar_url, bio, created_at, updated_at, reset_to
 		&user.Email,
 		&user.AvatarURL,
 		&user.Bio,
+		&user.PostKarma,
+		&user.CommentKarma,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.ResetToken,
@@ -56,7 +60,7 @@ func GetUserByEmail(email string) (*User, error) {
 
 	user := &User{}
 	query := `
-		SELECT id, username, email, password_hash, avatar_url, bio, created_at, updated_at
+		SELECT id, username, email, password_hash, avatar_url, bio, post_karma, comment_karma, created_at, updated_at
 		FROM users
 		WHERE email = $1
 	`
@@ -67,6 +71,8 @@ func GetUserByEmail(email string) (*User, error) {
 		&user.PasswordHash,
 		&user.AvatarURL,
 		&user.Bio,
+		&user.PostKarma,
+		&user.CommentKarma,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -80,7 +86,7 @@ func GetUserByEmail(email string) (*User, error) {
 func GetUserByID(id int) (*User, error) {
 	user := &User{}
 	query := `
-		SELECT id, username, email, password_hash, avatar_url, bio, created_at, updated_at
+		SELECT id, username, email, password_hash, avatar_url, bio, post_karma, comment_karma, created_at, updated_at
 		FROM users
 		WHERE id = $1
 	`
@@ -91,6 +97,8 @@ func GetUserByID(id int) (*User, error) {
 		&user.PasswordHash,
 		&user.AvatarURL,
 		&user.Bio,
+		&user.PostKarma,
+		&user.CommentKarma,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 	)
@@ -126,7 +134,7 @@ func SaveResetToken(userID int, token string, expiresAt time.Time) error {
 	return nil
 }
 func GetUserByResetToken(token string) (*User, error) {
-	query := `SELECT id, username, email, password_hash, avatar_url,bio, created_at, updated_at, reset_token, reset_token_expires 
+	query := `SELECT id, username, email, password_hash, avatar_url,bio, post_karma, comment_karma, created_at, updated_at, reset_token, reset_token_expires 
 	          FROM users WHERE reset_token = $1`
 
 	var user User
@@ -137,6 +145,8 @@ func GetUserByResetToken(token string) (*User, error) {
 		&user.PasswordHash,
 		&user.AvatarURL,
 		&user.Bio,
+		&user.PostKarma,
+		&user.CommentKarma,
 		&user.CreatedAt,
 		&user.UpdatedAt,
 		&user.ResetToken,
@@ -163,3 +173,52 @@ func ClearResetToken(userID int) error {
 
 	return nil
 }
+
+// GetUserByUsername returns nil when no user has that username.
+func GetUserByUsername(username string) (*User, error) {
+	user := &User{}
+	query := `
+		SELECT id, username, email, password_hash, avatar_url, bio, post_karma, comment_karma, created_at, updated_at
+		FROM users
+		WHERE username = $1
+	`
+	err := database.DB.QueryRow(query, username).Scan(
+		&user.ID,
+		&user.Username,
+		&user.Email,
+		&user.PasswordHash,
+		&user.AvatarURL,
+		&user.Bio,
+		&user.PostKarma,
+		&user.CommentKarma,
+		&user.CreatedAt,
+		&user.UpdatedAt,
+	)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("error fetching user: %w", err)
+	}
+
+	return user, nil
+}
+
+// UpdateUserProfile sets the editable public profile fields.
+func UpdateUserProfile(userID int, avatarURL, bio *string) error {
+	query := `UPDATE users SET avatar_url = $1, bio = $2 WHERE id = $3`
+
+	_, err := database.DB.Exec(query, avatarURL, bio, userID)
+	if err != nil {
+		return fmt.Errorf("failed to update profile: %w", err)
+	}
+
+	return nil
+}
+
+// UserSummary is the public identity shown next to content and in lists.
+type UserSummary struct {
+	ID        int     `json:"id"`
+	Username  string  `json:"username"`
+	AvatarURL *string `json:"avatar_url"`
+}
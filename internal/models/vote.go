@@ -0,0 +1,151 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/realtime"
+	"github.com/lib/pq"
+)
+
+// Vote directions
+const (
+	VoteUp   = 1
+	VoteNone = 0
+	VoteDown = -1
+)
+
+// PostScore is a post's vote counts after a vote, plus the voter's vote.
+type PostScore struct {
+	PostID    int `json:"post_id"`
+	Upvotes   int `json:"upvotes"`
+	Downvotes int `json:"downvotes"`
+	Score     int `json:"score"`
+	Vote      int `json:"vote"`
+}
+
+// IsVoteDirection reports whether d is VoteUp, VoteNone or VoteDown.
+func IsVoteDirection(d int) bool {
+	return d == VoteUp || d == VoteNone || d == VoteDown
+}
+
+// VotePost sets userID's vote on a post to direction, replacing any earlier
+// vote; VoteNone clears it. The post's counts change by the difference and
+// so does the author's karma, unless the voter is the author.
+func VotePost(postID, userID, direction int) (*PostScore, error) {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	var authorID, subredditID int
+	var archived bool
+	err = tx.QueryRow(`SELECT author_id, subreddit_id, is_archived FROM posts WHERE id = $1 FOR UPDATE`, postID).
+		Scan(&authorID, &subredditID, &archived)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get post: %w", err)
+	}
+	if archived {
+		return nil, ErrPostArchived
+	}
+
+	previous := VoteNone
+	err = tx.QueryRow(`SELECT direction FROM post_votes WHERE post_id = $1 AND user_id = $2`, postID, userID).
+		Scan(&previous)
+	if err != nil && !errors.Is(err, sql.ErrNoRows) {
+		return nil, fmt.Errorf("failed to get vote: %w", err)
+	}
+
+	if direction == VoteNone {
+		_, err = tx.Exec(`DELETE FROM post_votes WHERE post_id = $1 AND user_id = $2`, postID, userID)
+	} else {
+		_, err = tx.Exec(`
+			INSERT INTO post_votes (post_id, user_id, direction) VALUES ($1, $2, $3)
+			ON CONFLICT (post_id, user_id)
+			DO UPDATE SET direction = EXCLUDED.direction, updated_at = CURRENT_TIMESTAMP
+		`, postID, userID, direction)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to record vote: %w", err)
+	}
+
+	score := &PostScore{PostID: postID, Vote: direction}
+	err = tx.QueryRow(`
+		UPDATE posts SET upvotes = upvotes + $1, downvotes = downvotes + $2, score = score + $3
+		WHERE id = $4
+		RETURNING upvotes, downvotes, score
+	`, countOf(direction, VoteUp)-countOf(previous, VoteUp),
+		countOf(direction, VoteDown)-countOf(previous, VoteDown),
+		direction-previous, postID).
+		Scan(&score.Upvotes, &score.Downvotes, &score.Score)
+	if err != nil {
+		return nil, fmt.Errorf("failed to update post score: %w", err)
+	}
+
+	if userID != authorID {
+		err = applyKarmaDelta(tx, authorID, subredditID, KarmaSourcePost, postID, direction-previous, "vote")
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	if err = tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit vote: %w", err)
+	}
+
+	if direction != previous {
+		realtime.Publish(realtime.PostTopic(postID), "vote", map[string]int{
+			"post_id":   postID,
+			"upvotes":   score.Upvotes,
+			"downvotes": score.Downvotes,
+			"score":     score.Score,
+		})
+	}
+	return score, nil
+}
+
+func countOf(vote, direction int) int {
+	if vote == direction {
+		return 1
+	}
+	return 0
+}
+
+// loadViewerVotes sets Vote on each post to the viewer's vote.
+func loadViewerVotes(posts []*Post, viewerID *int) error {
+	if viewerID == nil || len(posts) == 0 {
+		return nil
+	}
+	byID := make(map[int][]*Post)
+	ids := make([]int64, 0, len(posts))
+	for _, p := range posts {
+		if _, ok := byID[p.ID]; !ok {
+			ids = append(ids, int64(p.ID))
+		}
+		byID[p.ID] = append(byID[p.ID], p)
+	}
+
+	rows, err := database.DB.Query(`
+		SELECT post_id, direction FROM post_votes
+		WHERE user_id = $1 AND post_id = ANY($2)
+	`, *viewerID, pq.Array(ids))
+	if err != nil {
+		return fmt.Errorf("failed to load votes: %w", err)
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var postID, direction int
+		if err := rows.Scan(&postID, &direction); err != nil {
+			return fmt.Errorf("failed to scan vote: %w", err)
+		}
+		for _, p := range byID[postID] {
+			p.Vote = direction
+		}
+	}
+
+	return rows.Err()
+}
@@ -0,0 +1,413 @@
+package models
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/kshzz24/gosocial/internal/utils"
+	"github.com/lib/pq"
+)
+
+// Who may edit a wiki page. Moderators can always edit; approved editors
+// can also edit "karma" pages.
+const (
+	WikiEditModerators = "moderators"
+	WikiEditApproved   = "approved" // Moderators and the subreddit's approved wiki editors
+	WikiEditKarma      = "karma"    // Also anyone with the page's min_karma in the subreddit
+)
+
+// MaxWikiPageLength is the longest page body accepted, in bytes.
+const MaxWikiPageLength = 256 * 1024
+
+var (
+	ErrWikiPageExists      = errors.New("a wiki page with this path already exists")
+	ErrWikiParentMissing   = errors.New("the parent page must be created first")
+	ErrWikiPageHasChildren = errors.New("delete the page's subpages first")
+)
+
+var wikiPathPattern = regexp.MustCompile(`^[a-z0-9_-]{1,64}(/[a-z0-9_-]{1,64})*$`)
+
+type WikiPage struct {
+	ID               int       `json:"id"`
+	SubredditID      int       `json:"subreddit_id"`
+	ParentID         *int      `json:"parent_id"`
+	Path             string    `json:"path"`
+	Content          string    `json:"content"`
+	ContentHTML      string    `json:"content_html"` // Rendered from Content on write
+	EditPermission   string    `json:"edit_permission"`
+	MinKarma         int       `json:"min_karma"`          // Subreddit karma needed when EditPermission is "karma"
+	DiscussionPostID *int      `json:"discussion_post_id"` // Post for discussing the page
+	UpdatedBy        *int      `json:"updated_by"`
+	CreatedAt        time.Time `json:"created_at"`
+	UpdatedAt        time.Time `json:"updated_at"`
+}
+
+// WikiPageSummary is a page in the wiki's index, without its content.
+type WikiPageSummary struct {
+	ID        int       `json:"id"`
+	ParentID  *int      `json:"parent_id"`
+	Path      string    `json:"path"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type WikiRevision struct {
+	ID           int       `json:"id"`
+	PageID       int       `json:"page_id"`
+	EditorID     *int      `json:"editor_id"`
+	Content      string    `json:"content"`
+	Reason       *string   `json:"reason"`
+	RevertedFrom *int      `json:"reverted_from"` // Revision restored by a revert
+	Diff         string    `json:"diff"`          // Unified diff from the previous revision
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type WikiEditor struct {
+	UserSummary
+	AddedAt time.Time `json:"added_at"`
+}
+
+// NormalizeWikiPath lowercases path and trims surrounding slashes. It
+// reports false when the result isn't a valid page path: up to 255
+// characters of slash-separated segments of letters, digits, - and _.
+func NormalizeWikiPath(path string) (string, bool) {
+	path = strings.ToLower(strings.Trim(path, "/"))
+	return path, len(path) <= 255 && wikiPathPattern.MatchString(path)
+}
+
+// WikiParentPath returns the path of the page above path, or "" for
+// top-level pages.
+func WikiParentPath(path string) string {
+	if i := strings.LastIndexByte(path, '/'); i != -1 {
+		return path[:i]
+	}
+	return ""
+}
+
+func IsWikiEditPermission(p string) bool {
+	return p == WikiEditModerators || p == WikiEditApproved || p == WikiEditKarma
+}
+
+const wikiPageColumns = `id, subreddit_id, parent_id, path, content, content_html, edit_permission,
+		min_karma, discussion_post_id, updated_by, created_at, updated_at`
+
+func scanWikiPage(row rowScanner) (*WikiPage, error) {
+	p := &WikiPage{}
+	err := row.Scan(&p.ID, &p.SubredditID, &p.ParentID, &p.Path, &p.Content, &p.ContentHTML, &p.EditPermission,
+		&p.MinKarma, &p.DiscussionPostID, &p.UpdatedBy, &p.CreatedAt, &p.UpdatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wiki page: %w", err)
+	}
+	return p, nil
+}
+
+// GetWikiPage returns the page at path in a subreddit's wiki, or nil if
+// there is none.
+func GetWikiPage(subredditID int, path string) (*WikiPage, error) {
+	return scanWikiPage(database.DB.QueryRow(`SELECT `+wikiPageColumns+` FROM wiki_pages
+		WHERE subreddit_id = $1 AND path = $2`, subredditID, path))
+}
+
+// GetWikiPageByID returns a wiki page, or nil if not found.
+func GetWikiPageByID(id int) (*WikiPage, error) {
+	return scanWikiPage(database.DB.QueryRow(`SELECT `+wikiPageColumns+` FROM wiki_pages WHERE id = $1`, id))
+}
+
+// ListWikiPages returns a subreddit's wiki index ordered by path, so every
+// page directly follows its parent.
+func ListWikiPages(subredditID int) ([]*WikiPageSummary, error) {
+	rows, err := database.DB.Query(`
+		SELECT id, parent_id, path, updated_at FROM wiki_pages
+		WHERE subreddit_id = $1 ORDER BY path
+	`, subredditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wiki pages: %w", err)
+	}
+	defer rows.Close()
+
+	pages := []*WikiPageSummary{}
+	for rows.Next() {
+		p := &WikiPageSummary{}
+		if err := rows.Scan(&p.ID, &p.ParentID, &p.Path, &p.UpdatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wiki page: %w", err)
+		}
+		pages = append(pages, p)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wiki pages: %w", err)
+	}
+
+	return pages, nil
+}
+
+// CanEditWikiPage reports whether userID may edit page. New pages are
+// checked against their parent; top-level pages are created by moderators.
+func CanEditWikiPage(page *WikiPage, userID int) (bool, error) {
+	isMod, err := IsSubredditModerator(page.SubredditID, userID)
+	if err != nil || isMod || page.EditPermission == WikiEditModerators {
+		return isMod, err
+	}
+
+	isEditor, err := IsWikiEditor(page.SubredditID, userID)
+	if err != nil || isEditor || page.EditPermission == WikiEditApproved {
+		return isEditor, err
+	}
+
+	karma, err := GetUserSubredditKarma(userID, page.SubredditID)
+	if err != nil {
+		return false, err
+	}
+	return karma >= page.MinKarma, nil
+}
+
+// CreateWikiPage creates page with content as its first revision. The
+// parent page, if the path has one, must exist; page.ParentID is set from
+// it.
+func CreateWikiPage(page *WikiPage, editorID int, reason *string) error {
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	page.ParentID = nil
+	if parentPath := WikiParentPath(page.Path); parentPath != "" {
+		var parentID int
+		err := tx.QueryRow(`SELECT id FROM wiki_pages WHERE subreddit_id = $1 AND path = $2 FOR SHARE`,
+			page.SubredditID, parentPath).Scan(&parentID)
+		if err == sql.ErrNoRows {
+			return ErrWikiParentMissing
+		}
+		if err != nil {
+			return fmt.Errorf("failed to get parent wiki page: %w", err)
+		}
+		page.ParentID = &parentID
+	}
+
+	page.ContentHTML = utils.RenderMarkdown(page.Content)
+	page.UpdatedBy = &editorID
+	err = tx.QueryRow(`
+		INSERT INTO wiki_pages (subreddit_id, parent_id, path, content, content_html, edit_permission, min_karma, updated_by)
+		VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+		RETURNING id, created_at, updated_at
+	`, page.SubredditID, page.ParentID, page.Path, page.Content, page.ContentHTML, page.EditPermission, page.MinKarma, editorID).
+		Scan(&page.ID, &page.CreatedAt, &page.UpdatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			return ErrWikiPageExists
+		}
+		return fmt.Errorf("failed to create wiki page: %w", err)
+	}
+
+	if err := recordWikiRevision(tx, page, editorID, reason, nil); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit wiki page: %w", err)
+	}
+	return nil
+}
+
+// EditWikiPage replaces the page's content and records it as a new
+// revision. revertedFrom names the revision being restored, if any.
+// Nothing is recorded when the content is unchanged.
+func EditWikiPage(page *WikiPage, content string, editorID int, reason *string, revertedFrom *int) error {
+	if content == page.Content {
+		return nil
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	page.Content = content
+	page.ContentHTML = utils.RenderMarkdown(content)
+	page.UpdatedBy = &editorID
+	err = tx.QueryRow(`
+		UPDATE wiki_pages SET content = $2, content_html = $3, updated_by = $4, updated_at = CURRENT_TIMESTAMP
+		WHERE id = $1
+		RETURNING updated_at
+	`, page.ID, page.Content, page.ContentHTML, editorID).Scan(&page.UpdatedAt)
+	if err != nil {
+		return fmt.Errorf("failed to update wiki page: %w", err)
+	}
+
+	if err := recordWikiRevision(tx, page, editorID, reason, revertedFrom); err != nil {
+		return err
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit wiki page: %w", err)
+	}
+	return nil
+}
+
+func recordWikiRevision(tx *sql.Tx, page *WikiPage, editorID int, reason *string, revertedFrom *int) error {
+	_, err := tx.Exec(`
+		INSERT INTO wiki_revisions (page_id, editor_id, content, reason, reverted_from)
+		VALUES ($1, $2, $3, $4, $5)
+	`, page.ID, editorID, page.Content, reason, revertedFrom)
+	if err != nil {
+		return fmt.Errorf("failed to record wiki revision: %w", err)
+	}
+	return nil
+}
+
+// UpdateWikiPageSettings saves the page's edit permission, karma threshold
+// and discussion post.
+func UpdateWikiPageSettings(page *WikiPage) error {
+	_, err := database.DB.Exec(`
+		UPDATE wiki_pages SET edit_permission = $2, min_karma = $3, discussion_post_id = $4
+		WHERE id = $1
+	`, page.ID, page.EditPermission, page.MinKarma, page.DiscussionPostID)
+	if err != nil {
+		return fmt.Errorf("failed to update wiki page settings: %w", err)
+	}
+	return nil
+}
+
+// DeleteWikiPage deletes a page and its history. Pages with subpages can't
+// be deleted.
+func DeleteWikiPage(id int) error {
+	_, err := database.DB.Exec(`DELETE FROM wiki_pages WHERE id = $1`, id)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23503" {
+			return ErrWikiPageHasChildren
+		}
+		return fmt.Errorf("failed to delete wiki page: %w", err)
+	}
+	return nil
+}
+
+// ListWikiRevisions returns a page's revisions, newest first, each with
+// the diff from the revision before it.
+func ListWikiRevisions(pageID, limit, offset int) ([]*WikiRevision, error) {
+	// One extra row gives the last revision on the page something to diff
+	// against.
+	rows, err := database.DB.Query(`
+		SELECT id, page_id, editor_id, content, reason, reverted_from, created_at
+		FROM wiki_revisions WHERE page_id = $1
+		ORDER BY id DESC
+		LIMIT $2 OFFSET $3
+	`, pageID, limit+1, offset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wiki revisions: %w", err)
+	}
+	defer rows.Close()
+
+	revisions := []*WikiRevision{}
+	for rows.Next() {
+		r := &WikiRevision{}
+		if err := rows.Scan(&r.ID, &r.PageID, &r.EditorID, &r.Content, &r.Reason, &r.RevertedFrom, &r.CreatedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wiki revision: %w", err)
+		}
+		revisions = append(revisions, r)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wiki revisions: %w", err)
+	}
+
+	for i, r := range revisions {
+		previous, from := "", "empty"
+		if i+1 < len(revisions) {
+			previous, from = revisions[i+1].Content, fmt.Sprintf("revision %d", revisions[i+1].ID)
+		}
+		r.Diff = utils.UnifiedDiff(from, fmt.Sprintf("revision %d", r.ID), previous, r.Content)
+	}
+	if len(revisions) > limit {
+		revisions = revisions[:limit]
+	}
+
+	return revisions, nil
+}
+
+// GetWikiRevision returns a revision of a page, or nil if the page has no
+// such revision.
+func GetWikiRevision(pageID, revisionID int) (*WikiRevision, error) {
+	r := &WikiRevision{}
+	err := database.DB.QueryRow(`
+		SELECT id, page_id, editor_id, content, reason, reverted_from, created_at
+		FROM wiki_revisions WHERE id = $1 AND page_id = $2
+	`, revisionID, pageID).Scan(&r.ID, &r.PageID, &r.EditorID, &r.Content, &r.Reason, &r.RevertedFrom, &r.CreatedAt)
+	if err == sql.ErrNoRows {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to get wiki revision: %w", err)
+	}
+	return r, nil
+}
+
+// AddWikiEditor approves userID to edit the subreddit's wiki pages that
+// allow approved editors. It is idempotent.
+func AddWikiEditor(subredditID, userID, addedBy int) error {
+	_, err := database.DB.Exec(`
+		INSERT INTO wiki_editors (subreddit_id, user_id, added_by) VALUES ($1, $2, $3)
+		ON CONFLICT (subreddit_id, user_id) DO NOTHING
+	`, subredditID, userID, addedBy)
+	if err != nil {
+		return fmt.Errorf("failed to add wiki editor: %w", err)
+	}
+	return nil
+}
+
+func RemoveWikiEditor(subredditID, userID int) error {
+	if _, err := database.DB.Exec(`DELETE FROM wiki_editors WHERE subreddit_id = $1 AND user_id = $2`, subredditID, userID); err != nil {
+		return fmt.Errorf("failed to remove wiki editor: %w", err)
+	}
+	return nil
+}
+
+func IsWikiEditor(subredditID, userID int) (bool, error) {
+	var isEditor bool
+	query := `SELECT EXISTS (SELECT 1 FROM wiki_editors WHERE subreddit_id = $1 AND user_id = $2)`
+	if err := database.DB.QueryRow(query, subredditID, userID).Scan(&isEditor); err != nil {
+		return false, fmt.Errorf("failed to check wiki editor: %w", err)
+	}
+	return isEditor, nil
+}
+
+// ListWikiEditors returns the subreddit's approved wiki editors, most
+// recently added first.
+func ListWikiEditors(subredditID int) ([]*WikiEditor, error) {
+	rows, err := database.DB.Query(`
+		SELECT u.id, u.username, u.avatar_url, e.created_at
+		FROM wiki_editors e
+		JOIN users u ON u.id = e.user_id
+		WHERE e.subreddit_id = $1
+		ORDER BY e.created_at DESC
+	`, subredditID)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list wiki editors: %w", err)
+	}
+	defer rows.Close()
+
+	editors := []*WikiEditor{}
+	for rows.Next() {
+		e := &WikiEditor{}
+		if err := rows.Scan(&e.ID, &e.Username, &e.AvatarURL, &e.AddedAt); err != nil {
+			return nil, fmt.Errorf("failed to scan wiki editor: %w", err)
+		}
+		editors = append(editors, e)
+	}
+
+	if err = rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating wiki editors: %w", err)
+	}
+
+	return editors, nil
+}
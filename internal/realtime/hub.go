@@ -0,0 +1,145 @@
+package realtime
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"log"
+	"sync"
+)
+
+// subscriberBuffer is how many events a slow subscriber may fall behind
+// before further events to it are dropped.
+const subscriberBuffer = 64
+
+type Event struct {
+	Topic string          `json:"topic"`
+	Type  string          `json:"type"`
+	Data  json.RawMessage `json:"data"`
+}
+
+// Hub is an in-process publish/subscribe broker keyed by topic. When a
+// forwarder is installed (see StartPostgresBridge) published events are
+// also fanned out to other instances.
+type Hub struct {
+	mu        sync.RWMutex
+	subs      map[string]map[*Subscription]struct{}
+	origin    string
+	forwarder func(origin string, e Event)
+}
+
+type Subscription struct {
+	C      chan Event
+	hub    *Hub
+	topics []string
+	once   sync.Once
+}
+
+// DefaultHub is the hub used by the package-level helpers.
+var DefaultHub = NewHub()
+
+func NewHub() *Hub {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return &Hub{
+		subs:   make(map[string]map[*Subscription]struct{}),
+		origin: hex.EncodeToString(b),
+	}
+}
+
+// Topic names
+func PostTopic(postID int) string           { return fmt.Sprintf("post:%d", postID) }
+func SubredditTopic(subredditID int) string { return fmt.Sprintf("subreddit:%d", subredditID) }
+func UserTopic(userID int) string           { return fmt.Sprintf("user:%d", userID) }
+
+// Subscribe returns a subscription receiving events for all given topics.
+// Callers must Close it when done.
+func (h *Hub) Subscribe(topics ...string) *Subscription {
+	s := &Subscription{
+		C:      make(chan Event, subscriberBuffer),
+		hub:    h,
+		topics: topics,
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	for _, t := range topics {
+		if h.subs[t] == nil {
+			h.subs[t] = make(map[*Subscription]struct{})
+		}
+		h.subs[t][s] = struct{}{}
+	}
+	return s
+}
+
+// Close unsubscribes and closes C.
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		h := s.hub
+		h.mu.Lock()
+		for _, t := range s.topics {
+			delete(h.subs[t], s)
+			if len(h.subs[t]) == 0 {
+				delete(h.subs, t)
+			}
+		}
+		h.mu.Unlock()
+		close(s.C)
+	})
+}
+
+func (s *Subscription) Topics() []string {
+	return s.topics
+}
+
+// SubscriberCount returns how many local subscriptions listen on topic.
+func (h *Hub) SubscriberCount(topic string) int {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return len(h.subs[topic])
+}
+
+// Publish delivers an event to local subscribers and, when configured, to
+// other instances.
+func (h *Hub) Publish(topic, eventType string, data any) {
+	payload, err := json.Marshal(data)
+	if err != nil {
+		log.Printf("realtime: failed to encode %s event: %v", eventType, err)
+		return
+	}
+	e := Event{Topic: topic, Type: eventType, Data: payload}
+
+	h.deliver(e)
+
+	h.mu.RLock()
+	forward := h.forwarder
+	h.mu.RUnlock()
+	if forward != nil {
+		forward(h.origin, e)
+	}
+}
+
+// deliver hands an event to local subscribers without blocking; events for
+// subscribers whose buffer is full are dropped.
+func (h *Hub) deliver(e Event) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	for s := range h.subs[e.Topic] {
+		select {
+		case s.C <- e:
+		default:
+		}
+	}
+}
+
+func (h *Hub) setForwarder(f func(origin string, e Event)) {
+	h.mu.Lock()
+	h.forwarder = f
+	h.mu.Unlock()
+}
+
+// Publish publishes on DefaultHub.
+func Publish(topic, eventType string, data any) {
+	DefaultHub.Publish(topic, eventType, data)
+}
@@ -0,0 +1,78 @@
+package realtime
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+// notifyChannel is the Postgres channel used to fan events out between
+// instances.
+const notifyChannel = "gosocial_events"
+
+// Postgres rejects NOTIFY payloads of 8000 bytes or more.
+const maxNotifyPayload = 7900
+
+type envelope struct {
+	Origin string `json:"origin"`
+	Event  Event  `json:"event"`
+}
+
+// StartPostgresBridge connects DefaultHub to other instances with
+// LISTEN/NOTIFY: events published here are sent with pg_notify, and events
+// from other instances are delivered to local subscribers.
+func StartPostgresBridge(connStr string) error {
+	return DefaultHub.startPostgresBridge(connStr)
+}
+
+func (h *Hub) startPostgresBridge(connStr string) error {
+	listener := pq.NewListener(connStr, 10*time.Second, time.Minute, func(ev pq.ListenerEventType, err error) {
+		if err != nil {
+			log.Printf("realtime: listener error: %v", err)
+		}
+	})
+	if err := listener.Listen(notifyChannel); err != nil {
+		listener.Close()
+		return fmt.Errorf("failed to listen on %s: %w", notifyChannel, err)
+	}
+
+	h.setForwarder(func(origin string, e Event) {
+		payload, err := json.Marshal(envelope{Origin: origin, Event: e})
+		if err != nil {
+			return
+		}
+		if len(payload) > maxNotifyPayload {
+			log.Printf("realtime: %s event on %s too large to fan out (%d bytes)", e.Type, e.Topic, len(payload))
+			return
+		}
+		if _, err := database.DB.Exec(`SELECT pg_notify($1, $2)`, notifyChannel, string(payload)); err != nil {
+			log.Printf("realtime: failed to notify: %v", err)
+		}
+	})
+
+	go func() {
+		for n := range listener.Notify {
+			// A nil notification means the connection was re-established;
+			// events sent while it was down are lost.
+			if n == nil {
+				continue
+			}
+			var env envelope
+			if err := json.Unmarshal([]byte(n.Extra), &env); err != nil {
+				log.Printf("realtime: bad notification payload: %v", err)
+				continue
+			}
+			if env.Origin == h.origin {
+				continue
+			}
+			h.deliver(env.Event)
+		}
+	}()
+
+	log.Println("✅ Realtime fan-out listening on Postgres channel", notifyChannel)
+	return nil
+}
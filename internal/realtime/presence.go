@@ -0,0 +1,195 @@
+package realtime
+
+import (
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/kshzz24/gosocial/internal/database"
+	"github.com/lib/pq"
+)
+
+// PresenceTTL is how long a viewer counts as active after its last
+// heartbeat. Clients should ping well within this window.
+const PresenceTTL = 2 * time.Minute
+
+// Presence tracks who is currently viewing each subreddit. Live counts are
+// served from memory; a flusher periodically publishes this instance's
+// counts to subreddit_presence, reads back the other instances' counts and
+// writes the totals to subreddits.active_users.
+type Presence struct {
+	mu      sync.Mutex
+	viewers map[int]map[string]time.Time // subreddit ID -> viewer key -> last seen
+	remote  map[int]int                  // subreddit ID -> viewers on other instances
+	origin  string
+}
+
+// DefaultPresence shares its instance ID with DefaultHub.
+var DefaultPresence = NewPresence(DefaultHub.origin)
+
+func NewPresence(origin string) *Presence {
+	return &Presence{
+		viewers: make(map[int]map[string]time.Time),
+		remote:  make(map[int]int),
+		origin:  origin,
+	}
+}
+
+// Touch records a heartbeat from viewer (a user or anonymous client key)
+// on a subreddit.
+func (p *Presence) Touch(subredditID int, viewer string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.viewers[subredditID] == nil {
+		p.viewers[subredditID] = make(map[string]time.Time)
+	}
+	p.viewers[subredditID][viewer] = time.Now()
+}
+
+// Count returns the live number of viewers on a subreddit across all
+// instances, as of this instance's last flush for the remote share.
+func (p *Presence) Count(subredditID int) int {
+	cutoff := time.Now().Add(-PresenceTTL)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	n := p.remote[subredditID]
+	for _, seen := range p.viewers[subredditID] {
+		if seen.After(cutoff) {
+			n++
+		}
+	}
+	return n
+}
+
+// sweep drops expired viewers and returns this instance's remaining counts.
+func (p *Presence) sweep() map[int]int {
+	cutoff := time.Now().Add(-PresenceTTL)
+
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	counts := make(map[int]int, len(p.viewers))
+	for id, viewers := range p.viewers {
+		for v, seen := range viewers {
+			if !seen.After(cutoff) {
+				delete(viewers, v)
+			}
+		}
+		if len(viewers) == 0 {
+			delete(p.viewers, id)
+			continue
+		}
+		counts[id] = len(viewers)
+	}
+	return counts
+}
+
+// Flush expires stale viewers and syncs counts with the database.
+func (p *Presence) Flush() error {
+	counts := p.sweep()
+
+	ids := make([]int64, 0, len(counts))
+	viewers := make([]int64, 0, len(counts))
+	for id, n := range counts {
+		ids = append(ids, int64(id))
+		viewers = append(viewers, int64(n))
+	}
+
+	tx, err := database.DB.Begin()
+	if err != nil {
+		return fmt.Errorf("failed to begin transaction: %w", err)
+	}
+	defer tx.Rollback()
+
+	// Replace this instance's rows and drop instances that stopped flushing.
+	_, err = tx.Exec(`
+		DELETE FROM subreddit_presence
+		WHERE instance_id = $1 OR updated_at < NOW() - $2 * INTERVAL '1 second'
+	`, p.origin, int((2 * PresenceTTL).Seconds()))
+	if err != nil {
+		return fmt.Errorf("failed to clear presence: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		INSERT INTO subreddit_presence (instance_id, subreddit_id, viewers)
+		SELECT $1, c.subreddit_id, c.viewers
+		FROM unnest($2::bigint[], $3::bigint[]) AS c(subreddit_id, viewers)
+		WHERE EXISTS (SELECT 1 FROM subreddits WHERE id = c.subreddit_id)
+	`, p.origin, pq.Array(ids), pq.Array(viewers))
+	if err != nil {
+		return fmt.Errorf("failed to record presence: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE subreddits s
+		SET active_users = t.viewers
+		FROM (
+			SELECT subreddit_id, SUM(viewers)::int AS viewers
+			FROM subreddit_presence
+			GROUP BY subreddit_id
+		) t
+		WHERE s.id = t.subreddit_id AND s.active_users IS DISTINCT FROM t.viewers
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to update active users: %w", err)
+	}
+
+	_, err = tx.Exec(`
+		UPDATE subreddits SET active_users = 0
+		WHERE active_users <> 0
+		  AND id NOT IN (SELECT subreddit_id FROM subreddit_presence)
+	`)
+	if err != nil {
+		return fmt.Errorf("failed to reset active users: %w", err)
+	}
+
+	rows, err := tx.Query(`
+		SELECT subreddit_id, SUM(viewers)
+		FROM subreddit_presence
+		WHERE instance_id <> $1
+		GROUP BY subreddit_id
+	`, p.origin)
+	if err != nil {
+		return fmt.Errorf("failed to read remote presence: %w", err)
+	}
+	defer rows.Close()
+
+	remote := make(map[int]int)
+	for rows.Next() {
+		var id, n int
+		if err := rows.Scan(&id, &n); err != nil {
+			return fmt.Errorf("failed to scan presence: %w", err)
+		}
+		remote[id] = n
+	}
+	if err = rows.Err(); err != nil {
+		return fmt.Errorf("error iterating presence: %w", err)
+	}
+
+	if err = tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit presence: %w", err)
+	}
+
+	p.mu.Lock()
+	p.remote = remote
+	p.mu.Unlock()
+	return nil
+}
+
+// Run flushes every interval until stop is closed. Errors are logged and
+// retried on the next tick.
+func (p *Presence) Run(interval time.Duration, stop <-chan struct{}) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+			if err := p.Flush(); err != nil {
+				log.Printf("realtime: presence flush failed: %v", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,55 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+)
+
+// numbered returns lines l1..ln, replacing line i with changed[i] if set.
+func numbered(n int, changed map[int]string) string {
+	var b strings.Builder
+	for i := 1; i <= n; i++ {
+		if line, ok := changed[i]; ok {
+			b.WriteString(line + "\n")
+		} else {
+			fmt.Fprintf(&b, "l%d\n", i)
+		}
+	}
+	return b.String()
+}
+
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b string
+		want string
+	}{
+		{"equal", "a\nb\n", "a\nb\n", ""},
+		{"delete line", "a\nb\nc\n", "a\nc\n", "--- a\n+++ b\n@@ -1,3 +1,2 @@\n a\n-b\n c\n"},
+		{"from empty", "", "a\n", "--- a\n+++ b\n@@ -0,0 +1 @@\n+a\n"},
+		{"to empty", "a\n", "", "--- a\n+++ b\n@@ -1 +0,0 @@\n-a\n"},
+		{"append", "a\nb\n", "a\nb\nc\n", "--- a\n+++ b\n@@ -1,2 +1,3 @@\n a\n b\n+c\n"},
+		{"replace", "x\n", "y\n", "--- a\n+++ b\n@@ -1 +1 @@\n-x\n+y\n"},
+		{
+			// 6 unchanged lines between changes: context overlaps, one hunk
+			"changes merge into one hunk",
+			numbered(20, nil), numbered(20, map[int]string{5: "X5", 12: "X12"}),
+			"--- a\n+++ b\n@@ -2,14 +2,14 @@\n l2\n l3\n l4\n-l5\n+X5\n l6\n l7\n l8\n l9\n l10\n l11\n-l12\n+X12\n l13\n l14\n l15\n",
+		},
+		{
+			// 7 unchanged lines between changes: two hunks
+			"changes split into two hunks",
+			numbered(20, nil), numbered(20, map[int]string{5: "X5", 13: "X13"}),
+			"--- a\n+++ b\n@@ -2,7 +2,7 @@\n l2\n l3\n l4\n-l5\n+X5\n l6\n l7\n l8\n" +
+				"@@ -10,7 +10,7 @@\n l10\n l11\n l12\n-l13\n+X13\n l14\n l15\n l16\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := UnifiedDiff("a", "b", tt.a, tt.b); got != tt.want {
+				t.Errorf("UnifiedDiff()\n got %q\nwant %q", got, tt.want)
+			}
+		})
+	}
+}
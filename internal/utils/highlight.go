@@ -0,0 +1,10 @@
+package utils
+
+import "strings"
+
+// HighlightHTML escapes text for HTML and wraps every region delimited by
+// the start and stop sentinels in <mark> tags.
+func HighlightHTML(text, start, stop string) string {
+	escaped := escapeHTML(text)
+	return strings.NewReplacer(start, "<mark>", stop, "</mark>").Replace(escaped)
+}
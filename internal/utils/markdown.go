@@ -0,0 +1,1258 @@
+package utils
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// RenderMarkdown converts user-submitted markdown into HTML that is safe to
+// embed directly in a page.
+//
+// The dialect is CommonMark blocks and inlines (paragraphs, headings, block
+// quotes, lists, code, thematic breaks, emphasis, links) plus the Reddit
+// extensions: >!spoilers!<, ^superscript / ^(superscript), ~~strikethrough~~,
+// pipe tables and u/username, r/subreddit autolinks.
+//
+// The output is sanitized by construction: raw HTML in the source is always
+// escaped, only the tags emitted by this renderer can appear, and link
+// targets are limited to http(s), mailto and site-relative URLs.
+func RenderMarkdown(src string) string {
+	src = strings.ReplaceAll(src, "\r\n", "\n")
+	src = strings.ReplaceAll(src, "\r", "\n")
+	src = strings.ReplaceAll(src, "\x00", "�")
+
+	lines := strings.Split(src, "\n")
+	for i, line := range lines {
+		lines[i] = expandTabs(line)
+	}
+	return strings.TrimSpace(renderBlocks(lines, false, 0))
+}
+
+// SanitizeURL returns a cleaned link target and whether it may be used in an
+// href. Only http, https and mailto schemes and scheme-less (relative) URLs
+// are allowed.
+func SanitizeURL(raw string) (string, bool) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", false
+	}
+	for _, r := range raw {
+		if r < 0x20 || r == 0x7f {
+			return "", false
+		}
+	}
+	raw = strings.ReplaceAll(raw, " ", "%20")
+
+	if colon := strings.IndexByte(raw, ':'); colon >= 0 {
+		if sep := strings.IndexAny(raw, "/?#"); sep == -1 || colon < sep {
+			switch strings.ToLower(raw[:colon]) {
+			case "http", "https", "mailto":
+			default:
+				return "", false
+			}
+		}
+	}
+	return raw, true
+}
+
+func expandTabs(line string) string {
+	if !strings.Contains(line, "\t") {
+		return line
+	}
+	var b strings.Builder
+	col := 0
+	for _, r := range line {
+		if r == '\t' {
+			n := 4 - col%4
+			b.WriteString(strings.Repeat(" ", n))
+			col += n
+			continue
+		}
+		b.WriteRune(r)
+		col++
+	}
+	return b.String()
+}
+
+// ---------------------------------------------------------------------------
+// Blocks
+// ---------------------------------------------------------------------------
+
+// maxNestingDepth caps how deeply block quotes and lists nest. Each level
+// re-renders its contents, so deeper markers are rendered as plain text to
+// keep the cost linear in the input.
+const maxNestingDepth = 16
+
+func renderBlocks(lines []string, tight bool, depth int) string {
+	var b strings.Builder
+	i := 0
+	for i < len(lines) {
+		line := lines[i]
+		switch {
+		case isBlank(line):
+			i++
+		case fenceOf(line) != "":
+			i = renderFencedCode(&b, lines, i)
+		case indentOf(line) >= 4:
+			i = renderIndentedCode(&b, lines, i)
+		case isATXHeading(line):
+			renderATXHeading(&b, line)
+			i++
+		case isThematicBreak(line):
+			b.WriteString("<hr>\n")
+			i++
+		case depth < maxNestingDepth && isBlockquoteLine(line):
+			i = renderBlockquote(&b, lines, i, depth)
+		case depth < maxNestingDepth && listMarkerOf(line) != nil:
+			i = renderList(&b, lines, i, depth)
+		case isTableStart(lines, i):
+			i = renderTable(&b, lines, i)
+		default:
+			i = renderParagraph(&b, lines, i, tight)
+		}
+	}
+	return b.String()
+}
+
+func isBlank(line string) bool {
+	return strings.TrimSpace(line) == ""
+}
+
+func indentOf(line string) int {
+	return len(line) - len(strings.TrimLeft(line, " "))
+}
+
+// startsBlock reports whether line would interrupt a paragraph.
+func startsBlock(line string) bool {
+	if indentOf(line) >= 4 {
+		return false
+	}
+	if fenceOf(line) != "" || isATXHeading(line) || isThematicBreak(line) || isBlockquoteLine(line) {
+		return true
+	}
+	if m := listMarkerOf(line); m != nil && !m.empty && (!m.ordered || m.start == 1) {
+		return true
+	}
+	return false
+}
+
+func fenceOf(line string) string {
+	if indentOf(line) >= 4 {
+		return ""
+	}
+	t := strings.TrimLeft(line, " ")
+	for _, ch := range []byte{'`', '~'} {
+		n := 0
+		for n < len(t) && t[n] == ch {
+			n++
+		}
+		if n >= 3 {
+			if ch == '`' && strings.ContainsRune(t[n:], '`') {
+				return ""
+			}
+			return t[:n]
+		}
+	}
+	return ""
+}
+
+func renderFencedCode(b *strings.Builder, lines []string, i int) int {
+	open := lines[i]
+	indent := indentOf(open)
+	fence := fenceOf(open)
+	info := strings.TrimSpace(strings.TrimLeft(open, " ")[len(fence):])
+	lang := ""
+	if fields := strings.Fields(info); len(fields) > 0 {
+		lang = sanitizeLanguage(fields[0])
+	}
+
+	var code []string
+	i++
+	for ; i < len(lines); i++ {
+		t := strings.TrimLeft(lines[i], " ")
+		if indentOf(lines[i]) < 4 && strings.HasPrefix(t, fence) && strings.Trim(t, fence[:1]+" ") == "" {
+			i++
+			break
+		}
+		line := lines[i]
+		strip := indent
+		if ind := indentOf(line); ind < strip {
+			strip = ind
+		}
+		code = append(code, line[strip:])
+	}
+
+	if lang != "" {
+		fmt.Fprintf(b, "<pre><code class=\"language-%s\">", lang)
+	} else {
+		b.WriteString("<pre><code>")
+	}
+	for _, line := range code {
+		b.WriteString(escapeHTML(line))
+		b.WriteByte('\n')
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+func sanitizeLanguage(lang string) string {
+	var b strings.Builder
+	for _, r := range lang {
+		if r < utf8.RuneSelf && (isAlnum(byte(r)) || r == '-' || r == '_' || r == '+' || r == '#') {
+			b.WriteRune(r)
+		}
+	}
+	return b.String()
+}
+
+func renderIndentedCode(b *strings.Builder, lines []string, i int) int {
+	var code []string
+	for i < len(lines) {
+		line := lines[i]
+		if isBlank(line) {
+			code = append(code, "")
+			i++
+			continue
+		}
+		if indentOf(line) < 4 {
+			break
+		}
+		code = append(code, line[4:])
+		i++
+	}
+	for len(code) > 0 && code[len(code)-1] == "" {
+		code = code[:len(code)-1]
+	}
+
+	b.WriteString("<pre><code>")
+	for _, line := range code {
+		b.WriteString(escapeHTML(line))
+		b.WriteByte('\n')
+	}
+	b.WriteString("</code></pre>\n")
+	return i
+}
+
+func isATXHeading(line string) bool {
+	if indentOf(line) >= 4 {
+		return false
+	}
+	t := strings.TrimLeft(line, " ")
+	n := 0
+	for n < len(t) && t[n] == '#' {
+		n++
+	}
+	return n >= 1 && n <= 6 && (n == len(t) || t[n] == ' ')
+}
+
+func renderATXHeading(b *strings.Builder, line string) {
+	t := strings.TrimLeft(line, " ")
+	level := 0
+	for level < len(t) && t[level] == '#' {
+		level++
+	}
+	text := strings.TrimSpace(t[level:])
+
+	// An optional closing sequence of #s is not part of the heading.
+	if trimmed := strings.TrimRight(text, "#"); trimmed != text {
+		if trimmed == "" || strings.HasSuffix(trimmed, " ") {
+			text = strings.TrimSpace(trimmed)
+		}
+	}
+	fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderInline(text), level)
+}
+
+func isThematicBreak(line string) bool {
+	if indentOf(line) >= 4 {
+		return false
+	}
+	t := strings.TrimSpace(line)
+	if t == "" {
+		return false
+	}
+	ch := t[0]
+	if ch != '-' && ch != '*' && ch != '_' {
+		return false
+	}
+	n := 0
+	for i := 0; i < len(t); i++ {
+		switch t[i] {
+		case ch:
+			n++
+		case ' ':
+		default:
+			return false
+		}
+	}
+	return n >= 3
+}
+
+func setextLevel(line string) int {
+	if indentOf(line) >= 4 {
+		return 0
+	}
+	t := strings.TrimSpace(line)
+	if t == "" {
+		return 0
+	}
+	if strings.Trim(t, "=") == "" {
+		return 1
+	}
+	if strings.Trim(t, "-") == "" {
+		return 2
+	}
+	return 0
+}
+
+// isBlockquoteLine reports whether line starts a block quote. A line that
+// opens with a Reddit spoiler (">!...!<") is a paragraph, not a quote.
+func isBlockquoteLine(line string) bool {
+	if indentOf(line) >= 4 {
+		return false
+	}
+	t := strings.TrimLeft(line, " ")
+	if !strings.HasPrefix(t, ">") {
+		return false
+	}
+	if strings.HasPrefix(t, ">!") && strings.Contains(t[2:], "!<") {
+		return false
+	}
+	return true
+}
+
+func stripBlockquote(line string) string {
+	t := strings.TrimLeft(line, " ")
+	t = strings.TrimPrefix(t, ">")
+	return strings.TrimPrefix(t, " ")
+}
+
+func renderBlockquote(b *strings.Builder, lines []string, i, depth int) int {
+	var inner []string
+	for i < len(lines) {
+		line := lines[i]
+		if isBlockquoteLine(line) {
+			inner = append(inner, stripBlockquote(line))
+			i++
+			continue
+		}
+		// Lazy continuation of a quoted paragraph.
+		if !isBlank(line) && len(inner) > 0 && !isBlank(inner[len(inner)-1]) && !startsBlock(line) {
+			inner = append(inner, line)
+			i++
+			continue
+		}
+		break
+	}
+	b.WriteString("<blockquote>\n")
+	b.WriteString(renderBlocks(inner, false, depth+1))
+	b.WriteString("</blockquote>\n")
+	return i
+}
+
+type listMarker struct {
+	ordered bool
+	bullet  byte // '-', '+', '*' or the ordered delimiter '.' / ')'
+	start   int
+	indent  int // column at which item content starts
+	empty   bool
+}
+
+func listMarkerOf(line string) *listMarker {
+	indent := indentOf(line)
+	if indent >= 4 {
+		return nil
+	}
+	t := line[indent:]
+	if t == "" {
+		return nil
+	}
+
+	m := &listMarker{}
+	width := 0
+	switch t[0] {
+	case '-', '+', '*':
+		m.bullet = t[0]
+		width = 1
+	default:
+		n := 0
+		for n < len(t) && n < 9 && t[n] >= '0' && t[n] <= '9' {
+			n++
+		}
+		if n == 0 || n >= len(t) || (t[n] != '.' && t[n] != ')') {
+			return nil
+		}
+		m.ordered = true
+		m.bullet = t[n]
+		m.start, _ = strconv.Atoi(t[:n])
+		width = n + 1
+	}
+
+	rest := t[width:]
+	if rest != "" && rest[0] != ' ' {
+		return nil
+	}
+	spaces := len(rest) - len(strings.TrimLeft(rest, " "))
+	m.empty = strings.TrimSpace(rest) == ""
+	if spaces == 0 || spaces > 4 || m.empty {
+		spaces = 1
+	}
+	m.indent = indent + width + spaces
+	return m
+}
+
+func sameListType(a, b *listMarker) bool {
+	return a.ordered == b.ordered && a.bullet == b.bullet
+}
+
+func renderList(b *strings.Builder, lines []string, i, depth int) int {
+	first := listMarkerOf(lines[i])
+	var items [][]string
+	loose := false
+
+	for i < len(lines) {
+		m := listMarkerOf(lines[i])
+		if m == nil || !sameListType(first, m) || isThematicBreak(lines[i]) {
+			break
+		}
+
+		item := []string{""}
+		if len(lines[i]) > m.indent {
+			item[0] = lines[i][m.indent:]
+		}
+		i++
+
+		sawBlank := false
+		for i < len(lines) {
+			line := lines[i]
+			if isBlank(line) {
+				sawBlank = true
+				item = append(item, "")
+				i++
+				continue
+			}
+			if indentOf(line) >= m.indent {
+				if sawBlank {
+					loose = true
+				}
+				sawBlank = false
+				item = append(item, line[m.indent:])
+				i++
+				continue
+			}
+			if !sawBlank && !startsBlock(line) && listMarkerOf(line) == nil {
+				item = append(item, strings.TrimLeft(line, " "))
+				i++
+				continue
+			}
+			break
+		}
+
+		// Trailing blank lines separate items; they only make the list
+		// loose if another item follows.
+		trailing := 0
+		for len(item) > 1 && item[len(item)-1] == "" {
+			item = item[:len(item)-1]
+			trailing++
+		}
+		items = append(items, item)
+
+		if trailing > 0 {
+			if i < len(lines) {
+				if next := listMarkerOf(lines[i]); next != nil && sameListType(first, next) {
+					loose = true
+					continue
+				}
+			}
+			break
+		}
+	}
+
+	tag := "ul"
+	if first.ordered {
+		tag = "ol"
+	}
+	if first.ordered && first.start != 1 {
+		fmt.Fprintf(b, "<ol start=\"%d\">\n", first.start)
+	} else {
+		fmt.Fprintf(b, "<%s>\n", tag)
+	}
+	for _, item := range items {
+		body := renderBlocks(item, !loose, depth+1)
+		if !loose {
+			body = strings.TrimSuffix(body, "\n")
+		}
+		fmt.Fprintf(b, "<li>%s</li>\n", body)
+	}
+	fmt.Fprintf(b, "</%s>\n", tag)
+	return i
+}
+
+func renderParagraph(b *strings.Builder, lines []string, i int, tight bool) int {
+	var para []string
+	for i < len(lines) {
+		line := lines[i]
+		if isBlank(line) {
+			break
+		}
+		if len(para) > 0 {
+			if level := setextLevel(line); level > 0 {
+				text := strings.TrimSpace(strings.Join(para, "\n"))
+				fmt.Fprintf(b, "<h%d>%s</h%d>\n", level, renderInline(text), level)
+				return i + 1
+			}
+			if startsBlock(line) || isTableStart(lines, i) {
+				break
+			}
+		}
+		para = append(para, strings.TrimLeft(line, " "))
+		i++
+	}
+
+	text := strings.TrimRight(strings.Join(para, "\n"), " ")
+	if tight {
+		b.WriteString(renderInline(text))
+		b.WriteByte('\n')
+	} else {
+		fmt.Fprintf(b, "<p>%s</p>\n", renderInline(text))
+	}
+	return i
+}
+
+// ---------------------------------------------------------------------------
+// Tables
+// ---------------------------------------------------------------------------
+
+func isTableStart(lines []string, i int) bool {
+	if i+1 >= len(lines) || !strings.Contains(lines[i], "|") || indentOf(lines[i]) >= 4 {
+		return false
+	}
+	aligns := tableAlignments(lines[i+1])
+	return aligns != nil && len(aligns) == len(splitTableRow(lines[i]))
+}
+
+func tableAlignments(line string) []string {
+	if !strings.Contains(line, "-") {
+		return nil
+	}
+	cells := splitTableRow(line)
+	aligns := make([]string, len(cells))
+	for n, cell := range cells {
+		if cell == "" || strings.Trim(cell, ":-") != "" || !strings.Contains(cell, "-") {
+			return nil
+		}
+		left := strings.HasPrefix(cell, ":")
+		right := strings.HasSuffix(cell, ":")
+		switch {
+		case left && right:
+			aligns[n] = "center"
+		case left:
+			aligns[n] = "left"
+		case right:
+			aligns[n] = "right"
+		}
+	}
+	return aligns
+}
+
+// splitTableRow splits a pipe table row into trimmed cells, honouring
+// backslash-escaped pipes and pipes inside code spans.
+func splitTableRow(line string) []string {
+	t := strings.TrimSpace(line)
+	t = strings.TrimPrefix(t, "|")
+	if strings.HasSuffix(t, "|") && !strings.HasSuffix(t, "\\|") {
+		t = t[:len(t)-1]
+	}
+
+	var cells []string
+	var cur strings.Builder
+	inCode := false
+	for i := 0; i < len(t); i++ {
+		c := t[i]
+		switch {
+		case c == '\\' && i+1 < len(t) && t[i+1] == '|':
+			cur.WriteByte('|')
+			i++
+		case c == '`':
+			inCode = !inCode
+			cur.WriteByte(c)
+		case c == '|' && !inCode:
+			cells = append(cells, strings.TrimSpace(cur.String()))
+			cur.Reset()
+		default:
+			cur.WriteByte(c)
+		}
+	}
+	return append(cells, strings.TrimSpace(cur.String()))
+}
+
+func renderTable(b *strings.Builder, lines []string, i int) int {
+	header := splitTableRow(lines[i])
+	aligns := tableAlignments(lines[i+1])
+	i += 2
+
+	writeRow := func(cells []string, tag string) {
+		b.WriteString("<tr>")
+		for n, align := range aligns {
+			cell := ""
+			if n < len(cells) {
+				cell = cells[n]
+			}
+			if align != "" {
+				fmt.Fprintf(b, "<%s align=\"%s\">%s</%s>", tag, align, renderInline(cell), tag)
+			} else {
+				fmt.Fprintf(b, "<%s>%s</%s>", tag, renderInline(cell), tag)
+			}
+		}
+		b.WriteString("</tr>\n")
+	}
+
+	b.WriteString("<table>\n<thead>\n")
+	writeRow(header, "th")
+	b.WriteString("</thead>\n")
+
+	body := false
+	for i < len(lines) && !isBlank(lines[i]) && strings.Contains(lines[i], "|") && !startsBlock(lines[i]) {
+		if !body {
+			b.WriteString("<tbody>\n")
+			body = true
+		}
+		writeRow(splitTableRow(lines[i]), "td")
+		i++
+	}
+	if body {
+		b.WriteString("</tbody>\n")
+	}
+	b.WriteString("</table>\n")
+	return i
+}
+
+// ---------------------------------------------------------------------------
+// Inlines
+// ---------------------------------------------------------------------------
+
+// inlineNode is either pre-rendered safe HTML or a run of emphasis
+// delimiters (*, _ or ~) waiting to be matched.
+type inlineNode struct {
+	html      string
+	delim     byte
+	count     int
+	origCount int
+	canOpen   bool
+	canClose  bool
+	openTags  string
+	closeTags string
+}
+
+type inlineParser struct {
+	src     string
+	noLinks bool
+	nodes   []*inlineNode
+	text    strings.Builder
+}
+
+func renderInline(s string) string {
+	p := &inlineParser{src: s}
+	return p.render()
+}
+
+func renderInlineNoLinks(s string) string {
+	p := &inlineParser{src: s, noLinks: true}
+	return p.render()
+}
+
+func (p *inlineParser) render() string {
+	p.parse()
+	p.processEmphasis()
+
+	var b strings.Builder
+	for _, n := range p.nodes {
+		if n.delim == 0 {
+			b.WriteString(n.html)
+			continue
+		}
+		b.WriteString(n.closeTags)
+		b.WriteString(strings.Repeat(string(n.delim), n.count))
+		b.WriteString(n.openTags)
+	}
+	return b.String()
+}
+
+func (p *inlineParser) flush() {
+	if p.text.Len() > 0 {
+		p.nodes = append(p.nodes, &inlineNode{html: escapeHTML(p.text.String())})
+		p.text.Reset()
+	}
+}
+
+func (p *inlineParser) emit(html string) {
+	p.flush()
+	p.nodes = append(p.nodes, &inlineNode{html: html})
+}
+
+func (p *inlineParser) parse() {
+	s := p.src
+	i := 0
+	for i < len(s) {
+		c := s[i]
+		switch c {
+		case '\\':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				p.emit("<br>\n")
+				i += 2
+				continue
+			}
+			if i+1 < len(s) && isASCIIPunct(s[i+1]) {
+				p.text.WriteByte(s[i+1])
+				i += 2
+				continue
+			}
+		case '\n':
+			raw := p.text.String()
+			trimmed := strings.TrimRight(raw, " ")
+			p.text.Reset()
+			p.text.WriteString(trimmed)
+			if len(raw)-len(trimmed) >= 2 {
+				p.emit("<br>\n")
+			} else {
+				p.emit("\n")
+			}
+			i++
+			for i < len(s) && s[i] == ' ' {
+				i++
+			}
+			continue
+		case '`':
+			if end, ok := p.codeSpan(i); ok {
+				i = end
+				continue
+			}
+			n := runLength(s, i, '`')
+			p.text.WriteString(s[i : i+n])
+			i += n
+			continue
+		case '*', '_', '~':
+			i = p.delimiterRun(i)
+			continue
+		case '>':
+			if end, ok := p.spoiler(i); ok {
+				i = end
+				continue
+			}
+		case '^':
+			if end, ok := p.superscript(i); ok {
+				i = end
+				continue
+			}
+		case '[':
+			if !p.noLinks {
+				if end, ok := p.link(i, false); ok {
+					i = end
+					continue
+				}
+			}
+		case '!':
+			if !p.noLinks && i+1 < len(s) && s[i+1] == '[' {
+				if end, ok := p.link(i+1, true); ok {
+					i = end
+					continue
+				}
+			}
+		case '<':
+			if !p.noLinks {
+				if end, ok := p.angleAutolink(i); ok {
+					i = end
+					continue
+				}
+			}
+		case '&':
+			if end, ok := entityAt(s, i); ok {
+				p.flush()
+				p.nodes = append(p.nodes, &inlineNode{html: s[i:end]})
+				i = end
+				continue
+			}
+		case 'h', 'H':
+			if !p.noLinks {
+				if end, ok := p.bareURL(i); ok {
+					i = end
+					continue
+				}
+			}
+		case 'u', 'r', '/':
+			if !p.noLinks {
+				if end, ok := p.redditLink(i); ok {
+					i = end
+					continue
+				}
+			}
+		}
+		p.text.WriteByte(c)
+		i++
+	}
+	p.flush()
+}
+
+func runLength(s string, i int, c byte) int {
+	n := 0
+	for i+n < len(s) && s[i+n] == c {
+		n++
+	}
+	return n
+}
+
+func (p *inlineParser) codeSpan(i int) (int, bool) {
+	s := p.src
+	n := runLength(s, i, '`')
+	for j := i + n; j < len(s); {
+		if s[j] != '`' {
+			j++
+			continue
+		}
+		m := runLength(s, j, '`')
+		if m == n {
+			code := strings.ReplaceAll(s[i+n:j], "\n", " ")
+			if len(code) >= 2 && code[0] == ' ' && code[len(code)-1] == ' ' && strings.Trim(code, " ") != "" {
+				code = code[1 : len(code)-1]
+			}
+			p.emit("<code>" + escapeHTML(code) + "</code>")
+			return j + m, true
+		}
+		j += m
+	}
+	return 0, false
+}
+
+func (p *inlineParser) delimiterRun(i int) int {
+	s := p.src
+	c := s[i]
+	n := runLength(s, i, c)
+
+	before, _ := utf8.DecodeLastRuneInString(s[:i])
+	if i == 0 {
+		before = ' '
+	}
+	after, _ := utf8.DecodeRuneInString(s[i+n:])
+	if i+n >= len(s) {
+		after = ' '
+	}
+
+	leftFlanking := !unicode.IsSpace(after) && (!isPunctRune(after) || unicode.IsSpace(before) || isPunctRune(before))
+	rightFlanking := !unicode.IsSpace(before) && (!isPunctRune(before) || unicode.IsSpace(after) || isPunctRune(after))
+
+	node := &inlineNode{delim: c, count: n, origCount: n}
+	if c == '_' {
+		node.canOpen = leftFlanking && (!rightFlanking || isPunctRune(before))
+		node.canClose = rightFlanking && (!leftFlanking || isPunctRune(after))
+	} else {
+		node.canOpen = leftFlanking
+		node.canClose = rightFlanking
+	}
+
+	p.flush()
+	p.nodes = append(p.nodes, node)
+	return i + n
+}
+
+// processEmphasis pairs delimiter runs following the CommonMark algorithm.
+// Matched delimiters become tags on the opener and closer nodes; anything
+// left over is rendered as literal text.
+func (p *inlineParser) processEmphasis() {
+	type bottomKey struct {
+		c       byte
+		canOpen bool
+		mod     int
+	}
+	bottom := map[bottomKey]int{}
+
+	for ci := 0; ci < len(p.nodes); ci++ {
+		closer := p.nodes[ci]
+		if closer.delim == 0 || !closer.canClose {
+			continue
+		}
+		for closer.count > 0 {
+			key := bottomKey{closer.delim, closer.canOpen, closer.origCount % 3}
+			floor, ok := bottom[key]
+			if !ok {
+				floor = -1
+			}
+
+			oi := -1
+			for j := ci - 1; j > floor; j-- {
+				opener := p.nodes[j]
+				if opener.delim != closer.delim || !opener.canOpen || opener.count == 0 {
+					continue
+				}
+				if closer.delim == '~' && (opener.count < 2 || closer.count < 2) {
+					continue
+				}
+				if (opener.canClose || closer.canOpen) &&
+					(opener.origCount+closer.origCount)%3 == 0 &&
+					!(opener.origCount%3 == 0 && closer.origCount%3 == 0) {
+					continue
+				}
+				oi = j
+				break
+			}
+			if oi == -1 {
+				bottom[key] = ci - 1
+				break
+			}
+
+			opener := p.nodes[oi]
+			use := 1
+			tag := "em"
+			switch {
+			case closer.delim == '~':
+				use, tag = 2, "del"
+			case opener.count >= 2 && closer.count >= 2:
+				use, tag = 2, "strong"
+			}
+			opener.count -= use
+			closer.count -= use
+			opener.openTags = "<" + tag + ">" + opener.openTags
+			closer.closeTags += "</" + tag + ">"
+
+			// Delimiters between a matched pair can no longer match.
+			for j := oi + 1; j < ci; j++ {
+				if p.nodes[j].delim != 0 {
+					p.nodes[j].canOpen = false
+					p.nodes[j].canClose = false
+				}
+			}
+		}
+	}
+}
+
+// spoiler renders Reddit's >!hidden text!< syntax.
+func (p *inlineParser) spoiler(i int) (int, bool) {
+	s := p.src
+	if !strings.HasPrefix(s[i:], ">!") {
+		return 0, false
+	}
+	end := strings.Index(s[i+2:], "!<")
+	if end <= 0 {
+		return 0, false
+	}
+	inner := s[i+2 : i+2+end]
+	p.emit("<span class=\"md-spoiler\">" + p.renderNested(inner) + "</span>")
+	return i + 2 + end + 2, true
+}
+
+// superscript renders ^word and ^(a phrase).
+func (p *inlineParser) superscript(i int) (int, bool) {
+	s := p.src
+	if i+1 >= len(s) {
+		return 0, false
+	}
+	if s[i+1] == '(' {
+		depth := 0
+		for j := i + 1; j < len(s); j++ {
+			switch s[j] {
+			case '(':
+				depth++
+			case ')':
+				depth--
+				if depth == 0 {
+					if j == i+2 {
+						return 0, false
+					}
+					p.emit("<sup>" + p.renderNested(s[i+2:j]) + "</sup>")
+					return j + 1, true
+				}
+			case '\n':
+				return 0, false
+			}
+		}
+		return 0, false
+	}
+
+	j := i + 1
+	for j < len(s) && s[j] != ' ' && s[j] != '\n' {
+		j++
+	}
+	if j == i+1 {
+		return 0, false
+	}
+	p.emit("<sup>" + p.renderNested(s[i+1:j]) + "</sup>")
+	return j, true
+}
+
+func (p *inlineParser) renderNested(s string) string {
+	nested := &inlineParser{src: s, noLinks: p.noLinks}
+	return nested.render()
+}
+
+// link parses [label](destination "title") starting at the opening bracket.
+// Images are rendered as plain links to their source rather than embedded.
+func (p *inlineParser) link(i int, image bool) (int, bool) {
+	s := p.src
+	depth := 0
+	close := -1
+	for j := i; j < len(s) && close == -1; j++ {
+		switch s[j] {
+		case '\\':
+			j++
+		case '[':
+			depth++
+		case ']':
+			depth--
+			if depth == 0 {
+				close = j
+			}
+		}
+	}
+	if close == -1 || close+1 >= len(s) || s[close+1] != '(' {
+		return 0, false
+	}
+
+	j := close + 2
+	for j < len(s) && (s[j] == ' ' || s[j] == '\n') {
+		j++
+	}
+
+	var dest string
+	if j < len(s) && s[j] == '<' {
+		end := strings.IndexAny(s[j+1:], ">\n")
+		if end == -1 || s[j+1+end] != '>' {
+			return 0, false
+		}
+		dest = s[j+1 : j+1+end]
+		j += end + 2
+	} else {
+		start := j
+		parens := 0
+		for j < len(s) {
+			c := s[j]
+			if c == '\\' && j+1 < len(s) && isASCIIPunct(s[j+1]) {
+				j += 2
+				continue
+			}
+			if c == ' ' || c == '\n' || c < 0x20 {
+				break
+			}
+			if c == '(' {
+				parens++
+			}
+			if c == ')' {
+				if parens == 0 {
+					break
+				}
+				parens--
+			}
+			j++
+		}
+		dest = s[start:j]
+	}
+
+	for j < len(s) && (s[j] == ' ' || s[j] == '\n') {
+		j++
+	}
+	title := ""
+	if j < len(s) && (s[j] == '"' || s[j] == '\'' || s[j] == '(') {
+		closer := s[j]
+		if closer == '(' {
+			closer = ')'
+		}
+		end := strings.IndexByte(s[j+1:], closer)
+		if end == -1 {
+			return 0, false
+		}
+		title = s[j+1 : j+1+end]
+		j += end + 2
+		for j < len(s) && (s[j] == ' ' || s[j] == '\n') {
+			j++
+		}
+	}
+	if j >= len(s) || s[j] != ')' {
+		return 0, false
+	}
+
+	label := s[i+1 : close]
+	var inner string
+	if image {
+		inner = escapeHTML(label)
+	} else {
+		inner = renderInlineNoLinks(label)
+	}
+
+	href, ok := SanitizeURL(unescapeBackslashes(dest))
+	if !ok {
+		p.emit(inner)
+		return j + 1, true
+	}
+	if title != "" {
+		p.emit(fmt.Sprintf("<a href=\"%s\" title=\"%s\" rel=\"nofollow ugc\">%s</a>",
+			escapeHTML(href), escapeHTML(unescapeBackslashes(title)), inner))
+	} else {
+		p.emit(fmt.Sprintf("<a href=\"%s\" rel=\"nofollow ugc\">%s</a>", escapeHTML(href), inner))
+	}
+	return j + 1, true
+}
+
+// angleAutolink renders <https://example.com> and <user@example.com>.
+func (p *inlineParser) angleAutolink(i int) (int, bool) {
+	s := p.src
+	end := strings.IndexAny(s[i+1:], "<> \n")
+	if end <= 0 || s[i+1+end] != '>' {
+		return 0, false
+	}
+	target := s[i+1 : i+1+end]
+
+	href := target
+	if !strings.Contains(target, ":") && strings.Contains(target, "@") {
+		href = "mailto:" + target
+	}
+	if !strings.Contains(href, ":") {
+		return 0, false
+	}
+	href, ok := SanitizeURL(href)
+	if !ok {
+		return 0, false
+	}
+	p.emit(fmt.Sprintf("<a href=\"%s\" rel=\"nofollow ugc\">%s</a>", escapeHTML(href), escapeHTML(target)))
+	return i + 1 + end + 1, true
+}
+
+// bareURL autolinks http:// and https:// URLs written without brackets.
+func (p *inlineParser) bareURL(i int) (int, bool) {
+	s := p.src
+	if i > 0 && isAlnum(s[i-1]) {
+		return 0, false
+	}
+	lower := strings.ToLower(s[i:min(len(s), i+8)])
+	if !strings.HasPrefix(lower, "http://") && !strings.HasPrefix(lower, "https://") {
+		return 0, false
+	}
+
+	j := i
+	for j < len(s) && s[j] > ' ' && s[j] != '<' {
+		j++
+	}
+	// Trailing punctuation usually belongs to the sentence, not the URL.
+	for j > i {
+		c := s[j-1]
+		if strings.IndexByte(".,:;!?\"'*_~", c) >= 0 {
+			j--
+			continue
+		}
+		if c == ')' && strings.Count(s[i:j], "(") < strings.Count(s[i:j], ")") {
+			j--
+			continue
+		}
+		break
+	}
+
+	raw := s[i:j]
+	if k := strings.Index(raw, "://"); k == -1 || k+3 == len(raw) {
+		return 0, false
+	}
+	href, ok := SanitizeURL(raw)
+	if !ok {
+		return 0, false
+	}
+	p.emit(fmt.Sprintf("<a href=\"%s\" rel=\"nofollow ugc\">%s</a>", escapeHTML(href), escapeHTML(raw)))
+	return j, true
+}
+
+// redditLink autolinks u/username and r/subreddit, with or without a
+// leading slash.
+func (p *inlineParser) redditLink(i int) (int, bool) {
+	s := p.src
+	start := i
+	if s[i] == '/' {
+		i++
+	}
+	if i+2 > len(s) || (s[i] != 'u' && s[i] != 'r') || s[i+1] != '/' {
+		return 0, false
+	}
+	if start > 0 {
+		prev := s[start-1]
+		if isAlnum(prev) || prev == '_' || (prev == '/' && start == i) {
+			return 0, false
+		}
+	}
+
+	kind := s[i]
+	j := i + 2
+	for j < len(s) && (isAlnum(s[j]) || s[j] == '_' || (kind == 'u' && s[j] == '-')) {
+		j++
+	}
+	name := s[i+2 : j]
+	if len(name) < 3 || len(name) > 50 {
+		return 0, false
+	}
+
+	href := fmt.Sprintf("/%c/%s", kind, name)
+	p.emit(fmt.Sprintf("<a href=\"%s\">%s</a>", escapeHTML(href), escapeHTML(s[start:j])))
+	return j, true
+}
+
+// entityAt recognises HTML entity and numeric character references so they
+// pass through unchanged. They cannot introduce markup.
+func entityAt(s string, i int) (int, bool) {
+	j := i + 1
+	if j < len(s) && s[j] == '#' {
+		j++
+	}
+	start := j
+	for j < len(s) && j-start <= 10 && isAlnum(s[j]) {
+		j++
+	}
+	if j == start || j >= len(s) || s[j] != ';' {
+		return 0, false
+	}
+	return j + 1, true
+}
+
+func unescapeBackslashes(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && isASCIIPunct(s[i+1]) {
+			i++
+		}
+		b.WriteByte(s[i])
+	}
+	return b.String()
+}
+
+func escapeHTML(s string) string {
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		switch s[i] {
+		case '&':
+			b.WriteString("&amp;")
+		case '<':
+			b.WriteString("&lt;")
+		case '>':
+			b.WriteString("&gt;")
+		case '"':
+			b.WriteString("&quot;")
+		case '\'':
+			b.WriteString("&#39;")
+		default:
+			b.WriteByte(s[i])
+		}
+	}
+	return b.String()
+}
+
+func isAlnum(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9')
+}
+
+func isASCIIPunct(c byte) bool {
+	return strings.IndexByte("!\"#$%&'()*+,-./:;<=>?@[\\]^_`{|}~", c) >= 0
+}
+
+func isPunctRune(r rune) bool {
+	if r < utf8.RuneSelf {
+		return isASCIIPunct(byte(r))
+	}
+	return unicode.IsPunct(r) || unicode.IsSymbol(r)
+}
@@ -0,0 +1,132 @@
+package utils
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestRenderMarkdown(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"emphasis", "hello *world*", "<p>hello <em>world</em></p>"},
+		{"strong and em", "**bold** and _em_", "<p><strong>bold</strong> and <em>em</em></p>"},
+		{"triple delimiters", "***both***", "<p><em><strong>both</strong></em></p>"},
+		{"strong inside em", "*a **b** c*", "<p><em>a <strong>b</strong> c</em></p>"},
+		{"em inside strong", "**a *b* c**", "<p><strong>a <em>b</em> c</strong></p>"},
+		{"unclosed emphasis", "*unclosed", "<p>*unclosed</p>"},
+		{"link", "[ok](https://example.com/a)", `<p><a href="https://example.com/a" rel="nofollow ugc">ok</a></p>`},
+		{"relative link", "[rel](/r/golang)", `<p><a href="/r/golang" rel="nofollow ugc">rel</a></p>`},
+		{"mailto link", "[x](mailto:a@b.c)", `<p><a href="mailto:a@b.c" rel="nofollow ugc">x</a></p>`},
+		{"reddit links", "u/alice and r/golang", `<p><a href="/u/alice">u/alice</a> and <a href="/r/golang">r/golang</a></p>`},
+		{"spoiler", ">!spoiler!<", `<p><span class="md-spoiler">spoiler</span></p>`},
+		{"code span", "`<b>`", "<p><code>&lt;b&gt;</code></p>"},
+		{"heading escapes html", "# Heading <i>", "<h1>Heading &lt;i&gt;</h1>"},
+		{"special characters", `a & b < c > d " e '`, "<p>a &amp; b &lt; c &gt; d &quot; e &#39;</p>"},
+		{"entities kept", "&amp; &lt;", "<p>&amp; &lt;</p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := RenderMarkdown(tt.src); got != tt.want {
+				t.Errorf("RenderMarkdown(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRenderMarkdownXSS(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want string
+	}{
+		{"javascript link", "[x](javascript:alert(1))", "<p>x</p>"},
+		{"mixed case scheme", "[x](JaVaScRiPt:alert(1))", "<p>x</p>"},
+		{"leading spaces", "[x](  javascript:alert(1))", "<p>x</p>"},
+		{"vbscript link", "[x](vbscript:msgbox)", "<p>x</p>"},
+		{"data link", "[x](data:text/html;base64,xx)", "<p>x</p>"},
+		{"javascript image", "![i](javascript:alert(1))", "<p>i</p>"},
+		{"entity encoded scheme", "[x](&#106;avascript:alert(1))",
+			`<p><a href="&amp;#106;avascript:alert(1)" rel="nofollow ugc">x</a></p>`},
+		{"javascript autolink", "<javascript:alert(1)>", "<p>&lt;javascript:alert(1)&gt;</p>"},
+		{"script tag", "<script>alert(1)</script>", "<p>&lt;script&gt;alert(1)&lt;/script&gt;</p>"},
+		{"event handler tag", "<img src=x onerror=alert(1)>", "<p>&lt;img src=x onerror=alert(1)&gt;</p>"},
+		{"href breakout", `[x](http://a.com/"onmouseover="alert(1))`,
+			`<p><a href="http://a.com/&quot;onmouseover=&quot;alert(1)" rel="nofollow ugc">x</a></p>`},
+		{"image alt breakout", `![a" onerror="alert(1)](http://a.com/i.png)`,
+			`<p><a href="http://a.com/i.png" rel="nofollow ugc">a&quot; onerror=&quot;alert(1)</a></p>`},
+		{"fence info breakout", "```\" onclick=\"x\n<b>code</b>\n```", "<pre><code>&lt;b&gt;code&lt;/b&gt;\n</code></pre>"},
+		{"bare url stops at tag", "https://example.com/a?b=<c>",
+			`<p><a href="https://example.com/a?b=" rel="nofollow ugc">https://example.com/a?b=</a>&lt;c&gt;</p>`},
+		{"emphasis around script", "*<script>* **x**", "<p><em>&lt;script&gt;</em> <strong>x</strong></p>"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := RenderMarkdown(tt.src)
+			if got != tt.want {
+				t.Errorf("RenderMarkdown(%q)\n got %q\nwant %q", tt.src, got, tt.want)
+			}
+			lower := strings.ToLower(got)
+			for _, bad := range []string{"<script", "<img", "javascript:", " onerror=", " onclick=", " onmouseover="} {
+				if strings.Contains(lower, bad) && !strings.Contains(tt.want, bad) {
+					t.Errorf("RenderMarkdown(%q) contains %q: %q", tt.src, bad, got)
+				}
+			}
+		})
+	}
+}
+
+func TestSanitizeURL(t *testing.T) {
+	tests := []struct {
+		raw   string
+		want  string
+		valid bool
+	}{
+		{"https://a.com/x y", "https://a.com/x%20y", true},
+		{"/r/x", "/r/x", true},
+		{"#frag", "#frag", true},
+		{"?q=1", "?q=1", true},
+		{"javascript:alert(1)", "", false},
+		{" JAVASCRIPT:x", "", false},
+		{"data:x", "", false},
+		{"ftp://x", "", false},
+	}
+	for _, tt := range tests {
+		got, valid := SanitizeURL(tt.raw)
+		if got != tt.want || valid != tt.valid {
+			t.Errorf("SanitizeURL(%q) = %q, %v; want %q, %v", tt.raw, got, valid, tt.want, tt.valid)
+		}
+	}
+}
+
+func TestRenderMarkdownNesting(t *testing.T) {
+	quote := RenderMarkdown(strings.Repeat(">", maxNestingDepth+2) + " x")
+	if n := strings.Count(quote, "<blockquote>"); n != maxNestingDepth {
+		t.Errorf("nested quote opened %d blockquotes, want %d", n, maxNestingDepth)
+	}
+	if !strings.Contains(quote, "<p>&gt;&gt; x</p>") {
+		t.Errorf("markers past the nesting limit should render as text: %q", quote)
+	}
+
+	list := RenderMarkdown(strings.Repeat("- ", maxNestingDepth+2) + "x")
+	if n := strings.Count(list, "<ul>"); n != maxNestingDepth {
+		t.Errorf("nested list opened %d lists, want %d", n, maxNestingDepth)
+	}
+
+	// Every nesting level re-renders its contents; without the cap these
+	// take seconds.
+	for _, src := range []string{
+		strings.Repeat(">", 50000) + " x",
+		strings.Repeat("- ", 50000) + "x",
+		strings.Repeat("> - ", 25000) + "x",
+	} {
+		start := time.Now()
+		RenderMarkdown(src)
+		if d := time.Since(start); d > time.Second {
+			t.Errorf("RenderMarkdown(%q...) took %v", src[:8], d)
+		}
+	}
+}
@@ -0,0 +1,43 @@
+package utils
+
+import "strings"
+
+// References holds the names a text links to with u/username and
+// r/subreddit, in order of first appearance and without duplicates.
+type References struct {
+	Users      []string
+	Subreddits []string
+}
+
+// ExtractReferences collects the u/ and r/ autolinks from html produced by
+// RenderMarkdown. Names inside code spans and blocks, and explicit markdown
+// links to /u/ or /r/ paths, are not references.
+func ExtractReferences(html string) References {
+	var refs References
+	seen := make(map[string]bool)
+	for {
+		i := strings.Index(html, `<a href="/`)
+		if i == -1 {
+			return refs
+		}
+		html = html[i+len(`<a href="/`):]
+		if len(html) < 2 || (html[0] != 'u' && html[0] != 'r') || html[1] != '/' {
+			continue
+		}
+		end := strings.IndexByte(html, '"')
+		// Autolinks are the only anchors emitted without a rel attribute.
+		if end == -1 || !strings.HasPrefix(html[end:], `">`) {
+			continue
+		}
+		kind, name := html[0], html[2:end]
+		if key := string(kind) + "/" + name; !seen[key] {
+			seen[key] = true
+			if kind == 'u' {
+				refs.Users = append(refs.Users, name)
+			} else {
+				refs.Subreddits = append(refs.Subreddits, name)
+			}
+		}
+		html = html[end:]
+	}
+}
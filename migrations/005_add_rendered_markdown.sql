@@ -0,0 +1,15 @@
+-- Migration: Add rendered markdown columns
+-- Date: 2025-11-08
+-- Description: Stores sanitized HTML rendered from markdown alongside the source text
+
+ALTER TABLE posts
+ADD COLUMN content_html TEXT;
+
+ALTER TABLE subreddits
+ADD COLUMN description_html TEXT;
+
+-- Comments for documentation
+COMMENT ON COLUMN posts.content IS 'Markdown source (CommonMark + Reddit extensions)';
+COMMENT ON COLUMN posts.content_html IS 'Sanitized HTML rendered from content, refreshed on every write';
+COMMENT ON COLUMN subreddits.description IS 'Markdown source for the about section';
+COMMENT ON COLUMN subreddits.description_html IS 'Sanitized HTML rendered from description, refreshed on every write';
@@ -0,0 +1,59 @@
+-- Migration: Add full-text search
+-- Date: 2025-11-09
+-- Description: Adds tsvector columns maintained by triggers on posts and subreddits,
+--              GIN indexes for search and trigram indexes for subreddit name autocomplete
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE posts
+ADD COLUMN search_vector TSVECTOR;
+
+ALTER TABLE subreddits
+ADD COLUMN search_vector TSVECTOR;
+
+-- Keep posts.search_vector in sync with the searchable columns
+CREATE OR REPLACE FUNCTION posts_search_vector_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('english', coalesce(NEW.title, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.content, '')), 'B') ||
+        setweight(to_tsvector('simple', coalesce(NEW.link_url, '')), 'C');
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER posts_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF title, content, link_url ON posts
+    FOR EACH ROW
+    EXECUTE FUNCTION posts_search_vector_update();
+
+-- Keep subreddits.search_vector in sync with the searchable columns
+CREATE OR REPLACE FUNCTION subreddits_search_vector_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', coalesce(NEW.name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.display_name, '')), 'A') ||
+        setweight(to_tsvector('english', coalesce(NEW.description, '')), 'B');
+    RETURN NEW;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER subreddits_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF name, display_name, description ON subreddits
+    FOR EACH ROW
+    EXECUTE FUNCTION subreddits_search_vector_update();
+
+-- Backfill existing rows (fires the triggers above)
+UPDATE posts SET title = title;
+UPDATE subreddits SET name = name;
+
+-- Indexes for search
+CREATE INDEX idx_posts_search_vector ON posts USING GIN (search_vector);
+CREATE INDEX idx_subreddits_search_vector ON subreddits USING GIN (search_vector);
+CREATE INDEX idx_subreddits_name_trgm ON subreddits USING GIN (name gin_trgm_ops);
+
+-- Comments for documentation
+COMMENT ON COLUMN posts.search_vector IS 'Weighted full-text document (title A, content B, link C), maintained by trigger';
+COMMENT ON COLUMN subreddits.search_vector IS 'Weighted full-text document (name/display_name A, description B), maintained by trigger';
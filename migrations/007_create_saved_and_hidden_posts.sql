@@ -0,0 +1,28 @@
+-- Migration: Create saved and hidden posts tables
+-- Date: 2025-11-10
+-- Description: Lets users bookmark posts (with optional categories) and hide posts from listings
+
+CREATE TABLE saved_posts (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    category VARCHAR(50),                       -- Optional user-defined folder
+    saved_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+CREATE TABLE hidden_posts (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    hidden_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, post_id)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_saved_posts_user_saved ON saved_posts(user_id, saved_at DESC);
+CREATE INDEX idx_saved_posts_user_category ON saved_posts(user_id, category);
+CREATE INDEX idx_hidden_posts_user_hidden ON hidden_posts(user_id, hidden_at DESC);
+
+-- Comments for documentation
+COMMENT ON TABLE saved_posts IS 'Posts bookmarked by users';
+COMMENT ON COLUMN saved_posts.category IS 'Optional user-defined category name; NULL means uncategorised';
+COMMENT ON TABLE hidden_posts IS 'Posts a user has hidden; excluded from that user''s listings';
@@ -0,0 +1,65 @@
+-- Migration: Create karma ledger
+-- Date: 2025-11-11
+-- Description: Tracks karma per user (totals and per subreddit) through an auditable
+--              ledger of score changes, and adds minimum-karma posting thresholds
+
+ALTER TABLE users
+ADD COLUMN post_karma INTEGER DEFAULT 0,
+ADD COLUMN comment_karma INTEGER DEFAULT 0;
+
+CREATE TABLE karma_ledger (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subreddit_id INTEGER REFERENCES subreddits(id) ON DELETE SET NULL,
+    source_type VARCHAR(20) NOT NULL,            -- 'post' or 'comment'
+    source_id INTEGER NOT NULL,                  -- ID of the post/comment (kept after deletion)
+    delta INTEGER NOT NULL,                      -- Change in karma
+    reason VARCHAR(50) NOT NULL DEFAULT 'vote',  -- 'vote', 'backfill', ...
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_subreddit_karma (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    post_karma INTEGER DEFAULT 0,
+    comment_karma INTEGER DEFAULT 0,
+    PRIMARY KEY (user_id, subreddit_id)
+);
+
+ALTER TABLE subreddits
+ADD COLUMN min_karma_to_post INTEGER DEFAULT 0,
+ADD COLUMN min_subreddit_karma_to_post INTEGER DEFAULT 0;
+
+-- Check constraints
+ALTER TABLE karma_ledger ADD CONSTRAINT check_karma_source_type
+    CHECK (source_type IN ('post', 'comment'));
+
+-- Indexes for performance
+CREATE INDEX idx_karma_ledger_user ON karma_ledger(user_id, created_at DESC);
+CREATE INDEX idx_karma_ledger_source ON karma_ledger(source_type, source_id);
+CREATE INDEX idx_user_subreddit_karma_subreddit ON user_subreddit_karma(subreddit_id);
+
+-- Backfill from existing post scores
+INSERT INTO karma_ledger (user_id, subreddit_id, source_type, source_id, delta, reason)
+SELECT author_id, subreddit_id, 'post', id, score, 'backfill'
+FROM posts
+WHERE score <> 0;
+
+INSERT INTO user_subreddit_karma (user_id, subreddit_id, post_karma)
+SELECT user_id, subreddit_id, SUM(delta)
+FROM karma_ledger
+WHERE subreddit_id IS NOT NULL
+GROUP BY user_id, subreddit_id;
+
+UPDATE users u
+SET post_karma = k.total
+FROM (SELECT user_id, SUM(delta) AS total FROM karma_ledger GROUP BY user_id) k
+WHERE k.user_id = u.id;
+
+-- Comments for documentation
+COMMENT ON TABLE karma_ledger IS 'Append-only log of karma changes; users.*_karma and user_subreddit_karma can be recomputed from it';
+COMMENT ON COLUMN users.post_karma IS 'Cached sum of post karma from karma_ledger';
+COMMENT ON COLUMN users.comment_karma IS 'Cached sum of comment karma from karma_ledger';
+COMMENT ON TABLE user_subreddit_karma IS 'Cached per-subreddit karma breakdown from karma_ledger';
+COMMENT ON COLUMN subreddits.min_karma_to_post IS 'Minimum total karma required to post (0 = no limit)';
+COMMENT ON COLUMN subreddits.min_subreddit_karma_to_post IS 'Minimum karma earned in this subreddit required to post (0 = no limit)';
@@ -0,0 +1,32 @@
+-- Migration: Create notifications tables
+-- Date: 2025-11-12
+-- Description: In-app notification inbox and per-type mute preferences
+
+CREATE TABLE notifications (
+    id BIGSERIAL PRIMARY KEY,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,          -- Recipient
+    type VARCHAR(30) NOT NULL,                                                -- See models.NotificationTypes
+    actor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,                 -- User who triggered it
+    post_id INTEGER REFERENCES posts(id) ON DELETE CASCADE,
+    subreddit_id INTEGER REFERENCES subreddits(id) ON DELETE CASCADE,
+    message TEXT NOT NULL,                                                    -- Human-readable summary
+    data JSONB DEFAULT '{}'::jsonb,                                           -- Type-specific extra fields
+    is_read BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE notification_preferences (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    type VARCHAR(30) NOT NULL,
+    muted BOOLEAN NOT NULL DEFAULT FALSE,
+    PRIMARY KEY (user_id, type)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_notifications_user_created ON notifications(user_id, created_at DESC);
+CREATE INDEX idx_notifications_user_unread ON notifications(user_id) WHERE NOT is_read;
+
+-- Comments for documentation
+COMMENT ON TABLE notifications IS 'Per-user inbox of events (replies, mentions, moderator actions, messages)';
+COMMENT ON COLUMN notifications.data IS 'JSON object with type-specific references, e.g. {"conversation_id": 1}';
+COMMENT ON TABLE notification_preferences IS 'Per-user, per-type mute settings; missing rows mean not muted';
@@ -0,0 +1,18 @@
+-- Migration: Create subreddit presence table
+-- Date: 2025-11-13
+-- Description: Per-instance viewer counts aggregated into subreddits.active_users
+
+CREATE TABLE subreddit_presence (
+    instance_id VARCHAR(32) NOT NULL,                                         -- API instance that owns the row
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    viewers INTEGER NOT NULL CHECK (viewers >= 0),                            -- Active viewers on that instance
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (instance_id, subreddit_id)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_subreddit_presence_subreddit ON subreddit_presence(subreddit_id);
+
+-- Comments for documentation
+COMMENT ON TABLE subreddit_presence IS 'Viewer counts flushed periodically by each API instance; rows from instances that stop flushing expire';
+COMMENT ON COLUMN subreddits.active_users IS 'Users viewing the subreddit in the last few minutes (sum of subreddit_presence, refreshed by the presence flusher)';
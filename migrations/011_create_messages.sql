@@ -0,0 +1,76 @@
+-- Migration: Create private messaging and modmail tables
+-- Date: 2025-11-14
+-- Description: Direct conversations, user block lists and shared moderator inboxes
+
+CREATE TABLE user_blocks (
+    blocker_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    blocked_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (blocker_id, blocked_id),
+    CHECK (blocker_id <> blocked_id)
+);
+
+-- One conversation per pair of users; user_low_id < user_high_id
+CREATE TABLE conversations (
+    id BIGSERIAL PRIMARY KEY,
+    user_low_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_high_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    user_low_last_read_at TIMESTAMP,                                          -- Read receipt for user_low_id
+    user_high_last_read_at TIMESTAMP,                                         -- Read receipt for user_high_id
+    last_message_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (user_low_id, user_high_id),
+    CHECK (user_low_id < user_high_id)
+);
+
+CREATE TABLE messages (
+    id BIGSERIAL PRIMARY KEY,
+    conversation_id BIGINT NOT NULL REFERENCES conversations(id) ON DELETE CASCADE,
+    sender_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Messages a user deleted from their own view
+CREATE TABLE message_deletions (
+    message_id BIGINT NOT NULL REFERENCES messages(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    deleted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, message_id)
+);
+
+CREATE TABLE modmail_threads (
+    id BIGSERIAL PRIMARY KEY,
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,                -- User who contacted the moderators
+    subject VARCHAR(200) NOT NULL,
+    is_archived BOOLEAN DEFAULT FALSE,
+    last_message_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE modmail_messages (
+    id BIGSERIAL PRIMARY KEY,
+    thread_id BIGINT NOT NULL REFERENCES modmail_threads(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    body TEXT NOT NULL,
+    is_internal BOOLEAN DEFAULT FALSE,                                        -- Moderator-only note
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for performance
+CREATE INDEX idx_user_blocks_blocked ON user_blocks(blocked_id);
+CREATE INDEX idx_conversations_low ON conversations(user_low_id, last_message_at DESC);
+CREATE INDEX idx_conversations_high ON conversations(user_high_id, last_message_at DESC);
+CREATE INDEX idx_messages_conversation ON messages(conversation_id, created_at DESC);
+CREATE INDEX idx_messages_sender_created ON messages(sender_id, created_at DESC);
+CREATE INDEX idx_modmail_threads_subreddit ON modmail_threads(subreddit_id, is_archived, last_message_at DESC);
+CREATE INDEX idx_modmail_threads_author ON modmail_threads(author_id, created_at DESC);
+CREATE INDEX idx_modmail_messages_thread ON modmail_messages(thread_id, created_at);
+
+-- Comments for documentation
+COMMENT ON TABLE user_blocks IS 'Users who may not message each other';
+COMMENT ON TABLE conversations IS 'Direct message threads between two users';
+COMMENT ON TABLE message_deletions IS 'Per-user soft deletes; the other participant still sees the message';
+COMMENT ON TABLE modmail_threads IS 'Messages to a subreddit, shared by its moderators';
+COMMENT ON COLUMN modmail_messages.is_internal IS 'Internal notes are only visible to moderators';
@@ -0,0 +1,13 @@
+-- Migration: Create muted subreddits table
+-- Date: 2025-11-15
+-- Description: Per-user list of subreddits excluded from aggregate feeds
+
+CREATE TABLE muted_subreddits (
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    muted_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (user_id, subreddit_id)
+);
+
+-- Comments for documentation
+COMMENT ON TABLE muted_subreddits IS 'Subreddits hidden from a user''s aggregate feeds; still visible when browsed directly';
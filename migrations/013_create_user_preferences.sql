@@ -0,0 +1,14 @@
+-- Migration: Create user preferences table
+-- Date: 2025-11-16
+-- Description: Per-user content and account settings stored as a versioned JSON document
+
+CREATE TABLE user_preferences (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    schema_version INTEGER NOT NULL DEFAULT 1,                                -- models.PreferencesSchemaVersion when written
+    settings JSONB NOT NULL DEFAULT '{}'::jsonb,                              -- Only keys the user changed need to be present
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Comments for documentation
+COMMENT ON TABLE user_preferences IS 'User settings; missing keys fall back to the defaults in models.DefaultPreferences';
+COMMENT ON COLUMN user_preferences.schema_version IS 'Older documents are upgraded on read by models.preferenceMigrations';
@@ -0,0 +1,17 @@
+-- Migration: Create user follows table
+-- Date: 2025-11-17
+-- Description: Users following other users' posts
+
+CREATE TABLE user_follows (
+    follower_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    followed_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (follower_id, followed_id),
+    CHECK (follower_id <> followed_id)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_user_follows_followed ON user_follows(followed_id, created_at DESC);
+
+-- Comments for documentation
+COMMENT ON TABLE user_follows IS 'Follower relationships; followers see the followed user''s posts in /api/me/feed';
@@ -0,0 +1,33 @@
+-- Migration: Add crossposts
+-- Date: 2025-11-18
+-- Description: Posts can reference an original post shared from another subreddit
+
+ALTER TABLE posts
+ADD COLUMN crosspost_parent_id INTEGER REFERENCES posts(id) ON DELETE SET NULL,  -- Always the original, never another crosspost
+ADD COLUMN crosspost_count INTEGER DEFAULT 0;                                   -- Crossposts of this post
+
+-- Keep posts.crosspost_count in sync with crossposts being created and deleted
+CREATE OR REPLACE FUNCTION posts_crosspost_count_update()
+RETURNS TRIGGER AS $$
+BEGIN
+    IF TG_OP = 'INSERT' AND NEW.crosspost_parent_id IS NOT NULL THEN
+        UPDATE posts SET crosspost_count = crosspost_count + 1 WHERE id = NEW.crosspost_parent_id;
+    ELSIF TG_OP = 'DELETE' AND OLD.crosspost_parent_id IS NOT NULL THEN
+        UPDATE posts SET crosspost_count = GREATEST(crosspost_count - 1, 0) WHERE id = OLD.crosspost_parent_id;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER posts_crosspost_count_trigger
+    AFTER INSERT OR DELETE ON posts
+    FOR EACH ROW
+    EXECUTE FUNCTION posts_crosspost_count_update();
+
+-- Indexes for performance
+CREATE INDEX idx_posts_crosspost_parent ON posts(crosspost_parent_id) WHERE crosspost_parent_id IS NOT NULL;
+CREATE INDEX idx_posts_link_url ON posts(link_url) WHERE link_url IS NOT NULL;
+
+-- Comments for documentation
+COMMENT ON COLUMN posts.crosspost_parent_id IS 'Original post this crosspost shares; its content is rendered inline';
+COMMENT ON COLUMN posts.crosspost_count IS 'Cached number of crossposts (maintained by posts_crosspost_count_trigger)';
@@ -0,0 +1,40 @@
+-- Migration: Create post drafts table
+-- Date: 2025-11-19
+-- Description: Private drafts and scheduled (optionally recurring) posts published by the scheduler
+
+CREATE TABLE post_drafts (
+    id BIGSERIAL PRIMARY KEY,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    subreddit_id INTEGER REFERENCES subreddits(id) ON DELETE SET NULL,        -- Required before scheduling or publishing
+    title VARCHAR(300) NOT NULL DEFAULT '',
+    content TEXT,
+    post_type VARCHAR(20) DEFAULT 'text',
+    link_url TEXT,
+    image_url TEXT,
+    is_nsfw BOOLEAN DEFAULT FALSE,
+    status VARCHAR(20) NOT NULL DEFAULT 'draft',                             -- draft, scheduled, published, cancelled, failed
+    publish_at TIMESTAMPTZ,                                                  -- Next publication time when scheduled
+    recurrence VARCHAR(20),                                                  -- daily, weekly, monthly (moderators only)
+    published_post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL,       -- Most recent post created from this draft
+    last_error TEXT,                                                         -- Why the last publish attempt failed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for performance
+CREATE INDEX idx_post_drafts_author ON post_drafts(author_id, updated_at DESC);
+CREATE INDEX idx_post_drafts_due ON post_drafts(publish_at) WHERE status = 'scheduled';
+
+-- Check constraints
+ALTER TABLE post_drafts ADD CONSTRAINT check_draft_status
+    CHECK (status IN ('draft', 'scheduled', 'published', 'cancelled', 'failed'));
+
+ALTER TABLE post_drafts ADD CONSTRAINT check_draft_recurrence
+    CHECK (recurrence IS NULL OR recurrence IN ('daily', 'weekly', 'monthly'));
+
+ALTER TABLE post_drafts ADD CONSTRAINT check_draft_schedule
+    CHECK (status <> 'scheduled' OR (publish_at IS NOT NULL AND subreddit_id IS NOT NULL));
+
+-- Comments for documentation
+COMMENT ON TABLE post_drafts IS 'Unpublished posts, visible only to their author';
+COMMENT ON COLUMN post_drafts.recurrence IS 'Recurring drafts stay scheduled and move publish_at forward after each publication';
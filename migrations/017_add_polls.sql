@@ -0,0 +1,49 @@
+-- Migration: Add polls
+-- Date: 2025-11-20
+-- Description: Poll post type with 2-6 options, a voting window and one vote per user
+
+ALTER TABLE posts DROP CONSTRAINT check_post_type;
+ALTER TABLE posts ADD CONSTRAINT check_post_type
+    CHECK (post_type IN ('text', 'link', 'image', 'poll'));
+
+CREATE TABLE polls (
+    post_id INTEGER PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    closes_at TIMESTAMPTZ NOT NULL,                                           -- End of the voting window
+    results_visibility VARCHAR(20) NOT NULL DEFAULT 'after_vote',             -- after_vote or after_close
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE poll_options (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES polls(post_id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL,                                               -- 0-based display order
+    text VARCHAR(120) NOT NULL,
+    vote_count INTEGER DEFAULT 0,                                             -- Cached count of poll_votes
+    UNIQUE (post_id, position),
+    UNIQUE (id, post_id)
+);
+
+-- The primary key enforces one vote per user per poll
+CREATE TABLE poll_votes (
+    post_id INTEGER NOT NULL,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    option_id INTEGER NOT NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_id, user_id),
+    FOREIGN KEY (option_id, post_id) REFERENCES poll_options(id, post_id) ON DELETE CASCADE
+);
+
+-- Indexes for performance
+CREATE INDEX idx_poll_votes_user_id ON poll_votes(user_id);
+
+-- Check constraints
+ALTER TABLE polls ADD CONSTRAINT check_poll_results_visibility
+    CHECK (results_visibility IN ('after_vote', 'after_close'));
+
+ALTER TABLE poll_options ADD CONSTRAINT check_poll_option_position
+    CHECK (position >= 0 AND position < 6);
+
+-- Comments for documentation
+COMMENT ON TABLE polls IS 'Voting settings for posts with post_type poll';
+COMMENT ON COLUMN polls.results_visibility IS 'after_vote: counts shown once the viewer voted or the poll closed; after_close: only once closed';
+COMMENT ON TABLE poll_votes IS 'One row per voter; the composite foreign key keeps option_id within the same poll';
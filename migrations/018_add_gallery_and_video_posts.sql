@@ -0,0 +1,40 @@
+-- Migration: Add gallery and video posts
+-- Date: 2025-11-24
+-- Description: Ordered gallery items and video metadata with generated poster frames
+
+ALTER TABLE posts DROP CONSTRAINT check_post_type;
+ALTER TABLE posts ADD CONSTRAINT check_post_type
+    CHECK (post_type IN ('text', 'link', 'image', 'poll', 'gallery', 'video'));
+
+CREATE TABLE gallery_items (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    position SMALLINT NOT NULL,                  -- 0-based display order
+    media_url TEXT NOT NULL,
+    caption VARCHAR(180),
+    outbound_url TEXT,                           -- Optional link shown with the item
+    UNIQUE (post_id, position)
+);
+
+CREATE TABLE post_videos (
+    post_id INTEGER PRIMARY KEY REFERENCES posts(id) ON DELETE CASCADE,
+    video_url TEXT NOT NULL,
+    duration_ms INTEGER NOT NULL,
+    width INTEGER NOT NULL,
+    height INTEGER NOT NULL,
+    poster BYTEA NOT NULL,                       -- PNG poster frame
+    poster_source VARCHAR(20) NOT NULL,          -- embedded (cover art) or placeholder
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Check constraints
+ALTER TABLE gallery_items ADD CONSTRAINT check_gallery_item_position
+    CHECK (position >= 0 AND position < 20);
+
+ALTER TABLE post_videos ADD CONSTRAINT check_post_video_poster_source
+    CHECK (poster_source IN ('embedded', 'placeholder'));
+
+-- Comments for documentation
+COMMENT ON TABLE gallery_items IS 'Ordered media items of gallery posts';
+COMMENT ON TABLE post_videos IS 'Metadata extracted from the video of video posts';
+COMMENT ON COLUMN post_videos.poster IS 'PNG served at /api/posts/:id/video/poster';
@@ -0,0 +1,29 @@
+-- Migration: Create post revisions
+-- Date: 2025-11-26
+-- Description: Edit history of posts, kept after the post is deleted so moderators can review it
+
+ALTER TABLE posts
+ADD COLUMN edited_at TIMESTAMP;                  -- Last edit of the title or body, NULL if never edited
+
+CREATE TABLE post_revisions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL,                    -- No foreign key: revisions outlive deleted posts
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    author_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    editor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    title VARCHAR(300) NOT NULL,                 -- Title before the change
+    content TEXT,                                -- Body before the change
+    reason VARCHAR(20) NOT NULL DEFAULT 'edit',  -- edit, deleted or removed
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for performance
+CREATE INDEX idx_post_revisions_post ON post_revisions(post_id, created_at);
+
+-- Check constraints
+ALTER TABLE post_revisions ADD CONSTRAINT check_post_revision_reason
+    CHECK (reason IN ('edit', 'deleted', 'removed'));
+
+-- Comments for documentation
+COMMENT ON TABLE post_revisions IS 'Previous versions of posts; each row is the version replaced at created_at';
+COMMENT ON COLUMN post_revisions.reason IS 'edit: replaced by an edit; deleted/removed: final version when the author or a moderator deleted the post';
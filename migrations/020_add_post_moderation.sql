@@ -0,0 +1,28 @@
+-- Migration: Add post moderation tools
+-- Date: 2025-11-28
+-- Description: Moderator-controlled locking, sticky posts, distinguished posts, contest mode and suggested comment sort
+
+ALTER TABLE users
+ADD COLUMN is_admin BOOLEAN DEFAULT FALSE;        -- Site administrators, granted directly in the database
+
+ALTER TABLE posts
+ADD COLUMN is_stickied BOOLEAN DEFAULT FALSE,     -- Pinned to the top of the subreddit listing
+ADD COLUMN stickied_at TIMESTAMP,                 -- Orders sticky posts among themselves
+ADD COLUMN distinguished VARCHAR(20),             -- moderator or admin, NULL if not distinguished
+ADD COLUMN contest_mode BOOLEAN DEFAULT FALSE,    -- Comments shown in random order with scores hidden
+ADD COLUMN suggested_sort VARCHAR(20);            -- Default comment sort for this post, NULL for the reader's choice
+
+-- Indexes for performance
+CREATE INDEX idx_posts_stickied ON posts(subreddit_id, stickied_at) WHERE is_stickied;
+
+-- Check constraints
+ALTER TABLE posts ADD CONSTRAINT check_post_distinguished
+    CHECK (distinguished IN ('moderator', 'admin'));
+
+ALTER TABLE posts ADD CONSTRAINT check_post_suggested_sort
+    CHECK (suggested_sort IN ('confidence', 'top', 'new', 'controversial', 'old', 'qa'));
+
+-- Comments for documentation
+COMMENT ON COLUMN posts.is_stickied IS 'At most two sticky posts per subreddit, enforced by the application';
+COMMENT ON COLUMN posts.distinguished IS 'Set by the author when posting in an official capacity';
+COMMENT ON COLUMN users.is_admin IS 'Administrators can distinguish their posts as admin';
@@ -0,0 +1,21 @@
+-- Migration: Add post archiving
+-- Date: 2025-12-01
+-- Description: Posts older than the subreddit's archive age become read-only for votes and comments
+
+ALTER TABLE subreddits
+ADD COLUMN archive_after_days INTEGER DEFAULT 180;   -- 0 = never archive
+
+ALTER TABLE posts
+ADD COLUMN is_archived BOOLEAN DEFAULT FALSE,        -- Set by the archive job, never cleared
+ADD COLUMN archived_at TIMESTAMP;
+
+-- Indexes for performance
+CREATE INDEX idx_posts_unarchived_created ON posts(subreddit_id, created_at) WHERE NOT is_archived;
+
+-- Check constraints
+ALTER TABLE subreddits ADD CONSTRAINT check_archive_after_days
+    CHECK (archive_after_days BETWEEN 0 AND 3650);
+
+-- Comments for documentation
+COMMENT ON COLUMN subreddits.archive_after_days IS 'Posts are archived this many days after creation (0 = never)';
+COMMENT ON COLUMN posts.is_archived IS 'Archived posts reject votes and comments; flipped by the background archive job';
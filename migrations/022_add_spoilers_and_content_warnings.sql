@@ -0,0 +1,34 @@
+-- Migration: Add spoilers and content warnings
+-- Date: 2025-12-03
+-- Description: Spoiler flag on posts and per-subreddit content warnings that posts can be tagged with
+
+ALTER TABLE posts
+ADD COLUMN is_spoiler BOOLEAN DEFAULT FALSE;
+
+CREATE TABLE subreddit_content_warnings (
+    id SERIAL PRIMARY KEY,
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    slug VARCHAR(32) NOT NULL,                   -- e.g. violence, medical; used in preferences and filters
+    label VARCHAR(64) NOT NULL,                  -- Shown to readers
+    description TEXT,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subreddit_id, slug)
+);
+
+CREATE TABLE post_content_warnings (
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    warning_id INTEGER NOT NULL REFERENCES subreddit_content_warnings(id) ON DELETE CASCADE,
+    PRIMARY KEY (post_id, warning_id)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_post_content_warnings_warning ON post_content_warnings(warning_id);
+
+-- Check constraints
+ALTER TABLE subreddit_content_warnings ADD CONSTRAINT check_content_warning_slug
+    CHECK (slug ~ '^[a-z0-9_-]{1,32}$' AND slug NOT IN ('nsfw', 'spoiler'));
+
+-- Comments for documentation
+COMMENT ON COLUMN posts.is_spoiler IS 'Whole-post spoiler; inline >!spoilers!< are handled by the markdown renderer';
+COMMENT ON TABLE subreddit_content_warnings IS 'Content warnings moderators define for their subreddit';
+COMMENT ON COLUMN subreddit_content_warnings.slug IS 'nsfw and spoiler are reserved for the built-in labels';
@@ -0,0 +1,62 @@
+-- Migration: Create awards
+-- Date: 2025-12-05
+-- Description: Award and reaction catalogue, per-user coin balances and awards given to posts
+
+CREATE TABLE award_types (
+    id SERIAL PRIMARY KEY,
+    subreddit_id INTEGER REFERENCES subreddits(id) ON DELETE CASCADE,  -- NULL for site-wide awards
+    slug VARCHAR(32) NOT NULL,
+    name VARCHAR(64) NOT NULL,
+    icon_url TEXT,
+    cost INTEGER NOT NULL DEFAULT 0,                                    -- Coins; 0 = free reaction
+    is_active BOOLEAN DEFAULT TRUE,                                     -- Retired awards stay on past posts
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE user_award_balances (
+    user_id INTEGER PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    balance INTEGER NOT NULL DEFAULT 0,
+    last_allowance_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP    -- Start of the last credited allowance period
+);
+
+CREATE TABLE awards (
+    id SERIAL PRIMARY KEY,
+    award_type_id INTEGER NOT NULL REFERENCES award_types(id) ON DELETE CASCADE,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    giver_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    recipient_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    cost INTEGER NOT NULL,                                              -- Coins spent, copied from the award type
+    is_anonymous BOOLEAN DEFAULT FALSE,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for performance
+CREATE UNIQUE INDEX idx_award_types_site_slug ON award_types(slug) WHERE subreddit_id IS NULL;
+CREATE UNIQUE INDEX idx_award_types_subreddit_slug ON award_types(subreddit_id, slug) WHERE subreddit_id IS NOT NULL;
+CREATE INDEX idx_awards_post ON awards(post_id);
+CREATE INDEX idx_awards_recipient ON awards(recipient_id, created_at DESC);
+-- Free reactions can be given once per user and post
+CREATE UNIQUE INDEX idx_awards_one_reaction ON awards(post_id, giver_id, award_type_id) WHERE cost = 0;
+
+-- Check constraints
+ALTER TABLE award_types ADD CONSTRAINT check_award_type_cost
+    CHECK (cost >= 0);
+
+ALTER TABLE award_types ADD CONSTRAINT check_award_type_slug
+    CHECK (slug ~ '^[a-z0-9_-]{1,32}$');
+
+ALTER TABLE user_award_balances ADD CONSTRAINT check_award_balance
+    CHECK (balance >= 0);
+
+-- Site-wide catalogue
+INSERT INTO award_types (slug, name, cost) VALUES
+    ('heart', 'Heart', 0),
+    ('laugh', 'Laugh', 0),
+    ('insightful', 'Insightful', 0),
+    ('silver', 'Silver', 100),
+    ('gold', 'Gold', 500),
+    ('platinum', 'Platinum', 1800);
+
+-- Comments for documentation
+COMMENT ON TABLE award_types IS 'Awards and reactions: site-wide when subreddit_id is NULL, otherwise usable only in that subreddit';
+COMMENT ON TABLE user_award_balances IS 'Coins for awards, topped up by the allowance (AWARD_ALLOWANCE every AWARD_ALLOWANCE_DAYS)';
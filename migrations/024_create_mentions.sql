@@ -0,0 +1,27 @@
+-- Migration: Create mentions
+-- Date: 2025-12-06
+-- Description: u/username mentions and r/subreddit references parsed from posts when they are written
+
+CREATE TABLE mentions (
+    id SERIAL PRIMARY KEY,
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id INTEGER REFERENCES users(id) ON DELETE CASCADE,             -- Set for u/ mentions
+    subreddit_id INTEGER REFERENCES subreddits(id) ON DELETE CASCADE,   -- Set for r/ references
+    is_current BOOLEAN NOT NULL DEFAULT TRUE,                          -- FALSE once edited out of the post
+    notified_at TIMESTAMP,                                             -- When the mentioned user was notified
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Indexes for performance
+CREATE UNIQUE INDEX idx_mentions_post_user ON mentions(post_id, user_id) WHERE user_id IS NOT NULL;
+CREATE UNIQUE INDEX idx_mentions_post_subreddit ON mentions(post_id, subreddit_id) WHERE subreddit_id IS NOT NULL;
+CREATE INDEX idx_mentions_user ON mentions(user_id) WHERE user_id IS NOT NULL AND is_current;
+CREATE INDEX idx_mentions_subreddit ON mentions(subreddit_id) WHERE subreddit_id IS NOT NULL AND is_current;
+
+-- Check constraints
+ALTER TABLE mentions ADD CONSTRAINT check_mention_target
+    CHECK ((user_id IS NULL) <> (subreddit_id IS NULL));
+
+-- Comments for documentation
+COMMENT ON TABLE mentions IS 'Users and subreddits a post references; only existing names are stored';
+COMMENT ON COLUMN mentions.notified_at IS 'At most MaxMentionNotifications users are notified per post, edits included';
@@ -0,0 +1,57 @@
+-- Migration: Create subreddit wiki
+-- Date: 2025-12-08
+-- Description: Hierarchical wiki pages per subreddit with full revision history and edit permissions
+
+CREATE TABLE wiki_pages (
+    id SERIAL PRIMARY KEY,
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    parent_id INTEGER REFERENCES wiki_pages(id) ON DELETE RESTRICT,       -- NULL for top-level pages
+    path VARCHAR(255) NOT NULL,                                           -- e.g. faq/posting
+    content TEXT NOT NULL DEFAULT '',
+    content_html TEXT NOT NULL DEFAULT '',                                -- Rendered from content on write
+    edit_permission VARCHAR(20) NOT NULL DEFAULT 'moderators',            -- moderators, approved or karma
+    min_karma INTEGER NOT NULL DEFAULT 0,                                 -- Subreddit karma needed with 'karma'
+    discussion_post_id INTEGER REFERENCES posts(id) ON DELETE SET NULL,   -- Thread for discussing the page
+    updated_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (subreddit_id, path)
+);
+
+CREATE TABLE wiki_revisions (
+    id SERIAL PRIMARY KEY,
+    page_id INTEGER NOT NULL REFERENCES wiki_pages(id) ON DELETE CASCADE,
+    editor_id INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    content TEXT NOT NULL,                                                -- The page as of this revision
+    reason VARCHAR(256),                                                  -- Edit summary
+    reverted_from INTEGER REFERENCES wiki_revisions(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE wiki_editors (
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    added_by INTEGER REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (subreddit_id, user_id)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_wiki_pages_parent ON wiki_pages(parent_id);
+CREATE INDEX idx_wiki_revisions_page ON wiki_revisions(page_id, id DESC);
+
+-- Check constraints
+ALTER TABLE wiki_pages ADD CONSTRAINT check_wiki_page_path
+    CHECK (path ~ '^[a-z0-9_-]{1,64}(/[a-z0-9_-]{1,64})*$');
+
+ALTER TABLE wiki_pages ADD CONSTRAINT check_wiki_edit_permission
+    CHECK (edit_permission IN ('moderators', 'approved', 'karma'));
+
+ALTER TABLE wiki_pages ADD CONSTRAINT check_wiki_min_karma
+    CHECK (min_karma >= 0);
+
+-- Comments for documentation
+COMMENT ON TABLE wiki_pages IS 'Subreddit wiki pages; a page''s parent is the page at its path minus the last segment';
+COMMENT ON COLUMN wiki_pages.edit_permission IS 'moderators: moderators only; approved: also wiki_editors; karma: also users with min_karma subreddit karma';
+COMMENT ON TABLE wiki_revisions IS 'Every version of every wiki page; the newest is the current content';
+COMMENT ON TABLE wiki_editors IS 'Users approved to edit the subreddit''s wiki pages that allow approved editors';
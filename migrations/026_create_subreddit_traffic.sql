@@ -0,0 +1,29 @@
+-- Migration: Create subreddit traffic
+-- Date: 2025-12-10
+-- Description: Hourly traffic and engagement rollups per subreddit, for moderators
+
+CREATE TABLE subreddit_traffic_hourly (
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    hour TIMESTAMP NOT NULL,                          -- Start of the hour, UTC
+    page_views INTEGER NOT NULL DEFAULT 0,
+    unique_visitors INTEGER NOT NULL DEFAULT 0,       -- Distinct visitors in the hour
+    subscriptions INTEGER NOT NULL DEFAULT 0,
+    unsubscriptions INTEGER NOT NULL DEFAULT 0,
+    posts INTEGER NOT NULL DEFAULT 0,
+    comments INTEGER NOT NULL DEFAULT 0,
+    PRIMARY KEY (subreddit_id, hour)
+);
+
+CREATE TABLE subreddit_traffic_visitors (
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    hour TIMESTAMP NOT NULL,
+    visitor BYTEA NOT NULL,                           -- Hash of the user ID or client address
+    PRIMARY KEY (subreddit_id, hour, visitor)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_subreddit_traffic_visitors_hour ON subreddit_traffic_visitors(hour);
+
+-- Comments for documentation
+COMMENT ON TABLE subreddit_traffic_hourly IS 'Per-hour counters, flushed from memory by each API instance every minute';
+COMMENT ON TABLE subreddit_traffic_visitors IS 'Visitors seen per hour, so daily and monthly unique visitors can be counted; pruned after 400 days';
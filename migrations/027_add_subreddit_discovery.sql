@@ -0,0 +1,49 @@
+-- Migration: Add subreddit discovery
+-- Date: 2025-12-12
+-- Description: Topic categories and a language on subreddits, for browsing, trending and recommendations
+
+ALTER TABLE subreddits
+ADD COLUMN language VARCHAR(35) NOT NULL DEFAULT 'en';   -- BCP 47 tag, e.g. en or pt-BR
+
+CREATE TABLE categories (
+    id SERIAL PRIMARY KEY,
+    slug VARCHAR(32) NOT NULL UNIQUE,
+    name VARCHAR(64) NOT NULL
+);
+
+CREATE TABLE subreddit_categories (
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    category_id INTEGER NOT NULL REFERENCES categories(id) ON DELETE CASCADE,
+    PRIMARY KEY (subreddit_id, category_id)
+);
+
+-- Indexes for performance
+CREATE INDEX idx_subreddit_categories_category ON subreddit_categories(category_id);
+CREATE INDEX idx_subreddits_language ON subreddits(language, members_count DESC);
+
+-- Check constraints
+ALTER TABLE subreddits ADD CONSTRAINT check_subreddit_language
+    CHECK (language ~ '^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$');
+
+-- Site-wide catalogue
+INSERT INTO categories (slug, name) VALUES
+    ('art', 'Art & Design'),
+    ('books', 'Books & Writing'),
+    ('business', 'Business & Finance'),
+    ('education', 'Education'),
+    ('fitness', 'Health & Fitness'),
+    ('food', 'Food & Drink'),
+    ('gaming', 'Gaming'),
+    ('humor', 'Humor'),
+    ('movies-tv', 'Movies & TV'),
+    ('music', 'Music'),
+    ('news', 'News & Politics'),
+    ('programming', 'Programming'),
+    ('science', 'Science'),
+    ('sports', 'Sports'),
+    ('technology', 'Technology'),
+    ('travel', 'Travel');
+
+-- Comments for documentation
+COMMENT ON TABLE categories IS 'Topic categories subreddits can be listed under';
+COMMENT ON TABLE subreddit_categories IS 'Up to three categories per subreddit, chosen by its moderators';
@@ -0,0 +1,10 @@
+-- Migration: Add content labels to post drafts
+-- Date: 2025-12-13
+-- Description: Drafts keep the spoiler flag and content warnings so scheduled posts publish with them
+
+ALTER TABLE post_drafts
+ADD COLUMN is_spoiler BOOLEAN DEFAULT FALSE,
+ADD COLUMN content_warnings TEXT[] NOT NULL DEFAULT '{}';   -- Slugs of the subreddit's content warnings
+
+-- Comments for documentation
+COMMENT ON COLUMN post_drafts.content_warnings IS 'Resolved against the subreddit when the draft is published; a warning deleted meanwhile fails the publish';
@@ -0,0 +1,24 @@
+-- Migration: Create post votes
+-- Date: 2025-12-14
+-- Description: One up- or downvote per user and post; posts.upvotes/downvotes/score and
+--              the author's karma are kept in step with it
+
+CREATE TABLE post_votes (
+    post_id INTEGER NOT NULL REFERENCES posts(id) ON DELETE CASCADE,
+    user_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    direction SMALLINT NOT NULL,                 -- 1 = upvote, -1 = downvote
+    created_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    updated_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (post_id, user_id)
+);
+
+-- Check constraints
+ALTER TABLE post_votes ADD CONSTRAINT check_post_vote_direction
+    CHECK (direction IN (1, -1));
+
+-- Indexes for performance
+CREATE INDEX idx_post_votes_user ON post_votes(user_id);
+
+-- Comments for documentation
+COMMENT ON TABLE post_votes IS 'Current vote per user and post; clearing a vote deletes the row';
+COMMENT ON COLUMN post_votes.direction IS 'Votes on your own posts count toward the score but not toward karma';
@@ -0,0 +1,15 @@
+-- Migration: Add recurrence start to post drafts
+-- Date: 2025-12-15
+-- Description: Recurring drafts count occurrences from their first publication time so
+--              monthly schedules don't drift when a month is shorter
+
+ALTER TABLE post_drafts
+ADD COLUMN recurrence_start TIMESTAMPTZ;    -- publish_at of the first occurrence
+
+-- Backfill existing recurring drafts from their next publication
+UPDATE post_drafts
+SET recurrence_start = publish_at
+WHERE recurrence IS NOT NULL;
+
+-- Comments for documentation
+COMMENT ON COLUMN post_drafts.recurrence_start IS 'Occurrence n is recurrence_start plus n periods; monthly ones are clamped to the end of shorter months';
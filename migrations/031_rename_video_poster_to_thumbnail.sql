@@ -0,0 +1,19 @@
+-- Migration: Rename video posters to thumbnails
+-- Date: 2025-12-16
+-- Description: Video images come from embedded cover art or a placeholder, not from a
+--              decoded frame, so they are called thumbnails rather than poster frames
+
+ALTER TABLE post_videos RENAME COLUMN poster TO thumbnail;
+ALTER TABLE post_videos RENAME COLUMN poster_source TO thumbnail_source;
+
+ALTER TABLE post_videos DROP CONSTRAINT check_post_video_poster_source;
+
+UPDATE post_videos SET thumbnail_source = 'cover_art' WHERE thumbnail_source = 'embedded';
+
+-- Check constraints
+ALTER TABLE post_videos ADD CONSTRAINT check_post_video_thumbnail_source
+    CHECK (thumbnail_source IN ('cover_art', 'placeholder'));
+
+-- Comments for documentation
+COMMENT ON COLUMN post_videos.thumbnail IS 'PNG served at /api/posts/:id/video/thumbnail';
+COMMENT ON COLUMN post_videos.thumbnail_source IS 'cover_art (embedded in the file) or placeholder (play button with the video''s aspect ratio)';
@@ -0,0 +1,71 @@
+-- Migration: Create subreddit contributors
+-- Date: 2025-12-17
+-- Description: Who has posted in each subreddit and how many contributors each has, kept
+--              in sync by a trigger so recommendations don't scan the posts table
+
+CREATE TABLE subreddit_contributors (
+    subreddit_id INTEGER NOT NULL REFERENCES subreddits(id) ON DELETE CASCADE,
+    author_id INTEGER NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    post_count INTEGER NOT NULL DEFAULT 0,
+    last_posted_at TIMESTAMP NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    PRIMARY KEY (subreddit_id, author_id)
+);
+
+ALTER TABLE subreddits
+ADD COLUMN contributors_count INTEGER NOT NULL DEFAULT 0;
+
+-- Keep subreddit_contributors and subreddits.contributors_count in sync with posts
+CREATE OR REPLACE FUNCTION subreddit_contributors_update()
+RETURNS TRIGGER AS $$
+DECLARE
+    remaining INTEGER;
+BEGIN
+    IF TG_OP = 'INSERT' THEN
+        INSERT INTO subreddit_contributors (subreddit_id, author_id, post_count, last_posted_at)
+        VALUES (NEW.subreddit_id, NEW.author_id, 1, COALESCE(NEW.created_at, CURRENT_TIMESTAMP))
+        ON CONFLICT (subreddit_id, author_id) DO NOTHING;
+        IF FOUND THEN
+            UPDATE subreddits SET contributors_count = contributors_count + 1 WHERE id = NEW.subreddit_id;
+        ELSE
+            UPDATE subreddit_contributors
+            SET post_count = post_count + 1,
+                last_posted_at = GREATEST(last_posted_at, COALESCE(NEW.created_at, CURRENT_TIMESTAMP))
+            WHERE subreddit_id = NEW.subreddit_id AND author_id = NEW.author_id;
+        END IF;
+    ELSIF TG_OP = 'DELETE' THEN
+        UPDATE subreddit_contributors SET post_count = post_count - 1
+        WHERE subreddit_id = OLD.subreddit_id AND author_id = OLD.author_id
+        RETURNING post_count INTO remaining;
+        IF remaining IS NOT NULL AND remaining <= 0 THEN
+            DELETE FROM subreddit_contributors
+            WHERE subreddit_id = OLD.subreddit_id AND author_id = OLD.author_id;
+            UPDATE subreddits SET contributors_count = GREATEST(contributors_count - 1, 0) WHERE id = OLD.subreddit_id;
+        END IF;
+    END IF;
+    RETURN NULL;
+END;
+$$ language 'plpgsql';
+
+CREATE TRIGGER subreddit_contributors_trigger
+    AFTER INSERT OR DELETE ON posts
+    FOR EACH ROW
+    EXECUTE FUNCTION subreddit_contributors_update();
+
+-- Indexes for performance
+CREATE INDEX idx_subreddit_contributors_author ON subreddit_contributors(author_id);
+CREATE INDEX idx_subreddit_contributors_recent ON subreddit_contributors(subreddit_id, last_posted_at DESC);
+
+-- Backfill from existing posts
+INSERT INTO subreddit_contributors (subreddit_id, author_id, post_count, last_posted_at)
+SELECT subreddit_id, author_id, COUNT(*), MAX(COALESCE(created_at, CURRENT_TIMESTAMP))
+FROM posts
+GROUP BY subreddit_id, author_id;
+
+UPDATE subreddits s
+SET contributors_count = c.total
+FROM (SELECT subreddit_id, COUNT(*) AS total FROM subreddit_contributors GROUP BY subreddit_id) c
+WHERE c.subreddit_id = s.id;
+
+-- Comments for documentation
+COMMENT ON TABLE subreddit_contributors IS 'Users who have posted in a subreddit (maintained by subreddit_contributors_trigger)';
+COMMENT ON COLUMN subreddits.contributors_count IS 'Cached number of subreddit_contributors rows';